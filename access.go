@@ -0,0 +1,66 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// goneErrorRegexp matches the status of an error response for a deleted PR.
+var goneErrorRegexp = regexp.MustCompile(`: (404|410) `)
+
+// checkAccessible reports whether the current PR can be handled, skipping deleted PRs with a warning.
+// On locked PRs, comments are suppressed while labels are still managed.
+func (a *Action) checkAccessible() (bool, error) {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		if goneErrorRegexp.MatchString(err.Error()) {
+			githubactions.Warningf("PR #%d is not found, skipping: %v", a.config.GetNumber(), err)
+			return false, nil
+		}
+		return false, fmt.Errorf("get PR: %w", err)
+	}
+
+	if _, locked := a.provider.(*lockedProvider); pr.Locked && !locked {
+		logger.Infof("PR #%d is locked, comments are suppressed\n", pr.Number)
+		a.provider = &lockedProvider{Provider: a.provider}
+	}
+	return true, nil
+}
+
+// lockedProvider drops the comments on a locked PR, which only collaborators can comment on.
+type lockedProvider struct {
+	scm.Provider
+}
+
+func (p *lockedProvider) Comment(ctx context.Context, number int, body string) error {
+	logger.Infof("Skip commenting on locked PR #%d\n", number)
+	return nil
+}
+
+func (p *lockedProvider) EditComment(ctx context.Context, number int, id int64, body string) error {
+	logger.Infof("Skip editing comment %d on locked PR #%d\n", id, number)
+	return nil
+}
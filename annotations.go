@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// maxCheckRunAnnotations is the maximum number of annotations per check run request.
+const maxCheckRunAnnotations = 50
+
+// templateLineNumbers returns the 1-based line number of the checkbox of each label in template.
+func (a *Action) templateLineNumbers(template string) map[string]int {
+	lines := make(map[string]int)
+	for i, line := range strings.Split(template, "\n") {
+		for _, preset := range a.config.labelExtractors {
+			for _, v := range preset.re.FindAllStringSubmatch(line, -1) {
+				_, label := preset.submatches(v)
+				label = strings.Trim(strings.TrimSpace(label), "`")
+				if _, exist := lines[label]; !exist {
+					lines[label] = i + 1
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// annotateChecklist reports a check run on the PR head annotating the checkboxes in the PR template
+// the author has to fix: the boxes of the lacking categories, which should have one of them checked,
+// and the checked boxes of the categories with multiple labels, which should have only one.
+// Annotations at the "warning" level result in a neutral check run.
+func (a *Action) annotateChecklist(pr *scm.PullRequest, message, level string, checked, lacking, multiple []string) error {
+	if a.client == nil || len(pr.HeadSHA) == 0 {
+		return nil
+	}
+	if !a.config.features().checkRuns {
+		logger.Infof("Check runs are disabled, skip annotating the checklist\n")
+		return nil
+	}
+
+	path := a.config.GetTemplatePath()
+	content, _, resp, err := a.client.Repositories.GetContents(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), path, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			logger.Infof("No PR template at %v, skip annotating\n", path)
+			return nil
+		}
+		return fmt.Errorf("get %v: %w", path, err)
+	}
+	template, err := content.GetContent()
+	if err != nil {
+		return fmt.Errorf("decode %v: %w", path, err)
+	}
+
+	isChecked := make(map[string]bool)
+	for _, label := range checked {
+		isChecked[label] = true
+	}
+	inCategories := func(label string, categories []string) bool {
+		category := a.config.labelCategory(label)
+		if category == missingCategoryDefault && !a.config.isWatchedLabel(label) {
+			return false
+		}
+		for _, c := range categories {
+			if c == category {
+				return true
+			}
+		}
+		return false
+	}
+
+	lines := a.templateLineNumbers(template)
+	labels := a.templateLabels(template)
+	sort.Slice(labels, func(i, j int) bool { return lines[labels[i]] < lines[labels[j]] })
+
+	annotations := []*ghapi.CheckRunAnnotation{}
+	for _, label := range labels {
+		var note string
+		switch {
+		case isChecked[label] && inCategories(label, multiple):
+			note = fmt.Sprintf("`%s` is checked along with other labels, keep only the one that applies.", label)
+		case !isChecked[label] && inCategories(label, lacking):
+			note = fmt.Sprintf("Check `%s` if it applies, one label of this group is required.", label)
+		default:
+			continue
+		}
+		if len(annotations) == maxCheckRunAnnotations {
+			break
+		}
+		annotations = append(annotations, &ghapi.CheckRunAnnotation{
+			Path:            ghapi.String(path),
+			StartLine:       ghapi.Int(lines[label]),
+			EndLine:         ghapi.Int(lines[label]),
+			AnnotationLevel: ghapi.String(level),
+			Message:         ghapi.String(note),
+		})
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	logger.Infof("@Annotate %v checkboxes of %v\n", len(annotations), path)
+	conclusion := "action_required"
+	if level == "warning" {
+		conclusion = "neutral"
+	}
+	_, _, err = a.client.Checks.CreateCheckRun(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), ghapi.CreateCheckRunOptions{
+		Name:       checkRunName,
+		HeadSHA:    pr.HeadSHA,
+		Status:     ghapi.String("completed"),
+		Conclusion: ghapi.String(conclusion),
+		Output: &ghapi.CheckRunOutput{
+			Title:       ghapi.String("Documentation label needs attention"),
+			Summary:     ghapi.String(message),
+			Annotations: annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create check run: %w", err)
+	}
+	return nil
+}
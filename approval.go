@@ -0,0 +1,118 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// docsApprovalCheckRunName is the name of the check run gating PRs labeled DOCS_APPROVAL_LABEL on DOCS_TEAM.
+const docsApprovalCheckRunName = "docbot / docs approval"
+
+// checkDocsApproval reports whether a member of DOCS_TEAM approved the current PR in a check run,
+// which only fails if the PR has DOCS_APPROVAL_LABEL.
+func (a *Action) checkDocsApproval() error {
+	if len(a.config.GetDocsTeam()) == 0 || a.client == nil {
+		return nil
+	}
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, pr.Number)
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	required := false
+	for _, label := range labels {
+		if label == a.config.GetDocsApprovalLabel() {
+			required = true
+		}
+	}
+	if !required {
+		return a.createNamedCheckRun(docsApprovalCheckRunName, pr.HeadSHA, "success", "Docs approval is not required",
+			fmt.Sprintf("The PR isn't labeled `%s`.", a.config.GetDocsApprovalLabel()))
+	}
+
+	logger.Infof("@Check approval of %v\n", a.config.GetDocsTeam())
+	approver, err := a.findDocsApprover()
+	if err != nil {
+		return err
+	}
+	if len(approver) > 0 {
+		logger.Infof("Approved by %v\n", approver)
+		return a.createNamedCheckRun(docsApprovalCheckRunName, pr.HeadSHA, "success", "Docs approved",
+			fmt.Sprintf("Approved by @%s of @%s.", approver, a.config.GetDocsTeam()))
+	}
+	return a.createNamedCheckRun(docsApprovalCheckRunName, pr.HeadSHA, "failure", "Waiting for docs approval",
+		fmt.Sprintf("The PR is labeled `%s` and needs an approval from a member of @%s.",
+			a.config.GetDocsApprovalLabel(), a.config.GetDocsTeam()))
+}
+
+// findDocsApprover returns a member of DOCS_TEAM whose latest review of the current PR is an approval,
+// or empty if there's none.
+func (a *Action) findDocsApprover() (string, error) {
+	org, slug, _ := strings.Cut(a.config.GetDocsTeam(), "/")
+	members := make(map[string]struct{})
+	memberOptions := &ghapi.TeamListTeamMembersOptions{ListOptions: ghapi.ListOptions{PerPage: 100}}
+	for {
+		users, resp, err := a.client.Teams.ListTeamMembersBySlug(a.globalContext, org, slug, memberOptions)
+		if err != nil {
+			return "", fmt.Errorf("list members of %v: %w", a.config.GetDocsTeam(), err)
+		}
+		for _, user := range users {
+			members[user.GetLogin()] = struct{}{}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		memberOptions.Page = resp.NextPage
+	}
+
+	// The latest review of each user decides, comments don't change the state
+	states := make(map[string]string)
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := a.client.PullRequests.ListReviews(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return "", fmt.Errorf("list reviews: %w", err)
+		}
+		for _, review := range reviews {
+			if review.GetState() != "COMMENTED" {
+				states[review.GetUser().GetLogin()] = review.GetState()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	approver := ""
+	for user, state := range states {
+		if _, member := members[user]; member && state == "APPROVED" && (len(approver) == 0 || user < approver) {
+			approver = user
+		}
+	}
+	return approver, nil
+}
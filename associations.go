@@ -0,0 +1,123 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Enforcement levels of the label checks for an author association.
+const (
+	// enforcementFull reminds the author and fails the run, the default
+	enforcementFull = "full"
+	// enforcementWarn only annotates the PR template with warnings
+	enforcementWarn = "warn"
+	// enforcementOff keeps labels in sync without reminding or failing
+	enforcementOff = "off"
+)
+
+// AssociationRule configures how the label checks are enforced for authors of the given associations,
+// e.g. only warning members while failing PRs of external contributors.
+type AssociationRule struct {
+	// Associations are GitHub author_association values, e.g. MEMBER or FIRST_TIME_CONTRIBUTOR
+	Associations []string `yaml:"associations" schema:"required"`
+	Enforcement  string   `yaml:"enforcement" description:"One of full, warn or off, defaults to full"`
+	// MessageMissing and MessageMultiple override the reminders, if set
+	MessageMissing  string `yaml:"message_missing"`
+	MessageMultiple string `yaml:"message_multiple"`
+}
+
+// associationRule returns the first rule matching the author association of pr, or nil.
+func (a *Action) associationRule(pr *scm.PullRequest) (*AssociationRule, error) {
+	if len(pr.AuthorAssociation) == 0 {
+		return nil, nil
+	}
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	for i, rule := range fc.AssociationRules {
+		for _, association := range rule.Associations {
+			if strings.EqualFold(association, pr.AuthorAssociation) {
+				return &fc.AssociationRules[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// enforceLabelCheck reports a failed label check of class for the author of pr at its enforcement level:
+// remind and fail when full, annotate with warnings when warn, and nothing when off.
+// The returned error is nil unless the check should fail the run.
+func (a *Action) enforceLabelCheck(pr *scm.PullRequest, class, reaction string, checked, lacking, multiple []string) error {
+	rule, err := a.associationRule(pr)
+	if err != nil {
+		return fmt.Errorf("get file config: %w", err)
+	}
+	enforcement := enforcementFull
+	if rule != nil && len(rule.Enforcement) > 0 {
+		enforcement = rule.Enforcement
+	}
+	switch enforcement {
+	case enforcementFull, enforcementWarn, enforcementOff:
+	default:
+		return fmt.Errorf("unknown enforcement %q for %v authors", enforcement, pr.AuthorAssociation)
+	}
+
+	message, reminder := a.config.GetMessageLabelMultiple(), ""
+	if class == failureLabelMissing {
+		message, reminder = a.config.GetMessageLabelMissing(), a.labelMissingMessage(pr)
+		if rule != nil && len(rule.MessageMissing) > 0 {
+			message, reminder = rule.MessageMissing, a.withGuide(a.withHints(rule.MessageMissing))
+		}
+	} else {
+		if rule != nil && len(rule.MessageMultiple) > 0 {
+			message = rule.MessageMultiple
+		}
+		reminder = a.withGuide(message)
+	}
+
+	switch enforcement {
+	case enforcementOff:
+		logger.Infof("Label check not enforced for %v authors\n", pr.AuthorAssociation)
+		return nil
+	case enforcementWarn:
+		logger.Infof("Label check only warned for %v authors\n", pr.AuthorAssociation)
+		if err := a.annotateChecklist(pr, message, "warning", checked, lacking, multiple); err != nil {
+			a.warn("Annotate checklist", err)
+		}
+		return nil
+	}
+
+	if err := a.remind(pr, reaction, reminder); err != nil {
+		if class == failureLabelMultiple {
+			return fmt.Errorf("remind multiple labels: %w", err)
+		}
+		a.warn("Remind missing label", err)
+	}
+	if checked != nil {
+		if err := a.annotateChecklist(pr, message, "failure", checked, lacking, multiple); err != nil {
+			a.warn("Annotate checklist", err)
+		}
+	}
+	return newComplianceError(class, message)
+}
@@ -0,0 +1,188 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const (
+	auditLogMarker = markerPrefix + "audit-log -->"
+	auditLogHeader = "<details>\n<summary>Label changes made by the bot</summary>\n\n"
+	auditLogFooter = "\n</details>"
+)
+
+// auditChanges records the changes made through the provider until the returned function is called,
+// which appends them to the audit log comment of the PR, one timestamped line per change along with its cause.
+func (a *Action) auditChanges(cause string) func() {
+	provider := &auditProvider{Provider: a.provider, cause: cause}
+	a.provider = provider
+	return func() {
+		a.provider = provider.Provider
+		if len(provider.entries) == 0 {
+			return
+		}
+		if err := a.appendAuditLog(provider.entries); err != nil {
+			a.warn("Append audit log", err)
+		}
+	}
+}
+
+// appendAuditLog appends entries to the collapsed audit log comment of the bot, posting it if the PR has none yet.
+// The oldest entries are dropped once the comment would exceed the length GitHub allows.
+func (a *Action) appendAuditLog(entries []string) error {
+	if a.client == nil {
+		return nil
+	}
+	comments, err := a.listBotComments()
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	var comment *ghapi.IssueComment
+	for _, c := range comments {
+		if strings.Contains(c.GetBody(), auditLogMarker) {
+			comment = c
+		}
+	}
+
+	lines := []string{}
+	if comment != nil {
+		log := strings.TrimPrefix(comment.GetBody(), auditLogMarker+"\n"+auditLogHeader)
+		// the provider appends the version marker after the footer
+		log, _, _ = strings.Cut(log, auditLogFooter)
+		for _, line := range strings.Split(log, "\n") {
+			if len(strings.TrimSpace(line)) > 0 {
+				lines = append(lines, line)
+			}
+		}
+	}
+	lines = append(lines, entries...)
+
+	body := auditLogBody(lines)
+	for len(body) > maxEditableBodyLength && len(lines) > 1 {
+		lines = lines[1:]
+		body = auditLogBody(lines)
+	}
+
+	if comment == nil {
+		if err := a.provider.Comment(a.globalContext, a.config.GetNumber(), body); err != nil {
+			return fmt.Errorf("create issue comment: %w", err)
+		}
+		return nil
+	}
+	if err := a.provider.EditComment(a.globalContext, a.config.GetNumber(), comment.GetID(), body); err != nil {
+		return fmt.Errorf("edit issue comment: %w", err)
+	}
+	return nil
+}
+
+func auditLogBody(lines []string) string {
+	return auditLogMarker + "\n" + auditLogHeader + strings.Join(lines, "\n") + auditLogFooter
+}
+
+// auditProvider records the changes made to the PR, successful or not, as audit log entries.
+// The label changes the provider skips, since the labels are already as asked, aren't recorded.
+type auditProvider struct {
+	scm.Provider
+	cause   string
+	entries []string
+}
+
+func (p *auditProvider) record(err error, format string, args ...interface{}) {
+	entry := fmt.Sprintf("- %s `%s`: %s", now().UTC().Format("2006-01-02 15:04:05 MST"), p.cause, fmt.Sprintf(format, args...))
+	if err != nil {
+		entry += fmt.Sprintf(" (failed: %v)", err)
+	}
+	p.entries = append(p.entries, entry)
+}
+
+// changingLabels returns the labels of add missing from the PR number and those of remove applied on it,
+// which are the changes actually made. All of them are returned if the labels of the PR can't be listed.
+func (p *auditProvider) changingLabels(ctx context.Context, number int, add, remove []string) ([]string, []string) {
+	current, err := p.Provider.ListLabels(ctx, number)
+	if err != nil {
+		return add, remove
+	}
+	currentSet := make(map[string]struct{}, len(current))
+	for _, label := range current {
+		currentSet[label] = struct{}{}
+	}
+	added, removed := []string{}, []string{}
+	for _, label := range add {
+		if _, ok := currentSet[label]; !ok {
+			added = append(added, label)
+		}
+	}
+	for _, label := range remove {
+		if _, ok := currentSet[label]; ok {
+			removed = append(removed, label)
+		}
+	}
+	return added, removed
+}
+
+func (p *auditProvider) AddLabels(ctx context.Context, number int, labels []string) error {
+	added, _ := p.changingLabels(ctx, number, labels, nil)
+	err := p.Provider.AddLabels(ctx, number, labels)
+	if len(added) > 0 {
+		p.record(err, "added %s", formatLabels(added))
+	}
+	return err
+}
+
+func (p *auditProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	_, removed := p.changingLabels(ctx, number, nil, []string{label})
+	err := p.Provider.RemoveLabel(ctx, number, label)
+	if len(removed) > 0 {
+		p.record(err, "removed %s", formatLabels(removed))
+	}
+	return err
+}
+
+func (p *auditProvider) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	added, removed := p.changingLabels(ctx, number, add, remove)
+	err := p.Provider.EditLabels(ctx, number, add, remove)
+	if len(removed) > 0 {
+		p.record(err, "removed %s", formatLabels(removed))
+	}
+	if len(added) > 0 {
+		p.record(err, "added %s", formatLabels(added))
+	}
+	return err
+}
+
+func (p *auditProvider) EditBody(ctx context.Context, number int, body string) error {
+	err := p.Provider.EditBody(ctx, number, body)
+	p.record(err, "edited the body")
+	return err
+}
+
+func (p *auditProvider) EditTitle(ctx context.Context, number int, title string) error {
+	err := p.Provider.EditTitle(ctx, number, title)
+	p.record(err, "edited the title to %q", title)
+	return err
+}
+
+func formatLabels(labels []string) string {
+	return "`" + strings.Join(labels, "`, `") + "`"
+}
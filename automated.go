@@ -0,0 +1,79 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// handleAutomatedPR applies BOT_AUTHOR_LABELS to PRs of automated dependency-update authors
+// like Dependabot, ticking their checkboxes in the PR body, and reports whether the PR is
+// such a PR, which is exempt from label enforcement.
+func (a *Action) handleAutomatedPR() (bool, error) {
+	if len(a.config.botAuthors) == 0 {
+		return false, nil
+	}
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return false, fmt.Errorf("get PR: %w", err)
+	}
+	if _, exist := a.config.botAuthors[pr.Author]; !exist {
+		return false, nil
+	}
+	logger.Infof("PR #%d is authored by %v, skip label enforcement\n", pr.Number, pr.Author)
+
+	if len(a.config.botAuthorLabels) == 0 {
+		return true, nil
+	}
+
+	current, err := a.provider.ListLabels(a.globalContext, pr.Number)
+	if err != nil {
+		return true, fmt.Errorf("list labels: %w", err)
+	}
+	currentSet := make(map[string]struct{})
+	for _, label := range current {
+		currentSet[label] = struct{}{}
+	}
+
+	labelsToAdd := []string{}
+	bodyLabels := a.extractLabels(pr.Body)
+	changeList := make(map[string]bool)
+	for _, label := range a.config.botAuthorLabels {
+		if _, exist := currentSet[label]; !exist {
+			labelsToAdd = append(labelsToAdd, label)
+		}
+		if checked, exist := bodyLabels[label]; exist && !checked {
+			changeList[label] = true
+		}
+	}
+
+	if len(labelsToAdd) > 0 {
+		logger.Infof("@Add labels %v\n", labelsToAdd)
+		if err := a.provider.AddLabels(a.globalContext, pr.Number, labelsToAdd); err != nil {
+			return true, fmt.Errorf("add labels %v: %w", labelsToAdd, err)
+		}
+	}
+	if len(changeList) > 0 {
+		if err := a.editCheckboxes(pr, changeList); err != nil {
+			return true, fmt.Errorf("edit body: %w", err)
+		}
+	}
+	return true, nil
+}
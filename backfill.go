@@ -0,0 +1,188 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/workerpool"
+)
+
+// runBackfill reconciles the labels of every open PR in the configured repos,
+// or only of the PRs listed in BATCH_NUMBERS.
+func runBackfill(ctx context.Context, ac *ActionConfig) error {
+	limiter := workerpool.NewLimiter(ac.GetBatchRateLimit())
+	client := newGitHubClient(ctx, ac.GetToken(), limiter.Transport(ac.transport()))
+
+	return backfill(ctx, ac, client)
+}
+
+func backfill(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	repoLabels := newRepoLabelCache()
+	tasks := []workerpool.Task{}
+	for _, slug := range ac.batchRepos {
+		ownerRepo := strings.Split(slug, "/")
+		if len(ownerRepo) != 2 {
+			return fmt.Errorf("invalid repo %v", slug)
+		}
+		owner, repo := ownerRepo[0], ownerRepo[1]
+
+		var prs []*ghapi.PullRequest
+		var err error
+		if len(ac.batchNumbers) > 0 {
+			logger.Infof("@Get PRs %v of %v\n", ac.batchNumbers, slug)
+			prs, err = getOpenPullRequests(ctx, client, owner, repo, ac.batchNumbers)
+		} else {
+			logger.Infof("@List open PRs of %v\n", slug)
+			prs, err = listOpenPullRequests(ctx, client, owner, repo)
+		}
+		if err != nil {
+			return fmt.Errorf("list open PRs of %v: %w", slug, err)
+		}
+		logger.Infof("Found %v open PRs in %v\n", len(prs), slug)
+
+		for _, pr := range prs {
+			action := newPullRequestAction(ctx, ac, client, owner, repo, pr).shareRepoLabels(repoLabels)
+			tasks = append(tasks, workerpool.Task{
+				Key: fmt.Sprintf("%s#%d", slug, pr.GetNumber()),
+				Fn: func(ctx context.Context) error {
+					return action.Run("edited")
+				},
+			})
+		}
+	}
+
+	results := workerpool.New(ac.GetBatchWorkers()).Run(ctx, tasks)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			logger.Errorf("%v: %v\n", result.Key, result.Err)
+		}
+	}
+	logger.Infof("Backfill done: %v processed, %v failed\n", len(results), failed)
+
+	// the audit covers the failed PRs too
+	if err := reportBackfill(ac, results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v PRs failed", failed, len(results))
+	}
+	return nil
+}
+
+// reportBackfill writes the audit of results to the report output and uploads it, if either is configured.
+func reportBackfill(ac *ActionConfig, results []workerpool.Result) error {
+	if len(ac.GetReportOutput()) == 0 && len(ac.GetReportUploadURL()) == 0 {
+		return nil
+	}
+	output := reportOutput(ac, "backfill-audit")
+	if err := writeBackfillAudit(results, ac.GetReportFormat(), output); err != nil {
+		return err
+	}
+	if len(ac.GetReportUploadURL()) > 0 {
+		return uploadReport(output, ac.GetReportUploadURL())
+	}
+	return nil
+}
+
+type backfillAuditEntry struct {
+	PullRequest string `json:"pull_request"`
+	Error       string `json:"error,omitempty"`
+}
+
+// writeBackfillAudit writes one entry per processed PR to output as format (JSON or CSV).
+func writeBackfillAudit(results []workerpool.Result, format, output string) error {
+	entries := make([]backfillAuditEntry, 0, len(results))
+	for _, result := range results {
+		entry := backfillAuditEntry{PullRequest: result.Key}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].PullRequest < entries[j].PullRequest })
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("create audit: %w", err)
+	}
+	defer f.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			return fmt.Errorf("write audit: %w", err)
+		}
+		return nil
+	}
+
+	records := [][]string{{"pull_request", "error"}}
+	for _, entry := range entries {
+		records = append(records, []string{entry.PullRequest, entry.Error})
+	}
+	if err := csv.NewWriter(f).WriteAll(records); err != nil {
+		return fmt.Errorf("write audit: %w", err)
+	}
+	return nil
+}
+
+// getOpenPullRequests gets the PRs numbers of owner/repo, skipping those no longer open.
+func getOpenPullRequests(ctx context.Context, client *ghapi.Client, owner, repo string, numbers []int) ([]*ghapi.PullRequest, error) {
+	prs := make([]*ghapi.PullRequest, 0, len(numbers))
+	for _, number := range numbers {
+		pr, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+		if err != nil {
+			return nil, fmt.Errorf("get PR #%d: %w", number, err)
+		}
+		if pr.GetState() != "open" {
+			logger.Infof("PR #%d is %v, skipping\n", number, pr.GetState())
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+func listOpenPullRequests(ctx context.Context, client *ghapi.Client, owner, repo string) ([]*ghapi.PullRequest, error) {
+	listOptions := &ghapi.PullRequestListOptions{State: "open", ListOptions: ghapi.ListOptions{PerPage: 100}}
+	prs := make([]*ghapi.PullRequest, 0)
+	for {
+		p, resp, err := client.PullRequests.List(ctx, owner, repo, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		prs = append(prs, p...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return prs, nil
+}
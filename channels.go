@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const (
+	MessageReviewResolved = "The PR has a valid documentation label now."
+
+	reviewMarker    = markerPrefix + "review -->"
+	noteStartMarker = markerPrefix + "note -->"
+	noteEndMarker   = markerPrefix + "note-end -->"
+)
+
+// noteRegexp matches the bot-managed note section of the PR description.
+var noteRegexp = regexp.MustCompile(`(?s)\s*` + regexp.QuoteMeta(noteStartMarker) + `.*?` + regexp.QuoteMeta(noteEndMarker))
+
+// deliver delivers guidance to the PR author through COMMENT_CHANNEL: an issue comment,
+// a review requesting changes, or a note in a bot-managed section of the PR description.
+func (a *Action) deliver(pr *scm.PullRequest, message string) error {
+	switch a.config.GetCommentChannel() {
+	case "review":
+		if _, locked := a.provider.(*lockedProvider); locked {
+			logger.Infof("Skip reviewing locked PR #%d\n", pr.Number)
+			return nil
+		}
+		if a.client != nil {
+			return a.requestChanges(pr, message)
+		}
+	case "description":
+		return a.setDescriptionNote(pr, message)
+	}
+	return a.comment(pr, message)
+}
+
+// requestChanges submits a review requesting changes, unless the bot already requested changes.
+func (a *Action) requestChanges(pr *scm.PullRequest, message string) error {
+	review, err := a.findBotReview()
+	if err != nil {
+		return fmt.Errorf("list reviews: %w", err)
+	}
+	if review != nil && review.GetState() == "CHANGES_REQUESTED" {
+		logger.Infof("Changes already requested in review %d\n", review.GetID())
+		return nil
+	}
+
+	_, _, err = a.client.PullRequests.CreateReview(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.Number,
+		&ghapi.PullRequestReviewRequest{
+			Event: ghapi.String("REQUEST_CHANGES"),
+			Body:  ghapi.String(fmt.Sprintf("%s\n@%s %s", reviewMarker, pr.Author, message)),
+		})
+	if err != nil {
+		return fmt.Errorf("create review: %w", err)
+	}
+	return nil
+}
+
+// findBotReview returns the latest review of the bot on the current PR, or nil if there's none.
+// The reviews of others embedding the marker are ignored, so that the bot never dismisses them.
+func (a *Action) findBotReview() (*ghapi.PullRequestReview, error) {
+	var found *ghapi.PullRequestReview
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := a.client.PullRequests.ListReviews(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, review := range reviews {
+			if strings.Contains(review.GetBody(), reviewMarker) && strings.EqualFold(review.GetUser().GetLogin(), a.config.GetBotLogin()) {
+				found = review
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return found, nil
+}
+
+// resolveGuidance withdraws the guidance delivered through COMMENT_CHANNEL once the PR has valid labels.
+func (a *Action) resolveGuidance() error {
+	switch a.config.GetCommentChannel() {
+	case "review":
+		if a.client != nil {
+			return a.resolveReview()
+		}
+	case "description":
+		return a.clearDescriptionNote()
+	}
+	return nil
+}
+
+// resolveReview dismisses or approves the review of the bot requesting changes,
+// so that the PR isn't blocked by a stale bot review.
+func (a *Action) resolveReview() error {
+	review, err := a.findBotReview()
+	if err != nil {
+		return fmt.Errorf("list reviews: %w", err)
+	}
+	if review == nil || review.GetState() != "CHANGES_REQUESTED" {
+		return nil
+	}
+
+	if a.config.GetReviewResolution() == "approve" {
+		logger.Infof("@Approve over review %d\n", review.GetID())
+		_, _, err = a.client.PullRequests.CreateReview(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
+			&ghapi.PullRequestReviewRequest{
+				Event: ghapi.String("APPROVE"),
+				Body:  ghapi.String(fmt.Sprintf("%s\n%s", reviewMarker, MessageReviewResolved)),
+			})
+		if err != nil {
+			return fmt.Errorf("create review: %w", err)
+		}
+		return nil
+	}
+
+	logger.Infof("@Dismiss review %d\n", review.GetID())
+	_, _, err = a.client.PullRequests.DismissReview(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), review.GetID(),
+		&ghapi.PullRequestReviewDismissalRequest{Message: ghapi.String(MessageReviewResolved)})
+	if err != nil {
+		return fmt.Errorf("dismiss review: %w", err)
+	}
+	return nil
+}
+
+// setDescriptionNote replaces the bot-managed note section of the PR description with message.
+func (a *Action) setDescriptionNote(pr *scm.PullRequest, message string) error {
+	note := fmt.Sprintf("%s\n> [!NOTE]\n> %s\n%s", noteStartMarker,
+		strings.ReplaceAll(strings.TrimRight(message, "\n"), "\n", "\n> "), noteEndMarker)
+	body := strings.TrimRight(noteRegexp.ReplaceAllString(pr.Body, ""), "\r\n") + "\n\n" + note
+	if body == pr.Body {
+		return nil
+	}
+	return a.provider.EditBody(a.globalContext, pr.Number, body)
+}
+
+// clearDescriptionNote removes the bot-managed note section from the PR description.
+func (a *Action) clearDescriptionNote() error {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	if !strings.Contains(pr.Body, noteStartMarker) {
+		return nil
+	}
+	return a.provider.EditBody(a.globalContext, pr.Number, noteRegexp.ReplaceAllString(pr.Body, ""))
+}
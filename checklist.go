@@ -0,0 +1,70 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+	"text/template"
+)
+
+// checklistData is the data of the checklist template of the config file.
+type checklistData struct {
+	// WatchedLabels are the sorted labels an author can select
+	WatchedLabels []string
+}
+
+// checklist renders the watched-label checklist offered to the author of the PR,
+// with the checklist_template of the config file if set.
+func (a *Action) checklist() string {
+	fc, err := a.getFileConfig()
+	if err != nil || len(fc.ChecklistTemplate) == 0 {
+		return renderChecklist(a.watchedLabels())
+	}
+
+	var files []string
+	changed := func(globs ...string) (bool, error) {
+		if files == nil {
+			changedFiles, err := a.listFiles()
+			if err != nil {
+				return false, err
+			}
+			files = make([]string, 0, len(changedFiles))
+			for _, file := range changedFiles {
+				files = append(files, file.GetFilename())
+			}
+		}
+		for _, file := range files {
+			if matchAny(globs, file) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	tmpl, err := template.New("checklist_template").Funcs(template.FuncMap{"changed": changed}).Parse(fc.ChecklistTemplate)
+	if err != nil {
+		a.warn("Parse checklist template", err)
+		return renderChecklist(a.watchedLabels())
+	}
+	checklist := &strings.Builder{}
+	if err := tmpl.Execute(checklist, checklistData{WatchedLabels: a.watchedLabels()}); err != nil {
+		a.warn("Render checklist template", err)
+		return renderChecklist(a.watchedLabels())
+	}
+	return checklist.String()
+}
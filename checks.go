@@ -0,0 +1,68 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// checkRunName is the name of the check run reporting the label status of a PR.
+const checkRunName = "docbot"
+
+// createCheckRun reports a completed check run on headSHA.
+func (a *Action) createCheckRun(headSHA, conclusion, title, summary string) error {
+	return a.createNamedCheckRun(checkRunName, headSHA, conclusion, title, summary)
+}
+
+// createNamedCheckRun reports a completed check run called name on headSHA.
+func (a *Action) createNamedCheckRun(name, headSHA, conclusion, title, summary string) error {
+	if a.client == nil || len(headSHA) == 0 {
+		return nil
+	}
+	if !a.config.features().checkRuns {
+		logger.Infof("Check runs are disabled, skip reporting %v as %v: %v\n", name, conclusion, title)
+		return nil
+	}
+
+	_, _, err := a.client.Checks.CreateCheckRun(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), ghapi.CreateCheckRunOptions{
+		Name:       name,
+		HeadSHA:    headSHA,
+		Status:     ghapi.String("completed"),
+		Conclusion: ghapi.String(conclusion),
+		Output: &ghapi.CheckRunOutput{
+			Title:   ghapi.String(title),
+			Summary: ghapi.String(summary),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create check run: %w", err)
+	}
+	return nil
+}
+
+// passCheckRun reports a successful check run on the current PR head.
+func (a *Action) passCheckRun() error {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	return a.createCheckRun(pr.HeadSHA, "success", "Documentation label is valid", "The PR has a valid documentation label.")
+}
@@ -0,0 +1,101 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// transientMarkers identify the bot comments which are obsolete once the PR is closed. Only the comments
+// written by the bot are minimized, whatever markers the others embed.
+var transientMarkers = []string{
+	markerPrefix + "reminder ",
+	markerPrefix + "rule ",
+	markerPrefix + "pending-labels ",
+	labelPickerMarker,
+	pingPongMarker,
+	releaseNoteMissingMarker,
+	staleMarker,
+}
+
+// onPullRequestClosed cleans up the enforcement artifacts of a closed PR: the missing label is
+// removed, obsolete bot comments are minimized, and the final labels are written to the labels output.
+func (a *Action) onPullRequestClosed() error {
+	if err := a.followUpDocs(); err != nil {
+		return fmt.Errorf("follow up docs: %w", err)
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	final := []string{}
+	for _, label := range labels {
+		if !a.config.isMissingLabel(label) {
+			final = append(final, label)
+			continue
+		}
+		logger.Infoln("@Remove missing label")
+		if err := a.provider.RemoveLabel(a.globalContext, a.config.GetNumber(), label); err != nil {
+			return fmt.Errorf("remove label %v: %w", label, err)
+		}
+	}
+	sort.Strings(final)
+	githubactions.SetOutput("labels", strings.Join(final, ","))
+
+	if err := a.resolveGuidance(); err != nil {
+		a.warn("Resolve guidance", err)
+	}
+
+	if a.client == nil {
+		return nil
+	}
+	comments, err := a.listBotComments()
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	for _, c := range comments {
+		if !isTransientComment(c.GetBody()) {
+			continue
+		}
+		logger.Infof("@Minimize comment %d\n", c.GetID())
+		err := ghapi.GraphQL(a.globalContext, a.client,
+			`mutation($id: ID!) { minimizeComment(input: {subjectId: $id, classifier: RESOLVED}) { clientMutationId } }`,
+			map[string]interface{}{"id": c.GetNodeID()}, nil)
+		if err != nil {
+			return fmt.Errorf("minimize comment %d: %w", c.GetID(), err)
+		}
+	}
+	return nil
+}
+
+func isTransientComment(body string) bool {
+	for _, marker := range transientMarkers {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/glob"
+)
+
+// labelHueTolerance is how far in degrees the hue of a label can be from the hue of its convention.
+const labelHueTolerance = 20
+
+// labelHue is the hue in degrees the colors of the labels matching glob share.
+type labelHue struct {
+	glob string
+	hue  float64
+}
+
+// labelColor is a label whose color is off the hue of its convention.
+type labelColor struct {
+	Label string
+	Color string
+	Hue   float64
+}
+
+// parseLabelHues parses LABEL_HUES, label globs mapped to hues in degrees separated by ",",
+// e.g. `doc*=210,area/*=30`. The first matching glob applies.
+func parseLabelHues(slug string) ([]labelHue, error) {
+	hues := []labelHue{}
+	for _, entry := range strings.Split(slug, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		pattern, hueSlug, ok := strings.Cut(entry, "=")
+		pattern = strings.TrimSpace(pattern)
+		hue, err := strconv.ParseFloat(strings.TrimSpace(hueSlug), 64)
+		if !ok || len(pattern) == 0 || err != nil || hue < 0 || hue >= 360 {
+			return nil, fmt.Errorf("%q is not label=hue with a hue in [0, 360)", entry)
+		}
+		hues = append(hues, labelHue{glob: pattern, hue: hue})
+	}
+	return hues, nil
+}
+
+// checkLabelColor returns the label with its color and expected hue if its color is off the hue of
+// the first of hues matching it, which gray colors always are.
+func checkLabelColor(hues []labelHue, label, color string) (labelColor, bool) {
+	for _, h := range hues {
+		if !glob.Match(h.glob, label) {
+			continue
+		}
+		hue, ok := colorHue(color)
+		if ok {
+			distance := math.Abs(hue - h.hue)
+			if math.Min(distance, 360-distance) <= labelHueTolerance {
+				return labelColor{}, false
+			}
+		}
+		return labelColor{Label: label, Color: color, Hue: h.hue}, true
+	}
+	return labelColor{}, false
+}
+
+// colorHue returns the hue in degrees of a hex color without the leading #, or false if it is gray or invalid.
+func colorHue(color string) (float64, bool) {
+	v, err := strconv.ParseUint(color, 16, 32)
+	if err != nil || len(color) != 6 {
+		return 0, false
+	}
+	r, g, b := float64(v>>16&0xff)/255, float64(v>>8&0xff)/255, float64(v&0xff)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	delta := max - min
+	if delta == 0 {
+		return 0, false
+	}
+
+	var hue float64
+	switch max {
+	case r:
+		hue = math.Mod((g-b)/delta, 6)
+	case g:
+		hue = (b-r)/delta + 2
+	default:
+		hue = (r-g)/delta + 4
+	}
+	hue *= 60
+	if hue < 0 {
+		hue += 360
+	}
+	return hue, true
+}
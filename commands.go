@@ -0,0 +1,121 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Commands of the bot, commented on a PR on their own first line.
+const (
+	// rerunCommand asks the bot to reconcile a PR again, e.g. after fixing the repository labels
+	rerunCommand = "/docbot rerun"
+	// undoCommand asks the bot to restore the labels and checkboxes before its latest label changes
+	undoCommand = "/docbot undo"
+)
+
+// commandPermissions are the repository permissions allowed to command the bot on any PR, besides its author
+// for the commands not in maintainerCommands.
+var commandPermissions = map[string]bool{"admin": true, "maintain": true, "write": true}
+
+// maintainerCommands are the commands only the collaborators of commandPermissions can run, not the PR author,
+// since they can change the labels enforced on the PR.
+var maintainerCommands = map[string]bool{undoCommand: true}
+
+// parseCommand returns the command on the first line of a comment body, or "" if there's none.
+func parseCommand(body string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	switch line = strings.TrimSpace(line); line {
+	case rerunCommand, undoCommand:
+		return line
+	}
+	return ""
+}
+
+// runCommand runs the command of a comment on the current PR.
+func (a *Action) runCommand(command string, comment *ghapi.IssueComment) error {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	if accepted, err := a.acceptCommand(pr, command, comment); err != nil || !accepted {
+		return err
+	}
+
+	logger.Infof("@Command %v on PR #%d by %v\n", command, pr.Number, comment.GetUser().GetLogin())
+	switch command {
+	case rerunCommand:
+		return a.rerun(pr)
+	case undoCommand:
+		if a.config.GetEnableAuditLog() {
+			defer a.auditChanges(command)()
+		}
+		return a.undo(pr)
+	}
+	return nil
+}
+
+// acceptCommand checks that the commenter of command can write to the repository, or is the PR author
+// if command isn't one of maintainerCommands.
+// The comment is acknowledged with a +1 reaction, or a -1 reaction if the commenter isn't allowed to command the bot.
+func (a *Action) acceptCommand(pr *scm.PullRequest, command string, comment *ghapi.IssueComment) (bool, error) {
+	commenter := comment.GetUser().GetLogin()
+	allowed := commenter == pr.Author && !maintainerCommands[command]
+	if !allowed {
+		level, _, err := a.client.Repositories.GetPermissionLevel(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), commenter)
+		if err != nil {
+			return false, fmt.Errorf("get permission of %v: %w", commenter, err)
+		}
+		allowed = commandPermissions[level.GetPermission()]
+	}
+
+	reaction := "+1"
+	if !allowed {
+		reaction = "-1"
+	}
+	if _, _, err := a.client.Reactions.CreateIssueCommentReaction(a.globalContext, a.config.GetOwner(), a.config.GetRepo(),
+		comment.GetID(), reaction); err != nil {
+		a.warn("React to command", err)
+	}
+	if !allowed {
+		logger.Infof("%v is not allowed to run %v on PR #%d, skip the command\n", commenter, command, pr.Number)
+		a.reportWarnings()
+		return false, nil
+	}
+	return true, nil
+}
+
+// rerun re-executes the full reconciliation of pr.
+func (a *Action) rerun(pr *scm.PullRequest) error {
+	labels := a.extractLabels(pr.Body)
+	if a.config.GetEnableLabelPicker() {
+		picker, err := a.ensureLabelPicker()
+		if err != nil {
+			return fmt.Errorf("ensure label picker: %w", err)
+		}
+		mergeLabels(labels, a.extractLabels(picker.GetBody()))
+	}
+	a.config.labels = labels
+
+	return a.Run("synchronize")
+}
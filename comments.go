@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// markerPrefix starts every hidden marker the bot embeds into its comments.
+const markerPrefix = "<!-- docbot:"
+
+// defaultBotLogin is the user of the GITHUB_TOKEN of the workflows.
+const defaultBotLogin = "github-actions[bot]"
+
+// resolveBotLogin sets BOT_LOGIN to the user of the token if unset: a personal access token labels and comments as its
+// user, so the labels it applied would otherwise look applied by a human. Installation tokens can't get their user,
+// and keep the default.
+func resolveBotLogin(ctx context.Context, ac *ActionConfig, client *ghapi.Client) {
+	if ac.botLogin != nil && len(*ac.botLogin) > 0 {
+		return
+	}
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		logger.Infof("Get the user of the token: %v, using %v as BOT_LOGIN", err, defaultBotLogin)
+		return
+	}
+	login := user.GetLogin()
+	ac.botLogin = &login
+}
+
+// listIssueComments lists all comments on the current PR.
+func (a *Action) listIssueComments() ([]*ghapi.IssueComment, error) {
+	listOptions := &ghapi.IssueListCommentsOptions{ListOptions: ghapi.ListOptions{PerPage: 100}}
+	comments := make([]*ghapi.IssueComment, 0)
+	for {
+		c, resp, err := a.client.Issues.ListComments(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return comments, nil
+}
+
+// listBotComments lists the comments on the current PR written by the bot, the only ones whose markers are trusted:
+// anyone can post a comment embedding a marker.
+func (a *Action) listBotComments() ([]*ghapi.IssueComment, error) {
+	comments, err := a.listIssueComments()
+	if err != nil {
+		return nil, err
+	}
+	botComments := make([]*ghapi.IssueComment, 0, len(comments))
+	for _, c := range comments {
+		if a.isBotComment(c) {
+			botComments = append(botComments, c)
+		}
+	}
+	return botComments, nil
+}
+
+// isBotComment reports whether c was written by the bot account of BOT_LOGIN.
+func (a *Action) isBotComment(c *ghapi.IssueComment) bool {
+	return strings.EqualFold(c.GetUser().GetLogin(), a.config.GetBotLogin())
+}
@@ -0,0 +1,120 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// ownersFile is the part of an OWNERS file, as used by Kubernetes, declaring the labels of its directory.
+type ownersFile struct {
+	Labels []string `yaml:"labels"`
+}
+
+// parseComponentFile returns the labels declared by the metadata file name: the labels of an OWNERS file,
+// or the lines of any other file, skipping empty lines and # comments.
+func parseComponentFile(name, content string) ([]string, error) {
+	if path.Base(name) == "OWNERS" {
+		owners := &ownersFile{}
+		if err := yaml.Unmarshal([]byte(content), owners); err != nil {
+			return nil, err
+		}
+		return owners.Labels, nil
+	}
+	labels := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 && !strings.HasPrefix(line, "#") {
+			labels = append(labels, line)
+		}
+	}
+	return labels, nil
+}
+
+// componentLabels returns the labels declared by the metadata files of COMPONENT_FILES closest to the changed files
+// of the current PR. From the directory of each file up to the root, the first directory with a metadata file declaring
+// labels wins. The metadata files are read from the default branch.
+func (a *Action) componentLabels() (map[string]bool, error) {
+	labels := make(map[string]bool)
+	if len(a.config.componentFiles) == 0 {
+		return labels, nil
+	}
+
+	files, err := a.listFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	// labels declared in each directory visited, nil if none
+	declared := make(map[string][]string)
+	for _, file := range files {
+		for dir := path.Dir(file.GetFilename()); ; dir = path.Dir(dir) {
+			dirLabels, visited := declared[dir]
+			if !visited {
+				if dirLabels, err = a.readComponentFiles(dir); err != nil {
+					return nil, err
+				}
+				declared[dir] = dirLabels
+			}
+			if len(dirLabels) > 0 {
+				for _, label := range dirLabels {
+					labels[label] = true
+				}
+				break
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+
+	return labels, nil
+}
+
+// readComponentFiles returns the labels declared by the first metadata file of COMPONENT_FILES in dir which declares any.
+func (a *Action) readComponentFiles(dir string) ([]string, error) {
+	for _, name := range a.config.componentFiles {
+		filePath := path.Join(dir, name)
+		content, _, resp, err := a.client.Repositories.GetContents(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), filePath, nil)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("get %v: %w", filePath, err)
+		}
+		data, err := content.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("decode %v: %w", filePath, err)
+		}
+		labels, err := parseComponentFile(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("parse %v: %w", filePath, err)
+		}
+		if len(labels) > 0 {
+			logger.Infof("%v declares %v\n", filePath, labels)
+			return labels, nil
+		}
+	}
+	return nil, nil
+}
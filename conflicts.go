@@ -0,0 +1,110 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// checkConflicts applies CONFLICT_LABEL to the current PR if it has merge conflicts,
+// asking the author to rebase, and removes it once the conflicts are resolved.
+func (a *Action) checkConflicts() error {
+	if len(a.config.GetConflictLabel()) == 0 || a.client == nil {
+		return nil
+	}
+
+	pr, err := a.getPullRequest()
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	return a.applyConflictLabel(pr)
+}
+
+func (a *Action) applyConflictLabel(pr *ghapi.PullRequest) error {
+	// GitHub computes mergeability in the background, mergeable is null until it's done
+	if pr.Mergeable == nil {
+		logger.Infof("Mergeability of #%d is not computed yet\n", pr.GetNumber())
+		return nil
+	}
+	conflicting := pr.GetMergeableState() == "dirty"
+
+	labeled := false
+	for _, label := range pr.Labels {
+		if label.GetName() == a.config.GetConflictLabel() {
+			labeled = true
+		}
+	}
+
+	switch {
+	case conflicting && !labeled:
+		logger.Infof("@Add conflict label to #%d\n", pr.GetNumber())
+		if err := a.provider.AddLabels(a.globalContext, pr.GetNumber(), []string{a.config.GetConflictLabel()}); err != nil {
+			return fmt.Errorf("add label %v: %w", a.config.GetConflictLabel(), err)
+		}
+		if len(a.config.GetConflictMessage()) > 0 {
+			scmPR, err := a.provider.GetPR(a.globalContext, pr.GetNumber())
+			if err != nil {
+				return fmt.Errorf("get PR: %w", err)
+			}
+			if err := a.comment(scmPR, a.config.GetConflictMessage()); err != nil {
+				return fmt.Errorf("comment: %w", err)
+			}
+		}
+	case !conflicting && labeled:
+		logger.Infof("@Remove conflict label from #%d\n", pr.GetNumber())
+		if err := a.provider.RemoveLabel(a.globalContext, pr.GetNumber(), a.config.GetConflictLabel()); err != nil {
+			return fmt.Errorf("remove label %v: %w", a.config.GetConflictLabel(), err)
+		}
+	}
+	return nil
+}
+
+// runConflicts checks the open PRs for merge conflicts after a push to the repository.
+func runConflicts(ctx context.Context, ac *ActionConfig) error {
+	if len(ac.GetConflictLabel()) == 0 {
+		return nil
+	}
+	return checkAllConflicts(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+}
+
+// checkAllConflicts checks the open PRs of the repository for merge conflicts with client.
+// A PR failing doesn't stop checking the others, but fails the run.
+func checkAllConflicts(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	logger.Infoln("@List open PRs")
+	prs, err := listOpenPullRequests(ctx, client, ac.GetOwner(), ac.GetRepo())
+	if err != nil {
+		return fmt.Errorf("list open PRs: %w", err)
+	}
+	failed := 0
+	for _, pr := range prs {
+		action := newPullRequestAction(ctx, ac, client, ac.GetOwner(), ac.GetRepo(), pr)
+		// The PR list doesn't include mergeability
+		if err := action.checkConflicts(); err != nil {
+			failed++
+			logger.Errorf("#%d: %v\n", pr.GetNumber(), err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("check conflicts: %d of %d PRs failed", failed, len(prs))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer delays a function per key until it was not scheduled again for window,
+// so that a burst of events for one PR is handled once, with the latest event.
+type debouncer struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer
+}
+
+func newDebouncer(window time.Duration) *debouncer {
+	return &debouncer{window: window, pending: make(map[string]*time.Timer)}
+}
+
+// debounce runs fn after window, unless debounce is called again for key in the meantime,
+// in which case fn is dropped in favor of the later one.
+func (d *debouncer) debounce(key string, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, exist := d.pending[key]; exist {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		// a timer that fired while being replaced is stale
+		if d.pending[key] != t {
+			d.mu.Unlock()
+			return
+		}
+		delete(d.pending, key)
+		d.mu.Unlock()
+
+		fn()
+	})
+	d.pending[key] = t
+}
@@ -0,0 +1,149 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/notify"
+)
+
+const digestMarker = markerPrefix + "digest -->"
+
+// runDigest opens or updates the tracking issue listing the PRs labeled DIGEST_LABEL
+// merged in the last DIGEST_DAYS days, which no other PR references yet.
+func runDigest(ctx context.Context, ac *ActionConfig) error {
+	return updateDigest(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+}
+
+// updateDigest opens or updates the digest issue of the repository with client.
+func updateDigest(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	since := time.Now().AddDate(0, 0, -ac.GetDigestDays())
+	query := fmt.Sprintf("repo:%s/%s is:pr is:merged label:%q merged:>=%s",
+		ac.GetOwner(), ac.GetRepo(), ac.GetDigestLabel(), since.Format("2006-01-02"))
+	logger.Infof("@Search PRs: %v\n", query)
+	prs, err := searchIssues(ctx, client, query)
+	if err != nil {
+		return fmt.Errorf("search PRs: %w", err)
+	}
+
+	pending := make([]*ghapi.Issue, 0)
+	for _, pr := range prs {
+		followed, err := hasFollowUpPR(ctx, client, ac.GetOwner(), ac.GetRepo(), pr.GetNumber())
+		if err != nil {
+			logger.Errorf("#%d: %v\n", pr.GetNumber(), err)
+			continue
+		}
+		if !followed {
+			pending = append(pending, pr)
+		}
+	}
+	logger.Infof("PRs without follow-up: %v/%v\n", len(pending), len(prs))
+
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "%s\nPRs labeled `%s` merged since %s without a follow-up PR:\n\n",
+		digestMarker, ac.GetDigestLabel(), since.Format("2006-01-02"))
+	for _, pr := range pending {
+		fmt.Fprintf(body, "- [ ] #%d %s (@%s)\n", pr.GetNumber(), pr.GetTitle(), pr.GetUser().GetLogin())
+	}
+	if len(pending) == 0 {
+		body.WriteString("Nothing to do :tada:\n")
+	}
+	githubactions.AddStepSummary(body.String())
+
+	issue, err := findDigestIssue(ctx, client, ac.GetOwner(), ac.GetRepo())
+	if err != nil {
+		return fmt.Errorf("find digest issue: %w", err)
+	}
+	request := &ghapi.IssueRequest{Title: ghapi.String(ac.GetDigestTitle()), Body: ghapi.String(body.String())}
+	if issue == nil {
+		logger.Infoln("@Create digest issue")
+		_, _, err = client.Issues.Create(ctx, ac.GetOwner(), ac.GetRepo(), request)
+	} else {
+		logger.Infof("@Update digest issue #%d\n", issue.GetNumber())
+		_, _, err = client.Issues.Edit(ctx, ac.GetOwner(), ac.GetRepo(), issue.GetNumber(), request)
+	}
+	if err != nil {
+		return fmt.Errorf("save digest issue: %w", err)
+	}
+
+	items := make([]notify.Item, 0, len(pending))
+	for _, pr := range pending {
+		items = append(items, notify.Item{Number: pr.GetNumber(), Title: pr.GetTitle(), Author: pr.GetUser().GetLogin(), URL: pr.GetHTMLURL()})
+	}
+	return notifyAll(ctx, ac, &notify.Message{Event: "digest", Title: fmt.Sprintf("PRs labeled %s without a follow-up PR", ac.GetDigestLabel()), Items: items})
+}
+
+func searchIssues(ctx context.Context, client *ghapi.Client, query string) ([]*ghapi.Issue, error) {
+	searchOptions := &ghapi.SearchOptions{ListOptions: ghapi.ListOptions{PerPage: 100}}
+	issues := make([]*ghapi.Issue, 0)
+	for {
+		result, resp, err := client.Search.Issues(ctx, query, searchOptions)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, result.Issues...)
+		if resp.NextPage == 0 {
+			break
+		}
+		searchOptions.Page = resp.NextPage
+	}
+	return issues, nil
+}
+
+// hasFollowUpPR reports whether another PR, possibly in another repo, references the PR.
+func hasFollowUpPR(ctx context.Context, client *ghapi.Client, owner, repo string, number int) (bool, error) {
+	timeline, err := listIssueTimeline(ctx, client, owner, repo, number)
+	if err != nil {
+		return false, err
+	}
+	for _, event := range timeline {
+		if event.GetEvent() == "cross-referenced" && event.GetSource().GetIssue().IsPullRequest() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findDigestIssue returns the open digest issue, or nil if there's none.
+func findDigestIssue(ctx context.Context, client *ghapi.Client, owner, repo string) (*ghapi.Issue, error) {
+	listOptions := &ghapi.IssueListByRepoOptions{State: "open", ListOptions: ghapi.ListOptions{PerPage: 100}}
+	for {
+		issues, resp, err := client.Issues.ListByRepo(ctx, owner, repo, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && strings.Contains(issue.GetBody(), digestMarker) {
+				return issue, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.ListOptions.Page = resp.NextPage
+	}
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// dispatchOperations maps a repository_dispatch operation to the action event it runs for each PR.
+var dispatchOperations = map[string]string{
+	// reconcile applies the labels from the PR body, as if the PR was edited
+	"reconcile": "edited",
+	// sync-labels writes the current labels back to the PR body, as if the PR was labeled
+	"sync-labels": "labeled",
+}
+
+// dispatchRequest is the client_payload of a repository_dispatch event.
+type dispatchRequest struct {
+	Operation string `json:"operation"`
+	Numbers   []int  `json:"numbers"`
+}
+
+// parseDispatchPayload validates the client_payload of a repository_dispatch event.
+// Except for report, the payload must list the PR numbers to run the operation on.
+func parseDispatchPayload(payload json.RawMessage) (*dispatchRequest, error) {
+	req := &dispatchRequest{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return nil, err
+		}
+	}
+	if req.Operation == "report" {
+		return req, nil
+	}
+	if _, ok := dispatchOperations[req.Operation]; !ok {
+		return nil, fmt.Errorf("unknown dispatch operation %q", req.Operation)
+	}
+	if len(req.Numbers) == 0 {
+		return nil, fmt.Errorf("dispatch payload has no PR numbers")
+	}
+	return req, nil
+}
+
+// runDispatch runs the operation of a repository_dispatch event.
+func runDispatch(ctx context.Context, ac *ActionConfig, req *dispatchRequest) error {
+	if req.Operation == "report" {
+		return runReport(ctx, ac)
+	}
+
+	client := newGitHubClient(ctx, ac.GetToken(), ac.transport())
+
+	return dispatch(ctx, ac, client, req)
+}
+
+// dispatch runs the operation on each PR of req in turn, fetching the repo labels once for all of them.
+func dispatch(ctx context.Context, ac *ActionConfig, client *ghapi.Client, req *dispatchRequest) error {
+	repoLabels := newRepoLabelCache()
+	failed := 0
+	for _, number := range req.Numbers {
+		if err := dispatchPullRequest(ctx, ac, client, repoLabels, number, dispatchOperations[req.Operation]); err != nil {
+			failed++
+			logger.Errorf("#%d: %v\n", number, err)
+		}
+	}
+	logger.Infof("Dispatch %v done: %v processed, %v failed\n", req.Operation, len(req.Numbers), failed)
+
+	if failed > 0 {
+		return fmt.Errorf("dispatch %v failed for %v PRs", req.Operation, failed)
+	}
+	return nil
+}
+
+func dispatchPullRequest(ctx context.Context, ac *ActionConfig, client *ghapi.Client, repoLabels *repoLabelCache, number int, actionType string) error {
+	logger.Infof("@Get PR #%d\n", number)
+	pr, _, err := client.PullRequests.Get(ctx, ac.GetOwner(), ac.GetRepo(), number)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	if pr.GetState() != "open" {
+		logger.Infof("PR #%d is %v, skipping\n", number, pr.GetState())
+		return nil
+	}
+	return newPullRequestAction(ctx, ac, client, ac.GetOwner(), ac.GetRepo(), pr).shareRepoLabels(repoLabels).Run(actionType)
+}
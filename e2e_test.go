@@ -0,0 +1,2859 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/ghapp"
+	"github.com/maxsxu/action-labeler/pkg/ghtest"
+	"github.com/maxsxu/action-labeler/pkg/hook"
+	"github.com/maxsxu/action-labeler/pkg/oidc"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+	"github.com/maxsxu/action-labeler/pkg/store"
+)
+
+const testBody = "### Documentation\r\n\r\n- [%s] `doc`\r\n- [%s] `doc-required`\r\n- [%s] `doc-not-needed`\r\n- [ ] `doc-complete`\r\n"
+
+func newTestServer(t *testing.T) *ghtest.Server {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "doc-required", "doc-not-needed", "doc-complete", "doc-label-missing")
+	t.Cleanup(s.Close)
+	return s
+}
+
+// runEvent runs the action for a pull_request event with the current PR body on s.
+func runEvent(t *testing.T, s *ghtest.Server, number int, event string) error {
+	action := newTestAction(t, s, number)
+	action.config.labels = action.extractLabels(s.Body(number))
+	return action.Run(event)
+}
+
+// newWebhookRequest creates the webhook request of a GitHub delivery of event, signed with WEBHOOK_SECRET.
+func newWebhookRequest(event, deliveryID, payload string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("WEBHOOK_SECRET")))
+	mac.Write([]byte(payload))
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", deliveryID)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+// newTestAction creates an action for PR number on s with the test inputs.
+func newTestAction(t *testing.T, s *ghtest.Server, number int) *Action {
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	if _, set := os.LookupEnv("LABEL_WATCH_LIST"); !set {
+		t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	}
+	if len(os.Getenv("LABEL_MISSING")) == 0 {
+		t.Setenv("LABEL_MISSING", "doc-label-missing")
+	}
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+
+	provider := newCachedProvider(&versionProvider{Provider: newFeatureProvider(scm.NewGitHub(client, s.Owner, s.Repo), ac)})
+	action := &Action{
+		config:        ac,
+		globalContext: ctx,
+		client:        client,
+		provider:      provider,
+		cache:         provider.cache,
+	}
+	ac.number = &number
+	return action
+}
+
+func assertLabels(t *testing.T, s *ghtest.Server, number int, want ...string) {
+	t.Helper()
+	got := s.Labels(number)
+	if want == nil {
+		want = []string{}
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("labels = %v, want %v", got, want)
+	}
+}
+
+func TestOpenedEditedLabeled(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	// opened without label
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], "\n@alice ") {
+		t.Fatalf("comments = %q, want one reminder", comments)
+	}
+	if comments := s.Comments(1); !strings.HasSuffix(comments[0], "\n"+versionMarker()) {
+		t.Fatalf("comment = %q, want the version marker", comments[0])
+	}
+
+	// edited to check a label
+	s.SetBody(1, strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc-required`", "[x] `doc-required`", 1))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+
+	// labeled by a maintainer
+	s.SetLabels(1, "doc-complete")
+	if err := runEvent(t, s, 1, "labeled"); err != nil {
+		t.Fatalf("labeled: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-complete")
+	if body := s.Body(1); !strings.Contains(body, "- [x] `doc-complete`") || !strings.Contains(body, "- [ ] `doc-required`") {
+		t.Fatalf("body = %q, want doc-complete checked only", body)
+	}
+}
+
+func TestEditedMultipleLabels(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[x]"), "[x] `doc-not-needed`", "[ ] `doc-not-needed`", 1))
+
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMultiple {
+		t.Fatalf("opened: err = %v, want multiple labels", err)
+	}
+	assertLabels(t, s, 1)
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want one reminder", comments)
+	}
+}
+
+func TestLabelNamespaces(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "doc-required", "doc-not-needed", "doc-complete", "doc-label-missing",
+		"area/broker", "area/client", "release/3.0", "release/4.0")
+	t.Cleanup(s.Close)
+	body := strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1)
+	s.AddPullRequest(1, "alice", body+"- [x] `area/broker`\r\n- [x] `area/client`\r\n- [ ] `release/3.0`\r\n")
+
+	t.Setenv("LABEL_NAMESPACES", "area/=many,release/=optional")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "area/broker", "area/client")
+
+	// the area namespace requires a label
+	s.SetBody(1, body+"- [ ] `area/broker`\r\n- [x] `release/3.0`\r\n")
+	if err := runEvent(t, s, 1, "edited"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("edited: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1, "doc", "release/3.0", "doc-label-missing")
+
+	// the release namespace allows one label at most
+	s.SetBody(1, body+"- [x] `area/broker`\r\n- [x] `release/3.0`\r\n- [x] `release/4.0`\r\n")
+	if err := runEvent(t, s, 1, "edited"); err == nil || err.Error() != MessageLabelMultiple {
+		t.Fatalf("edited: err = %v, want multiple labels", err)
+	}
+}
+
+func TestMissingLabelPerCategory(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "doc-required", "doc-not-needed", "doc-complete", "doc-label-missing",
+		"area/broker", "area-label-missing")
+	t.Cleanup(s.Close)
+	body := strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1)
+	s.AddPullRequest(1, "alice", body+"- [ ] `area/broker`\r\n")
+
+	t.Setenv("LABEL_NAMESPACES", "area/")
+	t.Setenv("LABEL_MISSING", "default=doc-label-missing,area/=area-label-missing")
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1, "doc", "area-label-missing")
+
+	s.SetBody(1, body+"- [x] `area/broker`\r\n")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "area/broker")
+}
+
+func TestMissingOnUnlabeled(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1))
+
+	// nothing happens when the last label is removed
+	t.Setenv("MISSING_ON_UNLABELED", "none")
+	if err := runEvent(t, s, 1, "unlabeled"); err != nil {
+		t.Fatalf("unlabeled: %v", err)
+	}
+	assertLabels(t, s, 1)
+
+	// the label is swapped within the grace period
+	t.Setenv("MISSING_ON_UNLABELED", "grace")
+	sleep = func(time.Duration) { s.SetLabels(1, "doc-required") }
+	t.Cleanup(func() { sleep = time.Sleep })
+	if err := runEvent(t, s, 1, "unlabeled"); err != nil {
+		t.Fatalf("unlabeled: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+
+	// no label is selected again within the grace period
+	s.SetLabels(1)
+	sleep = func(time.Duration) {}
+	if err := runEvent(t, s, 1, "unlabeled"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("unlabeled: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+}
+
+func TestDocsApproval(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc-required`", "[x] `doc-required`", 1))
+	s.AddTeamMember("apache/docs", "dave")
+
+	t.Setenv("DOCS_TEAM", "apache/docs")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertDocsApproval := func(want string) {
+		t.Helper()
+		checkRuns := s.CheckRuns()
+		if len(checkRuns) == 0 || checkRuns[len(checkRuns)-1].Name != docsApprovalCheckRunName || checkRuns[len(checkRuns)-1].Conclusion != want {
+			t.Fatalf("check runs = %+v, want docs approval %v", checkRuns, want)
+		}
+	}
+	assertDocsApproval("failure")
+
+	// approvals from outside the team don't count
+	s.AddReview(1, "bob", "APPROVED")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertDocsApproval("failure")
+
+	s.AddReview(1, "dave", "APPROVED")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertDocsApproval("success")
+}
+
+func TestChecklistAnnotations(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/PULL_REQUEST_TEMPLATE.md", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	assertAnnotatedLines := func(want ...int) {
+		t.Helper()
+		checkRuns := s.CheckRuns()
+		if len(checkRuns) == 0 {
+			t.Fatalf("no check run, want annotations")
+		}
+		lines := []int{}
+		for _, annotation := range checkRuns[len(checkRuns)-1].Output.Annotations {
+			if annotation.Path != ".github/PULL_REQUEST_TEMPLATE.md" || annotation.StartLine != annotation.EndLine {
+				t.Errorf("annotation = %+v", annotation)
+			}
+			lines = append(lines, annotation.StartLine)
+		}
+		if !reflect.DeepEqual(lines, want) {
+			t.Fatalf("annotated lines = %v, want %v", lines, want)
+		}
+	}
+
+	// all the unchecked boxes of the watch list
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertAnnotatedLines(3, 4, 5, 6)
+
+	// only the checked boxes when multiple are checked
+	s.SetBody(1, fmt.Sprintf(testBody, "x", "x", " "))
+	if err := runEvent(t, s, 1, "edited"); err == nil || err.Error() != MessageLabelMultiple {
+		t.Fatalf("edited: err = %v, want multiple labels", err)
+	}
+	assertAnnotatedLines(3, 4)
+}
+
+func TestAuthorAssociationRules(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `author_associations:
+  - associations: [OWNER, MEMBER]
+    enforcement: warn
+  - associations: [FIRST_TIME_CONTRIBUTOR]
+    message_missing: Please check one documentation label, a maintainer will help if unsure.
+`)
+	s.AddFile(".github/PULL_REQUEST_TEMPLATE.md", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+
+	// members get a warning annotation only
+	s.AddPullRequest(1, "alice", body)
+	s.SetAuthorAssociation(1, "MEMBER")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened by member: %v", err)
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Errorf("comments = %v, want none", comments)
+	}
+	checkRuns := s.CheckRuns()
+	if len(checkRuns) != 1 || checkRuns[0].Conclusion != "neutral" || len(checkRuns[0].Output.Annotations) != 4 {
+		t.Fatalf("check runs = %+v, want a neutral one annotating 4 checkboxes", checkRuns)
+	}
+	if level := checkRuns[0].Output.Annotations[0].Level; level != "warning" {
+		t.Errorf("annotation level = %v, want warning", level)
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+
+	// first-time contributors get the full reminder with their own message
+	s.AddPullRequest(2, "bob", body)
+	s.SetAuthorAssociation(2, "FIRST_TIME_CONTRIBUTOR")
+	err := runEvent(t, s, 2, "opened")
+	if err == nil || !strings.HasPrefix(err.Error(), "Please check one documentation label") {
+		t.Fatalf("opened by first-time contributor: err = %v, want the configured message", err)
+	}
+	if comments := s.Comments(2); len(comments) != 1 || !strings.Contains(comments[0], "a maintainer will help") {
+		t.Errorf("comments = %v, want the configured message", comments)
+	}
+
+	// other authors keep the default enforcement
+	s.AddPullRequest(3, "carol", body)
+	s.SetAuthorAssociation(3, "CONTRIBUTOR")
+	if err := runEvent(t, s, 3, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened by contributor: err = %v, want missing label", err)
+	}
+}
+
+func TestExemptTeams(t *testing.T) {
+	s := newTestServer(t)
+	s.AddTeamMember("apache/docs", "alice")
+	t.Setenv("EXEMPT_TEAMS", "apache/docs")
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+
+	// members of the team skip the missing label check
+	s.AddPullRequest(1, "alice", body)
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened by member: %v", err)
+	}
+	assertLabels(t, s, 1)
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Errorf("comments = %v, want none", comments)
+	}
+
+	s.AddPullRequest(2, "dave", body)
+	if err := runEvent(t, s, 2, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened by non-member: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 2, "doc-label-missing")
+
+	// the membership is cached
+	s.AddTeamMember("apache/docs", "dave")
+	if err := runEvent(t, s, 2, "edited"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("edited after joining: err = %v, want missing label from the cached membership", err)
+	}
+}
+
+func TestAssignAuthorOnMissing(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ASSIGN_AUTHOR_ON_MISSING", "true")
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	assertAssignees := func(want ...string) {
+		t.Helper()
+		if got := s.Assignees(1); !reflect.DeepEqual(got, append([]string{}, want...)) {
+			t.Fatalf("assignees = %v, want %v", got, want)
+		}
+	}
+
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertAssignees("alice")
+
+	s.SetBody(1, fmt.Sprintf(testBody, "x", " ", " "))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+	assertAssignees()
+}
+
+func TestReviewerMatrix(t *testing.T) {
+	s := newTestServer(t)
+	body := fmt.Sprintf(testBody, "x", " ", " ")
+	s.AddPullRequest(1, "alice", body)
+	s.AddPullRequest(2, "bob", body)
+	s.AddPullRequest(3, "alice", body)
+	t.Setenv("REVIEWER_MATRIX", "doc:bob,carol;doc-required:dave")
+
+	assertReviewers := func(number int, want ...string) {
+		t.Helper()
+		if got := s.RequestedReviewers(number); !reflect.DeepEqual(got, want) {
+			t.Fatalf("reviewers of #%d = %v, want %v", number, got, want)
+		}
+	}
+
+	// round robin by PR number, requested once per label
+	for _, event := range []string{"opened", "edited"} {
+		if err := runEvent(t, s, 1, event); err != nil {
+			t.Fatalf("%v: %v", event, err)
+		}
+	}
+	assertReviewers(1, "carol")
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], "Requested review from @carol for `doc`.") {
+		t.Fatalf("comments = %q, want one reviewer comment", comments)
+	}
+
+	// the author doesn't review their own PR
+	if err := runEvent(t, s, 2, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertReviewers(2, "carol")
+
+	// the reviewer with the fewest pending reviews is picked
+	t.Setenv("REVIEWER_ASSIGNMENT", "least-assigned")
+	if err := runEvent(t, s, 3, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertReviewers(3, "bob")
+}
+
+func TestOnboardingReminder(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	s.AddPullRequest(1, "alice", body)
+	s.AddPullRequest(2, "bob", body)
+	s.AddPullRequest(3, "carol", body)
+	s.SetAuthorAssociation(1, "FIRST_TIME_CONTRIBUTOR")
+	s.SetAuthorAssociation(2, "CONTRIBUTOR")
+	s.SetAuthorAssociation(3, "FIRST_TIMER")
+
+	for number := 1; number <= 2; number++ {
+		if err := runEvent(t, s, number, "opened"); err == nil || err.Error() != MessageLabelMissing {
+			t.Fatalf("opened #%d: err = %v, want missing label", number, err)
+		}
+	}
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], "Thanks for your first contribution!") ||
+		!strings.Contains(comments[0], "- [ ] `doc`\n- [ ] `doc-complete`\n- [ ] `doc-not-needed`\n- [ ] `doc-required`\n") {
+		t.Fatalf("comments = %q, want the onboarding reminder with the checklist", comments)
+	}
+	if comments := s.Comments(2); len(comments) != 1 || strings.Contains(comments[0], "Thanks for your first contribution!") {
+		t.Fatalf("comments = %q, want the usual reminder", comments)
+	}
+
+	t.Setenv("ENABLE_ONBOARDING", "false")
+	if err := runEvent(t, s, 3, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	if comments := s.Comments(3); len(comments) != 1 || strings.Contains(comments[0], "Thanks for your first contribution!") {
+		t.Fatalf("comments = %q, want the usual reminder when onboarding is disabled", comments)
+	}
+}
+
+func TestLabelPicker(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	t.Setenv("ENABLE_LABEL_PICKER", "true")
+
+	action := newTestAction(t, s, 1)
+	for i := 0; i < 2; i++ {
+		if _, err := action.ensureLabelPicker(); err != nil {
+			t.Fatalf("ensureLabelPicker: %v", err)
+		}
+	}
+	comments, err := action.listBotComments()
+	if err != nil || len(comments) != 1 || !strings.Contains(comments[0].GetBody(), "- [ ] `doc-required`") {
+		t.Fatalf("bot comments = %v, %v, want one label picker", comments, err)
+	}
+	picker := comments[0]
+
+	// a picker pasted by someone else is ignored
+	forged := &ghapi.IssueComment{Body: ghapi.String(labelPickerMarker + "\n- [x] `doc-not-needed`"), User: &ghapi.User{Login: ghapi.String("mallory")}}
+	if err := newTestAction(t, s, 1).onLabelPickerEdited(forged); err != nil {
+		t.Fatalf("onLabelPickerEdited: %v", err)
+	}
+	assertLabels(t, s, 1)
+
+	// ticking a box of the picker of the bot labels the PR
+	picker.Body = ghapi.String(strings.Replace(picker.GetBody(), "- [ ] `doc-required`", "- [x] `doc-required`", 1))
+	if err := newTestAction(t, s, 1).onLabelPickerEdited(picker); err != nil {
+		t.Fatalf("onLabelPickerEdited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+}
+
+func TestProjectColumns(t *testing.T) {
+	s := newTestServer(t)
+	s.AddProject("apache/7", "Todo", "Needs docs", "Done")
+	t.Setenv("PROJECT", "apache/7")
+	t.Setenv("PROJECT_COLUMNS", "doc-required=Needs docs,doc-complete=Done")
+
+	// not moved without a mapped label
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	if status := s.ProjectStatus("apache/7", 1); status != "" {
+		t.Fatalf("status = %q, want not in the project", status)
+	}
+
+	s.SetBody(1, fmt.Sprintf(testBody, " ", "x", " "))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	if status := s.ProjectStatus("apache/7", 1); status != "Needs docs" {
+		t.Fatalf("status = %q, want Needs docs", status)
+	}
+
+	// moved along with the labels
+	s.SetLabels(1, "doc-complete")
+	if err := runEvent(t, s, 1, "labeled"); err != nil {
+		t.Fatalf("labeled: %v", err)
+	}
+	if status := s.ProjectStatus("apache/7", 1); status != "Done" {
+		t.Fatalf("status = %q, want Done", status)
+	}
+}
+
+func TestLandedDocs(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("DOCS_PATHS", "site/docs/**")
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", "")
+	}
+	s.SetLabels(1, "doc-pending")
+	s.SetLabels(3, "doc-pending")
+
+	push := func(ref string) error {
+		t.Helper()
+		payload := fmt.Sprintf(`{"ref": %q, "repository": {"default_branch": "master"}, "commits": [
+			{"message": "Docs for #1 and #2", "added": ["site/docs/broker.md"]},
+			{"message": "Fix #3", "modified": ["pom.xml"]}]}`, ref)
+		event, err := ghapi.ParseWebHook("push", []byte(payload))
+		if err != nil {
+			t.Fatalf("parse payload: %v", err)
+		}
+		ac, err := NewActionConfig()
+		if err != nil {
+			t.Fatalf("NewActionConfig: %v", err)
+		}
+		client := newGitHubClient(context.Background(), "", nil)
+		client.BaseURL, _ = url.Parse(s.BaseURL())
+		return completeLandedDocs(context.Background(), ac, client, event.(*ghapi.PushEvent))
+	}
+
+	// only pushes to the default branch complete docs
+	if err := push("refs/heads/branch-2.11"); err != nil {
+		t.Fatalf("push to branch: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-pending")
+
+	if err := push("refs/heads/master"); err != nil {
+		t.Fatalf("push to default branch: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-complete")
+	// not pending, or not referenced by a commit changing docs
+	assertLabels(t, s, 2)
+	assertLabels(t, s, 3, "doc-pending")
+}
+
+func TestLabelSync(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	s.SetLabelMeta("doc", "Documentation", "0075ca")
+	s.SetLabelMeta("doc-required", "Needs docs", "ededed")
+	s.AddFile(".github/docbot.yml", `labels:
+  - name: doc
+    description: Documentation
+    color: "#0075CA"
+  - name: doc-required
+    description: The PR needs documentation
+    color: d73a4a
+  - name: doc-not-needed
+    description: The PR needs no documentation
+  - name: doc-unknown
+    description: Not in the repository
+`)
+
+	sync := func() error {
+		t.Helper()
+		ac, err := NewActionConfig()
+		if err != nil {
+			t.Fatalf("NewActionConfig: %v", err)
+		}
+		client := newGitHubClient(context.Background(), "", nil)
+		client.BaseURL, _ = url.Parse(s.BaseURL())
+		return syncLabels(context.Background(), ac, client)
+	}
+
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	t.Setenv("LABEL_SYNC_DRY_RUN", "true")
+	if err := sync(); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got := s.LabelMeta("doc-required"); got.Description != "Needs docs" {
+		t.Fatalf("dry run updated doc-required: %+v", got)
+	}
+	data, _ := os.ReadFile(summary)
+	if diff := "@@ doc-required @@\n-description: \"Needs docs\"\n+description: \"The PR needs documentation\"\n-color: ededed\n+color: d73a4a\n"; !strings.Contains(string(data), diff) {
+		t.Fatalf("summary = %q, want diff %q", data, diff)
+	}
+
+	t.Setenv("LABEL_SYNC_DRY_RUN", "false")
+	if err := sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	want := map[string]ghtest.Label{
+		"doc":            {Name: "doc", Description: "Documentation", Color: "0075ca"},
+		"doc-required":   {Name: "doc-required", Description: "The PR needs documentation", Color: "d73a4a"},
+		"doc-not-needed": {Name: "doc-not-needed", Description: "The PR needs no documentation"},
+	}
+	for name, label := range want {
+		if got := s.LabelMeta(name); got != label {
+			t.Fatalf("label %v = %+v, want %+v", name, got, label)
+		}
+	}
+}
+
+func TestLabelUsage(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete,doc-legacy")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+	s.AddFile(".github/docbot.yml", `labels:
+  - name: doc
+  - name: doc-deprecated
+`)
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", "")
+	}
+	s.SetLabels(1, "doc")
+	s.SetLabels(2, "doc-required")
+	s.SetState(2, "closed")
+	s.SetLabels(3, "doc-label-missing")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	client := newGitHubClient(context.Background(), "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	usage, err := reportLabelUsage(context.Background(), ac, client)
+	if err != nil {
+		t.Fatalf("reportLabelUsage: %v", err)
+	}
+	want := &labelUsage{
+		Unused:     []string{"doc-complete", "doc-legacy", "doc-not-needed"},
+		Deleted:    []string{"doc-deprecated", "doc-legacy"},
+		Miscolored: []labelColor{},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Fatalf("usage = %+v, want %+v", usage, want)
+	}
+}
+
+func TestAppServer(t *testing.T) {
+	s := newTestServer(t)
+	s.AddInstallation(42)
+	s.AddFile(".github/docbot.yml", "labels:\n  - name: doc\n")
+	body := fmt.Sprintf(testBody, "x", " ", " ")
+	s.AddPullRequest(1, "alice", body)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	t.Setenv("MODE", "server")
+	t.Setenv("WEBHOOK_SECRET", "secret")
+	t.Setenv("APP_ID", "7")
+	t.Setenv("APP_PRIVATE_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})))
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+
+	ctx := context.Background()
+	app, err := ghapp.New(ac.GetAppID(), []byte(ac.GetAppPrivateKey()), func(token string) *ghapi.Client {
+		client := newGitHubClient(ctx, token, nil)
+		client.BaseURL, _ = url.Parse(s.BaseURL())
+		return client
+	})
+	if err != nil {
+		t.Fatalf("ghapp.New: %v", err)
+	}
+	srv := &server{config: ac, app: app, repoConfigs: make(map[string]*repoConfig)}
+	if err := srv.discover(ctx); err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if c := srv.repoConfigs["apache/pulsar"]; c == nil || len(c.fileConfig.Labels) != 1 {
+		t.Fatalf("repo configs = %v, want the config of apache/pulsar", srv.repoConfigs)
+	}
+
+	payload := fmt.Sprintf(`{"action": "opened", "number": 1, "installation": {"id": 42},
+		"repository": {"name": "pulsar", "full_name": "apache/pulsar", "owner": {"login": "apache"}},
+		"pull_request": {"number": 1, "body": %q, "user": {"login": "alice"}}}`, body)
+	w := httptest.NewRecorder()
+	srv.handleWebhook(w, newWebhookRequest("pull_request", "1", payload))
+	if w.Code != http.StatusOK {
+		t.Fatalf("webhook: %v %v", w.Code, w.Body)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	// the installation token of the discovery is reused, and was requested with a JWT signed by the app
+	requests := s.TokenRequests(42)
+	if len(requests) != 1 {
+		t.Fatalf("token requests = %d, want 1", len(requests))
+	}
+	parts := strings.Split(strings.TrimPrefix(requests[0], "Bearer "), ".")
+	if len(parts) != 3 {
+		t.Fatalf("authorization = %q, want a JWT", requests[0])
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Fatalf("verify JWT: %v", err)
+	}
+
+	if login, err := app.BotLogin(ctx); err != nil || login != "github-actions[bot]" {
+		t.Fatalf("BotLogin = %v, %v, want github-actions[bot]", login, err)
+	}
+}
+
+func TestDebouncedEdits(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", " ", " "))
+
+	t.Setenv("MODE", "server")
+	t.Setenv("WEBHOOK_SECRET", "secret")
+	t.Setenv("DEBOUNCE_WINDOW", "50ms")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	client := newGitHubClient(context.Background(), "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	st, err := store.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	srv := &server{config: ac, client: client, store: st, debouncer: newDebouncer(ac.GetDebounceWindow())}
+
+	// the author ticks a box, unticks it, then ticks another one
+	for i, body := range []string{
+		fmt.Sprintf(testBody, "x", " ", " "),
+		fmt.Sprintf(testBody, " ", " ", " "),
+		fmt.Sprintf(testBody, " ", "x", " "),
+	} {
+		s.SetBody(1, body)
+		payload := fmt.Sprintf(`{"action": "edited", "number": 1,
+			"repository": {"name": "pulsar", "full_name": "apache/pulsar", "owner": {"login": "apache"}},
+			"pull_request": {"number": 1, "body": %q, "user": {"login": "alice"}, "updated_at": "2024-05-02T09:00:0%dZ"}}`, body, i)
+		w := httptest.NewRecorder()
+		srv.handleWebhook(w, newWebhookRequest("pull_request", fmt.Sprint(i), payload))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("webhook: %v %v", w.Code, w.Body)
+		}
+	}
+
+	// the delivery is only handled, and can't be redelivered, once the action ran
+	if seen, _ := st.HasDelivery("2"); seen {
+		t.Fatalf("delivery 2 marked as handled before running")
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(s.Labels(1)) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertLabels(t, s, 1, "doc-required")
+	// the unlabeled body in between was never handled
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if seen, _ := st.HasDelivery("2"); seen {
+			return
+		}
+	}
+	t.Fatalf("delivery 2 not marked as handled")
+}
+
+func TestSelftest(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+
+	// a designated PR gets its body and labels back
+	s.AddPullRequest(1, "alice", "Keep me")
+	s.SetLabels(1, "doc-required", "lgtm")
+	if err := runSelftest(ctx, ac, client, 1); err != nil {
+		t.Fatalf("selftest on #1: %v", err)
+	}
+	if body := s.Body(1); body != "Keep me" {
+		t.Errorf("body = %q, want restored", body)
+	}
+	assertLabels(t, s, 1, "doc-required", "lgtm")
+
+	// a throwaway PR is closed and its branch deleted
+	if err := runSelftest(ctx, ac, client, 0); err != nil {
+		t.Fatalf("selftest on a throwaway PR: %v", err)
+	}
+	if state := s.State(2); state != "closed" {
+		t.Errorf("state of #2 = %v, want closed", state)
+	}
+	if branches := s.Branches(); !reflect.DeepEqual(branches, []string{"master"}) {
+		t.Errorf("branches = %v, want the selftest branch deleted", branches)
+	}
+
+	// the flows fail without the labels in the sandbox
+	t.Setenv("LABEL_WATCH_LIST", "doc,docs-unknown")
+	if ac, err = NewActionConfig(); err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if err := runSelftest(ctx, ac, client, 1); err == nil || failureClass(err) != failureConfig {
+		t.Fatalf("selftest = %v, want a config failure", err)
+	}
+}
+
+func TestBatchLabelChanges(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "doc-required", "doc-not-needed", "doc-complete", "doc-label-missing",
+		"area/broker", "area/client", "area/proxy")
+	t.Cleanup(s.Close)
+	t.Setenv("GITHUB_GRAPHQL_URL", s.URL+"/graphql")
+	t.Setenv("LABEL_NAMESPACES", "area/=many")
+	body := func(checked ...string) string {
+		b := &strings.Builder{}
+		for _, label := range []string{"doc", "doc-required", "area/broker", "area/client", "area/proxy"} {
+			mark := " "
+			for _, c := range checked {
+				if c == label {
+					mark = "x"
+				}
+			}
+			fmt.Fprintf(b, "- [%s] `%s`\r\n", mark, label)
+		}
+		return b.String()
+	}
+
+	// fewer than 5 changes go through REST
+	s.AddPullRequest(1, "alice", body("doc", "area/broker"))
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "area/broker", "doc")
+	if s.Mutations() != 0 {
+		t.Fatalf("mutations = %v, want 0", s.Mutations())
+	}
+
+	// 5 changes are applied with one mutation
+	s.SetBody(1, body("doc-required", "area/client", "area/proxy"))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "area/client", "area/proxy", "doc-required")
+	if s.Mutations() != 1 {
+		t.Fatalf("mutations = %v, want 1", s.Mutations())
+	}
+}
+
+func TestIdempotentLabelChanges(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.SetLabels(1, "doc")
+
+	client := newGitHubClient(context.Background(), "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	provider := scm.NewGitHub(client, s.Owner, s.Repo)
+
+	// a re-delivered event finds its changes already made, and sends no mutation
+	s.Fail(http.MethodPost, "issues/1/labels", 1)
+	if err := provider.EditLabels(context.Background(), 1, []string{"doc"}, []string{"doc-required"}); err != nil {
+		t.Fatalf("EditLabels: %v", err)
+	}
+	if err := provider.RemoveLabel(context.Background(), 1, "doc-label-missing"); err != nil {
+		t.Fatalf("RemoveLabel: %v", err)
+	}
+	if err := provider.AddLabels(context.Background(), 1, []string{"doc"}); err != nil {
+		t.Fatalf("AddLabels: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+}
+
+func TestResumeLabelChanges(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	// doc is removed, but adding doc-required fails, which is recorded in the state without telling anything
+	s.SetBody(1, fmt.Sprintf(testBody, " ", "x", " "))
+	s.Fail("POST", "issues/1/labels", 1)
+	if err := runEvent(t, s, 1, "edited"); err == nil {
+		t.Fatalf("edited: want error")
+	}
+	assertLabels(t, s, 1)
+	comments := s.Comments(1)
+	if len(comments) != 1 || !stateMarkerRegexp(statePendingLabels).MatchString(comments[0]) {
+		t.Fatalf("comments = %q, want the pending label changes", comments)
+	}
+	if visible := versionMarkerRegexp.ReplaceAllString(stateMarkerRegexp(statePendingLabels).ReplaceAllString(comments[0], ""), ""); len(strings.TrimSpace(visible)) > 0 {
+		t.Fatalf("comment = %q, want nothing visible", comments[0])
+	}
+
+	// the next event completes the changes
+	if err := runEvent(t, s, 1, "labeled"); err != nil {
+		t.Fatalf("labeled: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+	if value, err := newTestAction(t, s, 1).getState().Load(statePendingLabels); err != nil || len(value) > 0 {
+		t.Fatalf("pending label changes = %q, %v, want none left", value, err)
+	}
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want the state updated in place", comments)
+	}
+
+	// the labels changed by someone since the record are left alone
+	action := newTestAction(t, s, 1)
+	data, _ := json.Marshal(&pendingLabels{Add: []string{"doc", "doc-complete"}, Remove: []string{"doc-required"}, At: time.Now().Add(-time.Minute)})
+	if err := action.getState().Save(statePendingLabels, string(data), ""); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s.SetLabels(1, "doc-required", "doc")
+	if err := newTestAction(t, s, 1).resumeLabelChanges(); err != nil {
+		t.Fatalf("resumeLabelChanges: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required", "doc", "doc-complete")
+}
+
+func TestNonFatalProblems(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	// the reminder is still posted when the missing label can't be applied
+	s.Fail(http.MethodPost, "issues/1/labels", 1)
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1)
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want the reminder", comments)
+	}
+}
+
+func TestLockedOrDeletedPR(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetLocked(1, true)
+
+	// labels are managed, comments are suppressed
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+
+	// a deleted PR is skipped
+	if err := runEvent(t, s, 2, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+}
+
+func TestPlanOnly(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1))
+	s.SetLabels(1, "doc-label-missing")
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", planOnlyTransport(nil))
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	planner := newPlanProvider(scm.NewGitHub(client, s.Owner, s.Repo))
+	action := &Action{config: ac, globalContext: ctx, client: client, provider: planner}
+	number := 1
+	ac.number = &number
+	ac.labels = action.extractLabels(s.Body(number))
+
+	if err := action.Run("opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+	if !reflect.DeepEqual(planner.plan.Add, []string{"doc"}) || !reflect.DeepEqual(planner.plan.Remove, []string{"doc-label-missing"}) {
+		t.Fatalf("plan = %+v, want doc added and doc-label-missing removed", planner.plan)
+	}
+}
+
+func TestPlanOnlyModes(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetLabels(1, "doc-label-missing")
+	s.Backdate(1, 30*24*time.Hour)
+	notified := 0
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { notified++ }))
+	t.Cleanup(webhook.Close)
+	t.Setenv("NOTIFY_WEBHOOKS", "teams="+webhook.URL)
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+	t.Setenv("PLAN_ONLY", "true")
+
+	// the modes send their requests through the plan-only transport too
+	ac := newTestAction(t, s, 0).config
+	if _, err := installAPITransport(ac); err != nil {
+		t.Fatalf("installAPITransport: %v", err)
+	}
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", ac.transport())
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	if err := applyStale(ctx, ac, client); err != nil {
+		t.Fatalf("applyStale: %v", err)
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want no warning", comments)
+	}
+	if notified != 0 {
+		t.Fatalf("notified %d times, want none", notified)
+	}
+}
+
+func TestPlanBodyEdits(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetLabels(1, "doc-required")
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	planner := newPlanProvider(scm.NewGitHub(client, s.Owner, s.Repo))
+	action := &Action{config: ac, globalContext: ctx, client: client, provider: planner, plan: planner.plan}
+	number := 1
+	ac.number = &number
+	ac.labels = action.extractLabels(s.Body(number))
+
+	// the label applied by the maintainer is checked in the body
+	err = action.Run("labeled")
+	planner.setPlanOutput(number, err)
+	if err != nil {
+		t.Fatalf("labeled: %v", err)
+	}
+	if want := []Edit{{Label: "doc-required", Checked: true}}; !reflect.DeepEqual(planner.plan.BodyEdits, want) {
+		t.Fatalf("body edits = %+v, want %+v", planner.plan.BodyEdits, want)
+	}
+	if planner.plan.Body == nil || planner.plan.Verdict != verdictSuccess {
+		t.Fatalf("plan = %+v, want the edited body and success", planner.plan)
+	}
+}
+
+func TestPingPongPauses(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	for i := 0; i < 3; i++ {
+		s.SetLabels(1, "doc")
+		s.SetLabels(1)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := runEvent(t, s, 1, "edited"); err != nil {
+			t.Fatalf("edited: %v", err)
+		}
+	}
+	assertLabels(t, s, 1)
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], pingPongMarker) {
+		t.Fatalf("comments = %q, want one diagnostic comment", comments)
+	}
+}
+
+func TestEditedKeepsHumanLabels(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1))
+	s.SetLabels(1, "doc-required")
+
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "doc-required")
+
+	t.Setenv("FORCE_MANAGE", "true")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+}
+
+func TestBotLoginFromToken(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1))
+
+	// the installation tokens can't get their user
+	action := newTestAction(t, s, 1)
+	resolveBotLogin(context.Background(), action.config, action.client)
+	if login := action.config.GetBotLogin(); login != "github-actions[bot]" {
+		t.Fatalf("bot login = %q, want github-actions[bot]", login)
+	}
+
+	// a personal access token labels as its user, whose labels are the bot's
+	s.SetTokenUser("docs-bot")
+	run := func() error {
+		action := newTestAction(t, s, 1)
+		resolveBotLogin(context.Background(), action.config, action.client)
+		if login := action.config.GetBotLogin(); login != "docs-bot" {
+			t.Fatalf("bot login = %q, want docs-bot", login)
+		}
+		action.config.labels = action.extractLabels(s.Body(1))
+		return action.Run("edited")
+	}
+	if err := run(); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	s.SetBody(1, strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc-required`", "[x] `doc-required`", 1))
+	if err := run(); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+}
+
+// testHook exempts the typo fixes from documentation, and pairs the doc label with area/docs.
+type testHook struct{}
+
+func (testHook) PrePlan(pr *scm.PullRequest, labels map[string]bool) error {
+	if strings.HasPrefix(pr.Title, "[typo]") {
+		labels["doc-not-needed"] = true
+	}
+	return nil
+}
+
+func (testHook) PostPlan(pr *scm.PullRequest, plan *hook.Plan) error {
+	for _, label := range plan.LabelsToAdd {
+		if label == "doc" {
+			plan.LabelsToAdd = append(plan.LabelsToAdd, "area/docs")
+			break
+		}
+	}
+	return nil
+}
+
+func TestPlanHooks(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetTitle(1, "[typo] Fix the broker config")
+	s.AddPullRequest(2, "bob", fmt.Sprintf(testBody, "x", " ", " "))
+	hooks := &hook.Hooks{}
+	if err := hooks.Add(testHook{}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	for number, want := range map[int][]string{1: {"doc-not-needed"}, 2: {"doc", "area/docs"}} {
+		action := newTestAction(t, s, number)
+		action.config.hooks = hooks
+		action.config.labels = action.extractLabels(s.Body(number))
+		if err := action.Run("opened"); err != nil {
+			t.Fatalf("opened #%d: %v", number, err)
+		}
+		assertLabels(t, s, number, want...)
+	}
+
+	if err := hooks.Add(struct{}{}); err == nil {
+		t.Fatalf("Add: err = nil, want neither hook")
+	}
+}
+
+func TestReminderCooldown(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	for i := 0; i < 2; i++ {
+		if err := runEvent(t, s, 1, "edited"); err == nil {
+			t.Fatalf("edited: err = nil, want missing label")
+		}
+	}
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want one reminder within cooldown", comments)
+	}
+
+	t.Setenv("COMMENT_COOLDOWN", "0")
+	if err := runEvent(t, s, 1, "edited"); err == nil {
+		t.Fatalf("edited: err = nil, want missing label")
+	}
+	if comments := s.Comments(1); len(comments) != 2 {
+		t.Fatalf("comments = %q, want two reminders without cooldown", comments)
+	}
+}
+
+func TestReminderForgedCooldown(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	// a reminder marker posted by the author doesn't silence the reminder
+	s.AddComment(1, "alice", fmt.Sprintf(`%sreminder at="%s" -->`, markerPrefix, time.Now().UTC().Format(time.RFC3339)))
+	if err := runEvent(t, s, 1, "edited"); err == nil {
+		t.Fatalf("edited: err = nil, want missing label")
+	}
+	if comments := s.Comments(1); len(comments) != 2 || !strings.Contains(comments[1], "@alice ") {
+		t.Fatalf("comments = %q, want the reminder", comments)
+	}
+}
+
+func TestStalePolicy(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	s.AddPullRequest(1, "alice", body)
+	s.AddPullRequest(2, "bob", body)
+	s.AddPullRequest(3, "carol", body)
+	s.SetLabels(1, "doc-label-missing")
+	s.SetLabels(2, "doc-label-missing", "wip")
+	s.SetLabels(3, "doc-label-missing")
+	t.Setenv("STALE_EXEMPT_LABELS", "wip")
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+
+	// a warning marker pasted by someone else isn't the warning of the bot, and the author responded on #3
+	s.AddComment(1, "mallory", staleMarker)
+	s.AddComment(3, "carol", "Which label do I need?")
+	for number := 1; number <= 3; number++ {
+		s.Backdate(number, 15*24*time.Hour)
+	}
+	action := newTestAction(t, s, 0)
+	if err := applyStale(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("applyStale: %v", err)
+	}
+	if comments := s.Comments(1); len(comments) != 2 || !strings.Contains(comments[1], "@alice This PR has been missing") {
+		t.Fatalf("comments = %q, want the stale warning", comments)
+	}
+	if state := s.State(1); state != "open" {
+		t.Fatalf("state = %v, want open until the warning expires", state)
+	}
+	if len(s.Comments(2)) != 0 || len(s.Comments(3)) != 1 {
+		t.Fatalf("comments on #2 %q and #3 %q, want no warning", s.Comments(2), s.Comments(3))
+	}
+
+	// closed once the warning expires
+	s.Backdate(1, 8*24*time.Hour)
+	if err := applyStale(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("applyStale: %v", err)
+	}
+	for number, want := range map[int]string{1: "closed", 2: "open", 3: "open"} {
+		if state := s.State(number); state != want {
+			t.Fatalf("state of #%d = %v, want %v", number, state, want)
+		}
+	}
+	if data, err := os.ReadFile(summary); err != nil || !strings.Contains(string(data), "| #1 |  | warned |") ||
+		!strings.Contains(string(data), "| #1 |  | closed |") {
+		t.Fatalf("summary = %q, %v, want #1 warned and closed", data, err)
+	}
+}
+
+func TestStaleCategoryMissingLabel(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	s.AddPullRequest(1, "alice", body)
+	s.AddPullRequest(2, "bob", body)
+	s.SetLabels(1, "area-label-missing")
+	s.SetLabels(2, "doc-label-missing")
+	s.Backdate(1, 15*24*time.Hour)
+	t.Setenv("LABEL_MISSING", "default=doc-label-missing,area/=area-label-missing")
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+
+	// the missing label of a category counts, since it was applied
+	action := newTestAction(t, s, 0)
+	if err := applyStale(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("applyStale: %v", err)
+	}
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], "@alice This PR has been missing") {
+		t.Fatalf("comments = %q, want the stale warning", comments)
+	}
+	if comments := s.Comments(2); len(comments) != 0 {
+		t.Fatalf("comments = %q, want no warning on the recently labeled PR", comments)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	s := newTestServer(t)
+	for number, author := range map[int]string{1: "alice", 2: "bob"} {
+		s.AddPullRequest(number, author, fmt.Sprintf(testBody, " ", "x", " "))
+		s.SetTitle(number, fmt.Sprintf("Change %d", number))
+		s.SetLabels(number, "doc-required")
+		s.SetState(number, "closed")
+	}
+	// a docs PR already followed up on PR 2
+	s.AddCrossReference(2, 3)
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(t.TempDir(), "summary.md"))
+
+	action := newTestAction(t, s, 0)
+	if err := updateDigest(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("updateDigest: %v", err)
+	}
+	issues := s.Issues("apache/pulsar")
+	if len(issues) != 1 || issues[0].Title != "Documentation digest" ||
+		!strings.Contains(issues[0].Body, "- [ ] #1 Change 1 (@alice)") || strings.Contains(issues[0].Body, "#2") {
+		t.Fatalf("issues = %+v, want the digest of PR 1", issues)
+	}
+
+	// the digest issue is updated in place
+	s.AddCrossReference(1, 4)
+	if err := updateDigest(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("updateDigest: %v", err)
+	}
+	issues = s.Issues("apache/pulsar")
+	if len(issues) != 1 || !strings.Contains(issues[0].Body, "Nothing to do") || strings.Contains(issues[0].Body, "#1") {
+		t.Fatalf("issues = %+v, want the digest updated with nothing to do", issues)
+	}
+}
+
+func TestLabelReport(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", body)
+	}
+	s.SetCreatedAt(1, time.Now().Add(-2*time.Hour))
+	s.SetLabels(1, "doc")
+	// PR 2 was flagged before being labeled
+	s.SetCreatedAt(2, time.Now().Add(-4*time.Hour))
+	s.SetLabels(2, "doc-label-missing")
+	s.SetLabels(2, "doc-required")
+	// PR 3 was never labeled
+	s.SetCreatedAt(3, time.Now())
+
+	action := newTestAction(t, s, 0)
+	report, err := labelStatistics(context.Background(), action.config, action.client)
+	if err != nil {
+		t.Fatalf("labelStatistics: %v", err)
+	}
+	if report.PullRequests != 3 || !reflect.DeepEqual(report.LabelCounts, map[string]int{"doc": 1, "doc-required": 1}) ||
+		report.Unlabeled != 1 || int(report.MissingPercent) != 33 {
+		t.Fatalf("report = %+v, want 3 PRs, 2 labeled, 1 flagged", report)
+	}
+	if avg := math.Round(report.AvgTimeToLabel); avg != 3 {
+		t.Fatalf("average time to label = %vh, want 3h", report.AvgTimeToLabel)
+	}
+
+	output := filepath.Join(t.TempDir(), "report.csv")
+	if err := writeReport(report, "csv", output); err != nil {
+		t.Fatalf("writeReport: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if !strings.Contains(string(data), "pull_requests,3\n") || !strings.HasSuffix(string(data), "label:doc,1\nlabel:doc-required,1\n") {
+		t.Fatalf("report = %s, want the CSV metrics", data)
+	}
+}
+
+func TestLabelExpiry(t *testing.T) {
+	s := newTestServer(t)
+	body := fmt.Sprintf(testBody, "x", " ", " ")
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", body)
+		s.SetLabels(number, "doc", "triage/needs-info")
+	}
+	s.Backdate(1, 15*24*time.Hour)
+	// PR 3 was labeled again since
+	s.Backdate(3, 20*24*time.Hour)
+	s.SetLabels(3, "doc", "triage/needs-info")
+	t.Setenv("LABEL_TTLS", "triage/needs-info=14d")
+	t.Setenv("LABEL_EXPIRY_MESSAGE", "The {label} label expired.")
+
+	action := newTestAction(t, s, 0)
+	if err := applyExpiry(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("applyExpiry: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+	if comments := s.Comments(1); len(comments) != 1 || !strings.HasPrefix(comments[0], "@alice The triage/needs-info label expired.") {
+		t.Fatalf("comments = %q, want the expiry comment", comments)
+	}
+	for number := 2; number <= 3; number++ {
+		assertLabels(t, s, number, "doc", "triage/needs-info")
+		if comments := s.Comments(number); len(comments) != 0 {
+			t.Fatalf("comments = %q, want none on #%d", comments, number)
+		}
+	}
+}
+
+func TestDocFollowUp(t *testing.T) {
+	s := newTestServer(t)
+	body := fmt.Sprintf(testBody, " ", "x", " ")
+	for number := 1; number <= 2; number++ {
+		s.AddPullRequest(number, "alice", body)
+		s.SetTitle(number, fmt.Sprintf("Change %d", number))
+		s.SetLabels(number, "doc-required")
+	}
+	s.SetMerged(1)
+	s.SetState(2, "closed")
+	// a pasted marker doesn't pass for the link of the bot
+	s.AddComment(1, "mallory", followUpMarker("apache", "pulsar", 1))
+	t.Setenv("DOCS_REPO", "apache/pulsar-site")
+
+	for i := 0; i < 2; i++ {
+		if err := runEvent(t, s, 1, "closed"); err != nil {
+			t.Fatalf("closed: %v", err)
+		}
+	}
+	issues := s.Issues("apache/pulsar-site")
+	if len(issues) != 1 || issues[0].Title != "Document Change 1" || !strings.Contains(issues[0].Body, followUpMarker("apache", "pulsar", 1)) {
+		t.Fatalf("issues = %+v, want one follow-up issue", issues)
+	}
+	if comments := s.Comments(1); len(comments) != 2 || !strings.Contains(comments[1], issues[0].HTMLURL) {
+		t.Fatalf("comments = %q, want the link to the follow-up issue", comments)
+	}
+	assertLabels(t, s, 1, "doc-pending")
+
+	// a PR closed without merging needs no documentation
+	if err := runEvent(t, s, 2, "closed"); err != nil {
+		t.Fatalf("closed #2: %v", err)
+	}
+	assertLabels(t, s, 2, "doc-required")
+	if issues := s.Issues("apache/pulsar-site"); len(issues) != 1 {
+		t.Fatalf("issues = %+v, want no more follow-up issues", issues)
+	}
+}
+
+func TestUploadReport(t *testing.T) {
+	// the fake CLIs record their arguments, and fail for the forbidden bucket
+	bin := t.TempDir()
+	for _, name := range []string{"aws", "gcloud"} {
+		script := fmt.Sprintf(`#!/bin/sh
+case "$*" in
+*forbidden*) echo "AccessDenied" >&2; exit 1 ;;
+esac
+echo "$*" >> %q
+`, filepath.Join(bin, name+".log"))
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	file := filepath.Join(t.TempDir(), "label-report.json")
+	for _, url := range []string{"s3://reports/docbot/", "gs://reports/docbot/today.json"} {
+		if err := uploadReport(file, url); err != nil {
+			t.Fatalf("uploadReport(%v): %v", url, err)
+		}
+	}
+	for name, want := range map[string]string{
+		"aws":    "s3 cp --only-show-errors " + file + " s3://reports/docbot/label-report.json\n",
+		"gcloud": "storage cp " + file + " gs://reports/docbot/today.json\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(bin, name+".log")); err != nil || string(data) != want {
+			t.Errorf("%v args = %q, %v, want %q", name, data, err, want)
+		}
+	}
+
+	if err := uploadReport(file, "s3://forbidden/"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Fatalf("uploadReport: err = %v, want the error of the CLI", err)
+	}
+	if err := uploadReport(file, "https://example.com/reports/"); err == nil {
+		t.Fatalf("uploadReport: err = nil, want unsupported URL")
+	}
+
+	// a report only uploaded is written to a temporary file
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("REPORT_UPLOAD_URL", "s3://reports/docbot/")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if output := reportOutput(ac, "label-report"); output != filepath.Join(os.TempDir(), "label-report.json") {
+		t.Fatalf("output = %v, want a temporary file", output)
+	}
+}
+
+func TestReactionReminder(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	t.Setenv("NOTIFY_MODE", "reaction")
+
+	// the same reaction of another bot isn't a reminder of the bot
+	s.AddReaction(1, ghtest.User{Login: "renovate[bot]", Type: "Bot"}, "confused")
+
+	// the first violation is only reacted to, and fails the check run
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+	if reactions := s.Reactions(1); len(reactions) != 2 || reactions[1].Content != "confused" || reactions[1].User.Login != "github-actions[bot]" {
+		t.Fatalf("reactions = %+v, want the confused reaction of the bot", reactions)
+	}
+	checkRuns := s.CheckRuns()
+	if len(checkRuns) == 0 || checkRuns[len(checkRuns)-1].Name != checkRunName || checkRuns[len(checkRuns)-1].Conclusion != "action_required" {
+		t.Fatalf("check runs = %+v, want action required", checkRuns)
+	}
+
+	// repeated violations are commented on
+	if err := runEvent(t, s, 1, "edited"); err == nil {
+		t.Fatalf("edited: err = nil, want missing label")
+	}
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], "@alice ") {
+		t.Fatalf("comments = %q, want the reminder", comments)
+	}
+	if reactions := s.Reactions(1); len(reactions) != 2 {
+		t.Fatalf("reactions = %+v, want no more reactions", reactions)
+	}
+}
+
+func TestCheckRunState(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("STATE_BACKEND", "check-run")
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	// another app can't silence the reminders with a forged state
+	s.AddCheckRun(fmt.Sprintf("%040d", 1), stateCheckRunName, fmt.Sprintf(`{"reminder at": %q}`, time.Now().Format(time.RFC3339)), "mallory")
+
+	for i := 0; i < 2; i++ {
+		if err := runEvent(t, s, 1, "edited"); err == nil {
+			t.Fatalf("edited: err = nil, want missing label")
+		}
+	}
+	comments := s.Comments(1)
+	if len(comments) != 1 || strings.Contains(comments[0], markerPrefix+stateReminderAt) {
+		t.Fatalf("comments = %q, want one reminder without marker within cooldown", comments)
+	}
+	states := 0
+	for _, c := range s.CheckRuns() {
+		if c.Name == stateCheckRunName && c.App.Slug != "mallory" {
+			states++
+			if !strings.Contains(c.ExternalID, `"reminder at":`) {
+				t.Fatalf("external_id = %q, want the reminder time", c.ExternalID)
+			}
+		}
+	}
+	if states != 1 {
+		t.Fatalf("state check runs = %d, want 1", states)
+	}
+}
+
+func TestDBStateNeedsStore(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+	t.Setenv("MODE", "server")
+	t.Setenv("WEBHOOK_SECRET", "secret")
+	t.Setenv("STATE_BACKEND", "db")
+
+	// without a db, the state would be silently lost
+	if _, err := NewActionConfig(); err == nil {
+		t.Fatalf("NewActionConfig: err = nil, want STATE_DB required")
+	}
+	t.Setenv("STATE_DB", filepath.Join(t.TempDir(), "state.db"))
+	if _, err := NewActionConfig(); err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	s := newTestServer(t)
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	features := `features:
+  - repos: ["apache/*"]
+    labels: false
+    body_edits: false
+    comments: false
+    check_runs: false
+  - repos: ["apache/pulsar"]
+    comments: true
+`
+	if err := os.WriteFile(filepath.Join(workspace, ".github", "docbot.yml"), []byte(features), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+	t.Setenv("NOTIFY_MODE", "comment")
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	if err := runEvent(t, s, 1, "opened"); err == nil || err.Error() != MessageLabelMissing {
+		t.Fatalf("opened: err = %v, want missing label", err)
+	}
+	// report-only, except for the comments enabled on the repo
+	assertLabels(t, s, 1)
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want the reminder", comments)
+	}
+	if checkRuns := s.CheckRuns(); len(checkRuns) != 0 {
+		t.Fatalf("check runs = %+v, want none", checkRuns)
+	}
+
+	s.SetLabels(1, "doc")
+	if err := runEvent(t, s, 1, "labeled"); err != nil {
+		t.Fatalf("labeled: %v", err)
+	}
+	if body := s.Body(1); strings.Contains(body, "[x]") {
+		t.Fatalf("body = %q, want unedited", body)
+	}
+}
+
+func TestSkipLabel(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetLabels(1, "docbot-skip")
+
+	t.Setenv("SKIP_LABEL", "docbot-skip")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "docbot-skip")
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+}
+
+func TestReleaseNote(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "doc-required", "doc-not-needed", "doc-complete", "doc-label-missing",
+		labelReleaseNote, labelReleaseNoteNone)
+	t.Cleanup(s.Close)
+	body := strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1)
+	s.AddPullRequest(1, "alice", body+"\r\n### Release note\r\n\r\n<!-- Describe the change -->\r\n")
+
+	t.Setenv("ENABLE_RELEASE_NOTE", "true")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], releaseNoteMissingMarker) {
+		t.Fatalf("comments = %q, want one release note reminder", comments)
+	}
+
+	s.SetBody(1, body+"\r\n### Release note\r\n\r\nNONE\r\n")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", labelReleaseNoteNone)
+
+	s.SetBody(1, body+"\r\n### Release note\r\n\r\nAdd the `foo` option.\r\n")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", labelReleaseNote)
+}
+
+func TestTitlePrefix(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.Replace(strings.ReplaceAll(testBody, "[%s]", "[ ]"), "[ ] `doc`", "[x] `doc`", 1))
+	s.SetTitle(1, "[doc-required] Fix typo")
+
+	t.Setenv("TITLE_PREFIX", "check")
+	if err := runEvent(t, s, 1, "edited"); err == nil {
+		t.Fatalf("edited: err = nil, want title mismatch")
+	}
+	if title := s.Title(1); title != "[doc-required] Fix typo" {
+		t.Fatalf("title = %q, want unchanged", title)
+	}
+
+	t.Setenv("TITLE_PREFIX", "fix")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	if title := s.Title(1); title != "[doc] Fix typo" {
+		t.Fatalf("title = %q, want %q", title, "[doc] Fix typo")
+	}
+}
+
+func TestDescriptionChannel(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	s.AddPullRequest(1, "alice", body)
+
+	t.Setenv("COMMENT_CHANNEL", "description")
+	if err := runEvent(t, s, 1, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+	if !strings.Contains(s.Body(1), noteStartMarker) {
+		t.Fatalf("body = %q, want note", s.Body(1))
+	}
+
+	s.SetBody(1, strings.Replace(s.Body(1), "[ ] `doc`", "[x] `doc`", 1))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	if strings.Contains(s.Body(1), noteStartMarker) {
+		t.Fatalf("body = %q, want note cleared", s.Body(1))
+	}
+}
+
+func TestReviewChannel(t *testing.T) {
+	s := newTestServer(t)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	s.AddPullRequest(1, "alice", body)
+	s.AddPullRequest(2, "bob", body)
+	// a review pasting the marker of the bot isn't dismissed with it
+	s.AddReviewBody(1, "mallory", "CHANGES_REQUESTED", reviewMarker)
+
+	t.Setenv("COMMENT_CHANNEL", "review")
+	t.Setenv("COMMENT_COOLDOWN", "0")
+	for i := 0; i < 2; i++ {
+		if err := runEvent(t, s, 1, "opened"); err == nil {
+			t.Fatalf("opened: err = nil, want missing label")
+		}
+	}
+	reviews := s.Reviews(1)
+	if len(reviews) != 2 || reviews[1].State != "CHANGES_REQUESTED" || !strings.Contains(reviews[1].Body, "@alice ") {
+		t.Fatalf("reviews = %+v, want changes requested once", reviews)
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+
+	s.SetBody(1, strings.Replace(body, "[ ] `doc`", "[x] `doc`", 1))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	reviews = s.Reviews(1)
+	if len(reviews) != 2 || reviews[0].State != "CHANGES_REQUESTED" || reviews[1].State != "DISMISSED" {
+		t.Fatalf("reviews = %+v, want the review of the bot dismissed", reviews)
+	}
+
+	// or approved over
+	t.Setenv("REVIEW_RESOLUTION", "approve")
+	if err := runEvent(t, s, 2, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	s.SetBody(2, strings.Replace(body, "[ ] `doc`", "[x] `doc`", 1))
+	if err := runEvent(t, s, 2, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	reviews = s.Reviews(2)
+	if len(reviews) != 2 || reviews[0].State != "CHANGES_REQUESTED" || reviews[1].State != "APPROVED" {
+		t.Fatalf("reviews = %+v, want the bot approving", reviews)
+	}
+}
+
+func TestAutomatedPR(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "dependabot[bot]", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	t.Setenv("BOT_AUTHOR_LABELS", "doc-not-needed")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-not-needed")
+	if !strings.Contains(s.Body(1), "[x] `doc-not-needed`") {
+		t.Fatalf("body = %q, want doc-not-needed checked", s.Body(1))
+	}
+	if comments := s.Comments(1); len(comments) != 0 {
+		t.Fatalf("comments = %q, want none", comments)
+	}
+}
+
+func TestSAMLEnforcement(t *testing.T) {
+	sso := "https://github.com/orgs/apache/sso?authorization_request=abc"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-SSO", "required; url="+sso)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"Resource protected by organization SAML enforcement. You must grant your Personal Access token access to this organization."}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", &ssoTransport{base: http.DefaultTransport})
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	_, _, err := client.Issues.ListLabelsByIssue(ctx, "apache", "pulsar", 1, nil)
+	if err == nil {
+		t.Fatalf("err = nil, want SAML enforcement")
+	}
+	if class := failureClass(err); class != failurePermission {
+		t.Fatalf("class = %v, want %v", class, failurePermission)
+	}
+	if hint := samlHint(err); !strings.Contains(hint, sso) {
+		t.Fatalf("hint = %q, want %v", hint, sso)
+	}
+	if hint := samlHint(fmt.Errorf("GET labels: 403 Resource not accessible by integration []")); len(hint) != 0 {
+		t.Fatalf("hint = %q, want none", hint)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("GITHUB_TOKEN", "unauthorized")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for source, want := range map[string]string{"": "unauthorized", "env:SSO_TOKEN": "from-env", "file:" + path: "from-file"} {
+		t.Setenv("SSO_TOKEN", "from-env")
+		t.Setenv("TOKEN_SOURCE", source)
+		ac, err := NewActionConfig()
+		if err != nil {
+			t.Fatalf("%q: NewActionConfig: %v", source, err)
+		}
+		if got := ac.GetToken(); got != want {
+			t.Fatalf("%q: token = %q, want %q", source, got, want)
+		}
+	}
+
+	t.Setenv("TOKEN_SOURCE", "vault:token")
+	if _, err := NewActionConfig(); err == nil {
+		t.Fatalf("NewActionConfig: err = nil, want invalid TOKEN_SOURCE")
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(stalled) })
+
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("HTTP_TIMEOUT", "50ms")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if err := configureTransport(ac); err != nil {
+		t.Fatalf("configureTransport: %v", err)
+	}
+
+	ctx := context.Background()
+	client := newGitHubClient(ctx, "", ac.transport())
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	start := time.Now()
+	_, _, err = client.Issues.ListLabelsByIssue(ctx, "apache", "pulsar", 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("err = %v, want timed out", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("elapsed = %v, want about 50ms", elapsed)
+	}
+
+	t.Setenv("TLS_MIN_VERSION", "1.4")
+	if _, err := NewActionConfig(); err == nil {
+		t.Fatalf("NewActionConfig: err = nil, want invalid TLS_MIN_VERSION")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(stalled) })
+
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("HTTP_TIMEOUT", "0")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := newGitHubClient(ctx, "", nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	// the request in flight fails with the run
+	err = applyStale(ctx, ac, client)
+	if err == nil {
+		t.Fatalf("applyStale: err = nil, want timed out")
+	}
+	if class := runFailureClass(ctx, err); class != failureTimeout {
+		t.Fatalf("class = %v, want %v", class, failureTimeout)
+	}
+	if class := runFailureClass(context.Background(), err); class != failureAPI {
+		t.Fatalf("class = %v without a deadline, want %v", class, failureAPI)
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	token := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"value": "oidc-token"}`)
+	})
+	tlsSrv := httptest.NewTLSServer(token)
+	t.Cleanup(tlsSrv.Close)
+	srv := httptest.NewServer(token)
+	t.Cleanup(srv.Close)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("CA_BUNDLE", bundle)
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	defaultTransport := http.DefaultTransport
+	if err := configureTransport(ac); err != nil {
+		t.Fatalf("configureTransport: %v", err)
+	}
+	ctx := context.Background()
+
+	// the client of the run trusts the bundle
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", tlsSrv.URL)
+	if token, err := oidc.Token(ctx, ac.httpClient, ""); err != nil || token != "oidc-token" {
+		t.Fatalf("Token = %q, %v, want the token trusting the CA bundle", token, err)
+	}
+	// while the default client is left alone
+	if http.DefaultTransport != defaultTransport {
+		t.Fatalf("configureTransport replaced the default transport")
+	}
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", srv.URL)
+	if token, err := oidc.Token(ctx, nil, ""); err != nil || token != "oidc-token" {
+		t.Fatalf("Token = %q, %v, want the token through the default client", token, err)
+	}
+
+	t.Setenv("CA_BUNDLE", filepath.Join(t.TempDir(), "missing.pem"))
+	if ac, err = NewActionConfig(); err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if err := configureTransport(ac); err == nil || !strings.Contains(err.Error(), "read CA bundle") {
+		t.Fatalf("configureTransport: err = %v, want a missing CA bundle", err)
+	}
+}
+
+func TestChecklistTemplate(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `checklist_template: |
+  {{range .WatchedLabels}}{{if ne . "doc-complete"}}- [ ] `+"`{{.}}`"+`
+  {{end}}{{end}}{{if changed "site/docs/**"}}- [ ] `+"`doc-complete`"+`
+  {{end}}
+`)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+
+	for number, path := range map[int]string{1: "conf/broker.conf", 2: "site/docs/admin.md"} {
+		s.AddPullRequest(number, "alice", body)
+		s.SetAuthorAssociation(number, "FIRST_TIME_CONTRIBUTOR")
+		s.SetChangedFiles(number, path)
+		if err := runEvent(t, s, number, "opened"); err == nil {
+			t.Fatalf("opened #%d: err = nil, want missing label", number)
+		}
+		comments := s.Comments(number)
+		if len(comments) != 1 || !strings.Contains(comments[0], "- [ ] `doc-required`\n") {
+			t.Fatalf("comments of #%d = %q, want the checklist", number, comments)
+		}
+		if got, want := strings.Contains(comments[0], "`doc-complete`"), path == "site/docs/admin.md"; got != want {
+			t.Errorf("comment of #%d = %q, want doc-complete offered: %v", number, comments[0], want)
+		}
+	}
+}
+
+func TestLabelHints(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `labels:
+  - name: doc-required
+    hint: The PR changes user-facing behavior
+  - name: doc-not-needed
+    hint: Refactoring, tests or build changes
+`)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+
+	if err := runEvent(t, s, 1, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	comments := s.Comments(1)
+	if len(comments) != 1 {
+		t.Fatalf("comments = %q, want the reminder", comments)
+	}
+	for _, want := range []string{"- `doc-required`: The PR changes user-facing behavior\n", "- `doc-not-needed`: Refactoring, tests or build changes\n", "- `doc`\n"} {
+		if !strings.Contains(comments[0], want) {
+			t.Errorf("comment = %q, want %q", comments[0], want)
+		}
+	}
+}
+
+func TestRerunCommand(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", "x", " "))
+	s.SetPermission("bob", "write")
+
+	// mallory can't rerun the PR of alice
+	id := s.AddComment(1, "mallory", rerunCommand)
+	comment := &ghapi.IssueComment{ID: &id, User: &ghapi.User{Login: ghapi.String("mallory")}}
+	if err := newTestAction(t, s, 1).runCommand(rerunCommand, comment); err != nil {
+		t.Fatalf("rerun by mallory: %v", err)
+	}
+	if reactions := s.CommentReactions(id); !reflect.DeepEqual(reactions, []string{"-1"}) {
+		t.Fatalf("reactions = %v, want -1", reactions)
+	}
+	assertLabels(t, s, 1)
+
+	// a collaborator with write permission can
+	id = s.AddComment(1, "bob", rerunCommand+"\nlabels were fixed")
+	comment = &ghapi.IssueComment{ID: &id, User: &ghapi.User{Login: ghapi.String("bob")}}
+	if command := parseCommand(s.Comments(1)[1]); command != rerunCommand {
+		t.Fatalf("parseCommand(%q) = %q, want %q", s.Comments(1)[1], command, rerunCommand)
+	}
+	if err := newTestAction(t, s, 1).runCommand(rerunCommand, comment); err != nil {
+		t.Fatalf("rerun by bob: %v", err)
+	}
+	if reactions := s.CommentReactions(id); !reflect.DeepEqual(reactions, []string{"+1"}) {
+		t.Fatalf("reactions = %v, want +1", reactions)
+	}
+	assertLabels(t, s, 1, "doc-required")
+}
+
+func TestUndoCommand(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+
+	t.Setenv("ENABLE_UNDO", "true")
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	s.SetBody(1, fmt.Sprintf(testBody, " ", "x", " "))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+	comments := s.Comments(1)
+	if len(comments) != 2 || !strings.Contains(comments[1], "added `doc-required`, removed `doc`") {
+		t.Fatalf("comments = %q, want the label changes", comments)
+	}
+
+	// the author can't undo, since the labels are enforced on them
+	s.SetLabels(1, "doc-required", "doc-complete")
+	id := s.AddComment(1, "alice", undoCommand)
+	comment := &ghapi.IssueComment{ID: &id, User: &ghapi.User{Login: ghapi.String("alice")}}
+	if err := newTestAction(t, s, 1).runCommand(undoCommand, comment); err != nil {
+		t.Fatalf("undo by alice: %v", err)
+	}
+	if reactions := s.CommentReactions(id); !reflect.DeepEqual(reactions, []string{"-1"}) {
+		t.Fatalf("reactions = %v, want -1", reactions)
+	}
+	assertLabels(t, s, 1, "doc-required", "doc-complete")
+
+	// a human label added since is left alone
+	s.SetPermission("bob", "write")
+	id = s.AddComment(1, "bob", undoCommand)
+	comment = &ghapi.IssueComment{ID: &id, User: &ghapi.User{Login: ghapi.String("bob")}}
+	if err := newTestAction(t, s, 1).runCommand(undoCommand, comment); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "doc-complete")
+}
+
+func TestUndoForgedSnapshot(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", "x", " "))
+	s.SetPermission("bob", "write")
+	s.SetLabels(1, "doc-required")
+	t.Setenv("ENABLE_UNDO", "true")
+
+	// a snapshot forged by the author is ignored, even when a maintainer runs undo
+	s.AddComment(1, "alice", `<!-- docbot:label snapshot="{\"before\":[\"doc-not-needed\"],\"after\":[\"doc-required\"]}" -->`)
+	id := s.AddComment(1, "bob", undoCommand)
+	comment := &ghapi.IssueComment{ID: &id, User: &ghapi.User{Login: ghapi.String("bob")}}
+	if err := newTestAction(t, s, 1).runCommand(undoCommand, comment); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+}
+
+func TestLabelHues(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("GITHUB_REPOSITORY", s.Owner+"/"+s.Repo)
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed")
+	t.Setenv("LABEL_MISSING", "doc-label-missing")
+	t.Setenv("LABEL_HUES", "doc-label-*=0,doc*=210")
+	s.SetLabelMeta("doc", "", "1d76db")
+	s.SetLabelMeta("doc-required", "", "d73a4a")
+	s.SetLabelMeta("doc-not-needed", "", "cccccc")
+	s.SetLabelMeta("doc-label-missing", "", "d73a4a")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	client := newGitHubClient(context.Background(), "", nil)
+	client.BaseURL, _ = url.Parse(s.BaseURL())
+	usage, err := reportLabelUsage(context.Background(), ac, client)
+	if err != nil {
+		t.Fatalf("reportLabelUsage: %v", err)
+	}
+	want := []labelColor{{Label: "doc-not-needed", Color: "cccccc", Hue: 210}, {Label: "doc-required", Color: "d73a4a", Hue: 210}}
+	if !reflect.DeepEqual(usage.Miscolored, want) {
+		t.Fatalf("miscolored = %+v, want %+v", usage.Miscolored, want)
+	}
+
+	t.Setenv("LABEL_HUES", "doc*=blue")
+	if _, err := NewActionConfig(); err == nil {
+		t.Fatalf("NewActionConfig: err = nil, want invalid LABEL_HUES")
+	}
+}
+
+func TestEmptyWatchList(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", "x", " "))
+	t.Setenv("LABEL_WATCH_LIST", "")
+
+	// nothing is watched by default
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1)
+
+	// every checkbox is managed
+	t.Setenv("EMPTY_WATCH_LIST", "watch-all-from-pattern")
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+
+	t.Setenv("EMPTY_WATCH_LIST", "fail")
+	if _, err := NewActionConfig(); err == nil || err.Error() != "LABEL_WATCH_LIST is empty" {
+		t.Fatalf("NewActionConfig: err = %v, want empty LABEL_WATCH_LIST", err)
+	}
+}
+
+func TestUnknownLabels(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", "- [x] `doc-requried`\r\n- [ ] `doc-typo`\r\n")
+	t.Setenv("LABEL_WATCH_LIST", "")
+	t.Setenv("EMPTY_WATCH_LIST", "watch-all-from-pattern")
+	t.Setenv("COMMENT_UNKNOWN_LABELS", "true")
+
+	for _, event := range []string{"opened", "edited"} {
+		if err := runEvent(t, s, 1, event); err == nil {
+			t.Fatalf("%v: err = nil, want missing label", event)
+		}
+	}
+	unknown := 0
+	for _, comment := range s.Comments(1) {
+		if strings.Contains(comment, "`doc-requried` (did you mean `doc-required`?) don't exist") {
+			unknown++
+		}
+	}
+	if unknown != 1 {
+		t.Fatalf("comments = %q, want one telling about doc-requried", s.Comments(1))
+	}
+}
+
+func TestAutocorrectLabels(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", "- [x] `doc-requred`\r\n- [ ] `doc-required`\r\n")
+	t.Setenv("AUTOCORRECT_LABELS", "true")
+
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+}
+
+func TestBackfillNumbers(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddPullRequest(2, "bob", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddPullRequest(3, "carol", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddPullRequest(4, "dave", fmt.Sprintf(testBody, "x", " ", " "))
+	s.SetState(2, "closed")
+	t.Setenv("BATCH_NUMBERS", "1, #2, 3")
+
+	action := newTestAction(t, s, 0)
+	if err := backfill(context.Background(), action.config, action.client); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+	assertLabels(t, s, 2)
+	assertLabels(t, s, 3, "doc")
+	assertLabels(t, s, 4)
+
+	// the PRs share the repo labels, and didn't bind the shared config to their number
+	if n := s.Requests(http.MethodGet, "labels"); n != 1 {
+		t.Fatalf("repo label requests = %d, want 1", n)
+	}
+	if n := action.config.GetNumber(); n != 0 {
+		t.Fatalf("number = %d, want 0", n)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	for payload, want := range map[string]string{
+		`{"operation": "reconcile", "numbers": [1, 2]}`: "",
+		`{"operation": "report"}`:                       "",
+		`{"operation": "reconcile"}`:                    "no PR numbers",
+		`{"operation": "close", "numbers": [1]}`:        "unknown dispatch operation",
+		`{"numbers": "1"}`:                              "cannot unmarshal",
+	} {
+		if _, err := parseDispatchPayload(json.RawMessage(payload)); (err == nil) != (len(want) == 0) || err != nil && !strings.Contains(err.Error(), want) {
+			t.Errorf("parseDispatchPayload(%v): err = %v, want %q", payload, err, want)
+		}
+	}
+
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddPullRequest(2, "bob", fmt.Sprintf(testBody, "x", " ", " "))
+	s.SetState(2, "closed")
+	s.AddPullRequest(3, "carol", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetLabels(3, "doc-required")
+
+	action := newTestAction(t, s, 0)
+	if err := dispatch(context.Background(), action.config, action.client, &dispatchRequest{Operation: "reconcile", Numbers: []int{1, 2}}); err != nil {
+		t.Fatalf("dispatch reconcile: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+	assertLabels(t, s, 2)
+
+	// the labels are written back to the body
+	if err := dispatch(context.Background(), action.config, action.client, &dispatchRequest{Operation: "sync-labels", Numbers: []int{3}}); err != nil {
+		t.Fatalf("dispatch sync-labels: %v", err)
+	}
+	if !strings.Contains(s.Body(3), "[x] `doc-required`") {
+		t.Fatalf("body = %q, want doc-required checked", s.Body(3))
+	}
+
+	// the other PRs are run all the same
+	err := dispatch(context.Background(), action.config, action.client, &dispatchRequest{Operation: "reconcile", Numbers: []int{9, 1}})
+	if err == nil || err.Error() != "dispatch reconcile failed for 1 PRs" {
+		t.Fatalf("dispatch: err = %v, want PR 9 failed", err)
+	}
+}
+
+func TestBackfillFailures(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddPullRequest(2, "bob", fmt.Sprintf(testBody, " ", " ", " "))
+	output := filepath.Join(t.TempDir(), "audit.json")
+	t.Setenv("REPORT_OUTPUT", output)
+
+	action := newTestAction(t, s, 0)
+	err := backfill(context.Background(), action.config, action.client)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 PRs failed") {
+		t.Fatalf("backfill: err = %v, want 1 failed PR", err)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	// the audit is written all the same
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read audit: %v", err)
+	}
+	entries := []backfillAuditEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil || len(entries) != 2 || len(entries[0].Error) > 0 || len(entries[1].Error) == 0 {
+		t.Fatalf("audit = %s, want PR 2 failed", data)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	t.Setenv("ENABLE_UNDO", "true")
+
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	// each resource is fetched once, except the labels re-read right before and after being changed
+	for path, want := range map[string]int{"pulls/1": 1, "labels": 1, "issues/1/labels": 3} {
+		if n := s.Requests(http.MethodGet, path); n != want {
+			t.Errorf("GET %v requests = %d, want %d", path, n, want)
+		}
+	}
+}
+
+func TestRemovalGracePeriod(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	t.Setenv("REMOVAL_GRACE_PERIOD", "10m")
+	start := time.Now()
+	at := func(d time.Duration) { now = func() time.Time { return start.Add(d) } }
+	t.Cleanup(func() { now = time.Now })
+
+	at(0)
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc")
+
+	// the unchecked label stays within the grace period
+	s.SetBody(1, fmt.Sprintf(testBody, " ", "x", " "))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "doc-required")
+	if comments := s.Comments(1); len(comments) != 1 || !strings.Contains(comments[0], fmt.Sprintf(MessageRemovalPending, "`doc`", "10m0s")) {
+		t.Fatalf("comments = %v, want the pending removal", comments)
+	}
+	at(5 * time.Minute)
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc", "doc-required")
+
+	// and is removed by the first event past it
+	at(11 * time.Minute)
+	if err := runEvent(t, s, 1, "synchronize"); err != nil {
+		t.Fatalf("synchronize: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+	if comments := s.Comments(1); len(comments) != 1 {
+		t.Fatalf("comments = %q, want only the pending removal", comments)
+	}
+
+	// checking the box again cancels the removal
+	t.Setenv("ENABLE_LABEL_MULTIPLE", "true")
+	for _, step := range []struct {
+		d       time.Duration
+		checked []interface{}
+	}{
+		{12 * time.Minute, []interface{}{"x", "x", " "}},
+		{13 * time.Minute, []interface{}{"x", " ", " "}},
+		{14 * time.Minute, []interface{}{"x", "x", " "}},
+		{30 * time.Minute, []interface{}{"x", " ", " "}},
+	} {
+		at(step.d)
+		s.SetBody(1, fmt.Sprintf(testBody, step.checked...))
+		if err := runEvent(t, s, 1, "edited"); err != nil {
+			t.Fatalf("edited at %v: %v", step.d, err)
+		}
+	}
+	assertLabels(t, s, 1, "doc", "doc-required")
+	// the state is updated in place, so that only the pending removals are commented
+	if comments := s.Comments(1); len(comments) != 3 {
+		t.Fatalf("comments = %q, want the 3 pending removals", comments)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	t.Setenv("ENABLE_AUDIT_LOG", "true")
+	now = func() time.Time { return time.Date(2024, 5, 2, 9, 12, 40, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	s.SetBody(1, fmt.Sprintf(testBody, " ", "x", " "))
+	if err := runEvent(t, s, 1, "edited"); err != nil {
+		t.Fatalf("edited: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+
+	// the changes of both runs are in a single comment
+	comments := s.Comments(1)
+	if len(comments) != 1 {
+		t.Fatalf("comments = %v, want the audit log", comments)
+	}
+	for _, want := range []string{
+		"- 2024-05-02 09:12:40 UTC `opened`: added `doc`\n",
+		"- 2024-05-02 09:12:40 UTC `edited`: removed `doc`\n- 2024-05-02 09:12:40 UTC `edited`: added `doc-required`\n",
+	} {
+		if !strings.Contains(comments[0], want) {
+			t.Fatalf("audit log = %q, want %q", comments[0], want)
+		}
+	}
+	// the edit goes through the provider, which stamps the version
+	if n := strings.Count(comments[0], markerPrefix+"version="); n != 1 {
+		t.Fatalf("audit log = %q has %d version markers, want 1", comments[0], n)
+	}
+}
+
+func TestLocalRun(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	t.Setenv("ACT", "true")
+	t.Setenv("GITHUB_REPOSITORY", "")
+	t.Setenv("GITHUB_EVENT_NAME", "")
+	t.Setenv("GITHUB_EVENT_PATH", "")
+
+	// the flags fill in the missing context
+	if err := prepareLocalRun("apache/pulsar", 1, "", ""); err != nil {
+		t.Fatalf("prepareLocalRun: %v", err)
+	}
+	if repo, name := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_EVENT_NAME"); repo != "apache/pulsar" || name != "pull_request" {
+		t.Fatalf("context = %v %v, want apache/pulsar pull_request", repo, name)
+	}
+	if !isLocalRun() {
+		t.Fatalf("isLocalRun = false under act")
+	}
+
+	// an event without PR, as the empty payload of act, is replaced by an edited event of the PR
+	action := newTestAction(t, s, 0)
+	for _, event := range []interface{}{nil, &ghapi.PullRequestEvent{}} {
+		got, err := localPullRequestEvent(action, event, 1)
+		if err != nil {
+			t.Fatalf("localPullRequestEvent: %v", err)
+		}
+		prEvent, ok := got.(*ghapi.PullRequestEvent)
+		if !ok || prEvent.GetAction() != "edited" || prEvent.GetPullRequest().GetBody() != fmt.Sprintf(testBody, "x", " ", " ") {
+			t.Fatalf("event = %v, want the edited PR", got)
+		}
+	}
+
+	action.config.disableLocalFeatures()
+	if action.config.features().checkRuns {
+		t.Fatalf("check runs are enabled in a local run")
+	}
+}
+
+func TestLabelLatency(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", " ", " "))
+	created := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	s.SetCreatedAt(1, created)
+	now = func() time.Time { return created.Add(30 * time.Minute) }
+	t.Cleanup(func() { now = time.Now })
+	before := labelLatency.count
+
+	// the missing label doesn't count as labeled
+	if err := runEvent(t, s, 1, "opened"); err == nil {
+		t.Fatalf("opened: want the missing label error")
+	}
+	if labelLatency.count != before {
+		t.Fatalf("latency observed for the missing label")
+	}
+
+	s.SetBody(1, fmt.Sprintf(testBody, "x", " ", " "))
+	for range 2 {
+		if err := runEvent(t, s, 1, "edited"); err != nil {
+			t.Fatalf("edited: %v", err)
+		}
+	}
+	assertLabels(t, s, 1, "doc")
+	// only the first watched label is observed
+	if labelLatency.count != before+1 {
+		t.Fatalf("latency count = %d, want %d", labelLatency.count, before+1)
+	}
+
+	w := httptest.NewRecorder()
+	handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := w.Body.String(); !strings.Contains(body, "# TYPE docbot_label_latency_seconds histogram\n") ||
+		!strings.Contains(body, fmt.Sprintf("docbot_label_latency_seconds_count %d\n", before+1)) {
+		t.Fatalf("metrics = %q", body)
+	}
+}
+
+func TestContentRules(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `content_rules:
+  - label: doc-required
+    paths: ['**/*.java']
+    pattern: '^\s*@Public\b'
+`)
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", body)
+	}
+	// only the lines added to the files of the rule count
+	s.SetPatch(1, "pkg/broker/Topic.java", "@@ -1,2 +1,3 @@\n import java.util.List;\n+  @Public\n class Topic {")
+	s.SetPatch(2, "pkg/broker/Topic.java", "@@ -1,3 +1,2 @@\n import java.util.List;\n-  @Public\n class Topic {")
+	s.SetPatch(3, "site/docs/admin.md", "@@ -1 +1,2 @@\n # Admin\n+@Public APIs are stable")
+
+	if err := runEvent(t, s, 1, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	assertLabels(t, s, 1, "doc-required")
+	for number := 2; number <= 3; number++ {
+		if err := runEvent(t, s, number, "opened"); err == nil || err.Error() != MessageLabelMissing {
+			t.Fatalf("opened #%d: err = %v, want missing label", number, err)
+		}
+		assertLabels(t, s, number, "doc-label-missing")
+	}
+
+	s.AddFile(".github/docbot.yml", "content_rules:\n  - label: doc-required\n    pattern: '('\n")
+	if err := runEvent(t, s, 1, "edited"); err == nil || !strings.Contains(err.Error(), "content rule for doc-required") {
+		t.Fatalf("edited: err = %v, want the invalid pattern", err)
+	}
+}
+
+func TestTickRuleLabels(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", "content_rules:\n  - label: doc-required\n    pattern: '@Public'\n")
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.AddPullRequest(2, "bob", "Fix the broker")
+	for number := 1; number <= 2; number++ {
+		s.SetPatch(number, "pkg/broker/Topic.java", "@@ -1 +1,2 @@\n+@Public\n class Topic {")
+	}
+
+	// the checkbox of the label deduced by the rule is ticked, once
+	for _, event := range []string{"opened", "edited"} {
+		if err := runEvent(t, s, 1, event); err != nil {
+			t.Fatalf("%v: %v", event, err)
+		}
+	}
+	assertLabels(t, s, 1, "doc-required")
+	if body := s.Body(1); body != fmt.Sprintf(testBody, " ", "x", " ") {
+		t.Fatalf("body = %q, want doc-required ticked", body)
+	}
+	if n := s.Requests(http.MethodPatch, "pulls/1"); n != 1 {
+		t.Fatalf("body edits = %d, want 1", n)
+	}
+
+	// the checkbox is appended to a body without it
+	if err := runEvent(t, s, 2, "opened"); err != nil {
+		t.Fatalf("opened: %v", err)
+	}
+	if body := s.Body(2); !strings.HasPrefix(body, "Fix the broker") || !strings.Contains(body, "- [x] `doc-required`") {
+		t.Fatalf("body = %q, want doc-required appended", body)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.SetTitle(1, "WIP: Add the broker config")
+	s.SetChangedFiles(1, "conf/broker.conf")
+	s.SetLabels(1, "lgtm")
+	s.AddPullRequest(2, "bob", fmt.Sprintf(testBody, "x", " ", " "))
+	s.SetTitle(2, "Add the broker config")
+
+	// the fake opa keeps its input, and denies the work in progress
+	dir := t.TempDir()
+	opa := filepath.Join(dir, "opa")
+	script := `#!/bin/sh
+input=$(cat)
+printf '%s' "$input" > "$(dirname "$0")/input.json"
+case "$input" in
+*'"title":"WIP'*) echo '{"result":[{"expressions":[{"value":["WIP PRs must not be merged"]}]}]}' ;;
+*) echo '{"result":[{"expressions":[{"value":[]}]}]}' ;;
+esac
+`
+	if err := os.WriteFile(opa, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPA_PATH", opa)
+	t.Setenv("POLICY_PATH", filepath.Join(dir, "docbot.rego"))
+
+	err := runEvent(t, s, 1, "opened")
+	if err == nil || failureClass(err) != failureNonCompliant || err.Error() != "WIP PRs must not be merged" {
+		t.Fatalf("opened: err = %v, want the policy violation", err)
+	}
+	if checkRuns := s.CheckRuns(); len(checkRuns) != 1 || checkRuns[0].Conclusion != "failure" {
+		t.Fatalf("check runs = %+v, want the failed rules check", checkRuns)
+	}
+	input := policyInput{}
+	if data, err := os.ReadFile(filepath.Join(dir, "input.json")); err != nil || json.Unmarshal(data, &input) != nil {
+		t.Fatalf("read policy input: %v", err)
+	}
+	if input.Number != 1 || input.Author != "alice" || !reflect.DeepEqual(input.Labels, []string{"lgtm"}) || !reflect.DeepEqual(input.Files, []string{"conf/broker.conf"}) {
+		t.Fatalf("policy input = %+v, want PR 1", input)
+	}
+
+	if err := runEvent(t, s, 2, "opened"); err != nil {
+		t.Fatalf("opened #2: %v", err)
+	}
+
+	// a broken policy fails the run rather than passing the PR
+	t.Setenv("OPA_PATH", filepath.Join(dir, "missing"))
+	if err := runEvent(t, s, 2, "edited"); err == nil || !strings.Contains(err.Error(), "docbot.rego") {
+		t.Fatalf("edited: err = %v, want the evaluation failure", err)
+	}
+}
+
+func TestExpressionRules(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `expression_rules:
+  - when: 'files.exists(f, f.startsWith("site/"))'
+    labels: [doc-complete]
+    comment: Thanks for updating the website!
+`)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	s.SetChangedFiles(1, "site/docs/admin.md")
+
+	// the marker of the rule pasted by someone else doesn't stop its comment
+	s.AddComment(1, "mallory", expressionMarker(`files.exists(f, f.startsWith("site/"))`))
+	for _, event := range []string{"opened", "edited"} {
+		if err := runEvent(t, s, 1, event); err != nil {
+			t.Fatalf("%v: %v", event, err)
+		}
+	}
+	assertLabels(t, s, 1, "doc-complete")
+	if comments := s.Comments(1); len(comments) != 2 || !strings.Contains(comments[1], "Thanks for updating the website!") {
+		t.Fatalf("comments = %q, want the rule comment once", comments)
+	}
+}
+
+func TestExtensionPresets(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile(".github/docbot.yml", `extension_labels:
+  go: doc-not-needed
+content_rules:
+  - label: doc-required
+    paths: ['conf/**']
+    pattern: '^\w+='
+`)
+	t.Setenv("EXTENSION_PRESETS", "docs")
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+
+	// a file targeted by the paths of a content rule is left to the rule
+	for number, c := range map[int]struct {
+		paths []string
+		want  string
+	}{
+		1: {[]string{"site/docs/admin.md", "README"}, "doc"},
+		2: {[]string{"conf/notes.md", "main.go"}, "doc-not-needed"},
+	} {
+		s.AddPullRequest(number, "alice", body)
+		s.SetChangedFiles(number, c.paths...)
+		if err := runEvent(t, s, number, "opened"); err != nil {
+			t.Fatalf("opened #%d: %v", number, err)
+		}
+		assertLabels(t, s, number, c.want)
+	}
+
+	t.Setenv("EXTENSION_PRESETS", "docs,unknown")
+	if _, err := NewActionConfig(); err == nil || !strings.Contains(err.Error(), "EXTENSION_PRESETS") {
+		t.Fatalf("NewActionConfig: err = %v, want EXTENSION_PRESETS is invalid", err)
+	}
+}
+
+func TestComponentFiles(t *testing.T) {
+	s := newTestServer(t)
+	s.AddFile("pkg/broker/OWNERS", "approvers:\n  - bob\nlabels:\n  - doc-required\n")
+	s.AddFile("site/OWNERS", "approvers:\n  - carol\n")
+	s.AddFile("site/COMPONENT", "# the website\ndoc\n")
+	t.Setenv("COMPONENT_FILES", "OWNERS,COMPONENT")
+	body := strings.ReplaceAll(testBody, "[%s]", "[ ]")
+
+	// the closest directory declaring labels wins, an OWNERS file without labels falls through to COMPONENT
+	for number, file := range map[int]string{1: "pkg/broker/service/Topic.java", 2: "site/docs/admin.md"} {
+		s.AddPullRequest(number, "alice", body)
+		s.SetChangedFiles(number, file)
+		if err := runEvent(t, s, number, "opened"); err != nil {
+			t.Fatalf("opened #%d: %v", number, err)
+		}
+	}
+	assertLabels(t, s, 1, "doc-required")
+	assertLabels(t, s, 2, "doc")
+
+	// no metadata file up to the root
+	s.AddPullRequest(3, "alice", body)
+	s.SetChangedFiles(3, "README.md")
+	if err := runEvent(t, s, 3, "opened"); err == nil {
+		t.Fatalf("opened #3: err = nil, want missing label")
+	}
+}
+
+func TestForgedState(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+	s.AddComment(1, "mallory", `<!-- docbot:key="forged" -->`)
+
+	// markers of the comments of other users are ignored
+	action := newTestAction(t, s, 1)
+	if value, err := action.getState().Load("key"); err != nil || value != "" {
+		t.Fatalf("Load = %q, %v, want the forged marker ignored", value, err)
+	}
+	if err := action.getState().Save("key", "saved", ""); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if value, err := action.getState().Load("key"); err != nil || value != "saved" {
+		t.Fatalf("Load = %q, %v, want saved", value, err)
+	}
+}
+
+func TestPingPongForgedMarker(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", " ", " "))
+	s.AddComment(1, "alice", pingPongMarker)
+
+	// the marker of an author comment doesn't pause the bot
+	if err := runEvent(t, s, 1, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	s := newTestServer(t)
+	body := fmt.Sprintf(testBody, "x", " ", " ")
+	s.AddPullRequest(1, "alice", body)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	t.Setenv("MODE", "server")
+	t.Setenv("APP_ID", "7")
+	t.Setenv("APP_PRIVATE_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})))
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	if _, err := NewActionConfig(); err == nil || !strings.Contains(err.Error(), "WEBHOOK_SECRET") {
+		t.Fatalf("NewActionConfig: err = %v, want WEBHOOK_SECRET needed", err)
+	}
+	t.Setenv("WEBHOOK_SECRET", "secret")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+
+	ctx := context.Background()
+	app, err := ghapp.New(ac.GetAppID(), []byte(ac.GetAppPrivateKey()), func(token string) *ghapi.Client {
+		client := newGitHubClient(ctx, token, nil)
+		client.BaseURL, _ = url.Parse(s.BaseURL())
+		return client
+	})
+	if err != nil {
+		t.Fatalf("ghapp.New: %v", err)
+	}
+	st, err := store.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("store.Open: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	srv := &server{config: ac, app: app, store: st, repoConfigs: make(map[string]*repoConfig)}
+
+	payload := fmt.Sprintf(`{"action": "opened", "number": 1, "installation": {"id": 42},
+		"repository": {"name": "pulsar", "full_name": "apache/pulsar", "owner": {"login": "apache"}},
+		"pull_request": {"number": 1, "body": %q, "user": {"login": "alice"}, "updated_at": "2024-05-02T09:00:00Z"}}`, body)
+	deliver := func(r *http.Request, want int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.handleWebhook(w, r)
+		if w.Code != want {
+			t.Fatalf("webhook: %v %v, want %v", w.Code, w.Body, want)
+		}
+	}
+
+	// an unsigned payload is rejected
+	unsigned := newWebhookRequest("pull_request", "1", payload)
+	unsigned.Header.Del("X-Hub-Signature-256")
+	deliver(unsigned, http.StatusBadRequest)
+
+	// the delivery fails as the installation is missing, and its redelivery is handled once it's installed
+	deliver(newWebhookRequest("pull_request", "1", payload), http.StatusInternalServerError)
+	s.AddInstallation(42)
+	deliver(newWebhookRequest("pull_request", "1", payload), http.StatusOK)
+	assertLabels(t, s, 1, "doc")
+
+	// a handled delivery is skipped
+	s.SetLabels(1)
+	deliver(newWebhookRequest("pull_request", "1", payload), http.StatusOK)
+	assertLabels(t, s, 1)
+}
+
+func TestAuditLogSkippedChanges(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", fmt.Sprintf(testBody, " ", " ", " "))
+	s.AddComment(1, "mallory", auditLogMarker+"\nforged")
+	t.Setenv("ENABLE_AUDIT_LOG", "true")
+
+	// the missing label is asked on every run, but only added once
+	for range 3 {
+		if err := runEvent(t, s, 1, "edited"); err == nil {
+			t.Fatalf("edited: err = nil, want missing label")
+		}
+	}
+	assertLabels(t, s, 1, "doc-label-missing")
+
+	// the audit log of the bot is posted besides the forged one, which is left alone
+	comments := s.Comments(1)
+	if len(comments) != 3 || comments[0] != auditLogMarker+"\nforged" {
+		t.Fatalf("comments = %q, want the forged comment, the reminder and the audit log", comments)
+	}
+	if n := strings.Count(comments[2], "added `doc-label-missing`"); n != 1 {
+		t.Fatalf("audit log = %q, want the missing label added once", comments[2])
+	}
+}
+
+func TestClosedMinimizesBotComments(t *testing.T) {
+	s := newTestServer(t)
+	s.AddPullRequest(1, "alice", strings.ReplaceAll(testBody, "[%s]", "[ ]"))
+	if err := runEvent(t, s, 1, "opened"); err == nil {
+		t.Fatalf("opened: err = nil, want missing label")
+	}
+	// a user comment quoting a reminder marker is left alone
+	forged := s.AddComment(1, "alice", markerPrefix+`reminder at="2024-05-02T09:00:00Z" -->`)
+
+	t.Setenv("GITHUB_GRAPHQL_URL", s.URL+"/graphql")
+	if err := runEvent(t, s, 1, "closed"); err != nil {
+		t.Fatalf("closed: %v", err)
+	}
+	minimized := s.MinimizedComments(1)
+	if len(minimized) != 1 || minimized[0] == forged {
+		t.Fatalf("minimized comments = %v, want the reminder of the bot only", minimized)
+	}
+}
+
+func TestConflictsFailures(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar", "doc", "conflict")
+	t.Cleanup(s.Close)
+	for number := 1; number <= 3; number++ {
+		s.AddPullRequest(number, "alice", fmt.Sprintf(testBody, "x", " ", " "))
+		s.SetMergeable(number, false, "dirty")
+	}
+	t.Setenv("CONFLICT_LABEL", "conflict")
+	s.Fail(http.MethodGet, "pulls/2", 10)
+
+	// the failure of a PR doesn't stop checking the others, but fails the run
+	action := newTestAction(t, s, 0)
+	if err := checkAllConflicts(context.Background(), action.config, action.client); err == nil ||
+		!strings.Contains(err.Error(), "1 of 3 PRs failed") {
+		t.Fatalf("checkAllConflicts: err = %v, want 1 of 3 PRs failed", err)
+	}
+	assertLabels(t, s, 1, "conflict")
+	assertLabels(t, s, 2)
+	assertLabels(t, s, 3, "conflict")
+}
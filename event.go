@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// handledEvents are the events triggering the workflow that the action handles.
+var handledEvents = map[string]struct{}{
+	"issues":              {},
+	"issue_comment":       {},
+	"push":                {},
+	"pull_request":        {},
+	"pull_request_target": {},
+	"pull_request_review": {},
+	"repository_dispatch": {},
+}
+
+// readEvent parses the payload of the event triggering the workflow into its go-github type,
+// e.g. *ghapi.PullRequestEvent. It returns nil if there is no payload, or the event isn't handled.
+func readEvent(eventName, eventPath string) (interface{}, error) {
+	if _, handled := handledEvents[eventName]; !handled || len(eventPath) == 0 {
+		return nil, nil
+	}
+	payload, err := os.ReadFile(eventPath)
+	if err != nil {
+		return nil, err
+	}
+	// pull_request_target has the payload of pull_request
+	if eventName == "pull_request_target" {
+		eventName = "pull_request"
+	}
+	event, err := ghapi.ParseWebHook(eventName, payload)
+	if err != nil {
+		return nil, fmt.Errorf("parse %v payload: %w", eventName, err)
+	}
+	return event, nil
+}
+
+// eventAction returns the activity type of event, e.g. opened, or empty if the event has none, like push.
+func eventAction(event interface{}) string {
+	if e, ok := event.(interface{ GetAction() string }); ok {
+		return e.GetAction()
+	}
+	return ""
+}
+
+// isEventEnabled reports whether EVENTS and ACTIONS let the action process event of name,
+// logging the reason it is skipped otherwise. Events without activity type are only filtered by EVENTS.
+func (ac *ActionConfig) isEventEnabled(name string, event interface{}) bool {
+	if _, enabled := ac.events[name]; len(ac.events) > 0 && !enabled {
+		logger.Infof("Event %v is not in EVENTS, skip it\n", name)
+		return false
+	}
+	action := eventAction(event)
+	if _, enabled := ac.actions[action]; len(ac.actions) > 0 && len(action) > 0 && !enabled {
+		logger.Infof("Action %v of event %v is not in ACTIONS, skip it\n", action, name)
+		return false
+	}
+	return true
+}
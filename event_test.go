@@ -0,0 +1,83 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+)
+
+func TestReadEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	payload := `{"action": "reopened", "pull_request": {"number": 7, "body": null}}`
+	if err := os.WriteFile(path, []byte(payload), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := readEvent("pull_request_target", path)
+	if err != nil {
+		t.Fatalf("readEvent: %v", err)
+	}
+	prEvent, ok := event.(*ghapi.PullRequestEvent)
+	if !ok || prEvent.GetAction() != "reopened" || prEvent.GetPullRequest().GetNumber() != 7 || prEvent.GetNumber() != 0 {
+		t.Fatalf("event = %+v, want reopened PR #7", event)
+	}
+
+	if event, err := readEvent("schedule", path); event != nil || err != nil {
+		t.Fatalf("readEvent(schedule) = %v, %v, want nil", event, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"action": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readEvent("pull_request", path); err == nil {
+		t.Fatal("readEvent: want error for a malformed payload")
+	}
+}
+
+func TestIsEventEnabled(t *testing.T) {
+	prEvent := func(action string) interface{} {
+		return &ghapi.PullRequestEvent{Action: ghapi.String(action)}
+	}
+	ac := &ActionConfig{
+		events:  map[string]struct{}{"pull_request": {}, "push": {}},
+		actions: map[string]struct{}{"opened": {}, "edited": {}},
+	}
+	for _, tc := range []struct {
+		name  string
+		event interface{}
+		want  bool
+	}{
+		{"pull_request", prEvent("opened"), true},
+		{"pull_request", prEvent("labeled"), false},
+		{"issue_comment", &ghapi.IssueCommentEvent{Action: ghapi.String("edited")}, false},
+		// push has no activity type
+		{"push", &ghapi.PushEvent{}, true},
+	} {
+		if got := ac.isEventEnabled(tc.name, tc.event); got != tc.want {
+			t.Errorf("isEventEnabled(%v, %v) = %v, want %v", tc.name, eventAction(tc.event), got, tc.want)
+		}
+	}
+
+	if !(&ActionConfig{}).isEventEnabled("pull_request", prEvent("labeled")) {
+		t.Errorf("isEventEnabled = false, want all events enabled by default")
+	}
+}
@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// teamMembershipTTL is how long a team membership is cached, which spares the API in server and batch modes.
+const teamMembershipTTL = 10 * time.Minute
+
+type teamMembership struct {
+	member    bool
+	expiresAt time.Time
+}
+
+// teamMemberships caches the memberships looked up by isTeamMember, keyed by "org/slug@user".
+var teamMemberships = struct {
+	sync.Mutex
+	entries map[string]teamMembership
+}{entries: make(map[string]teamMembership)}
+
+// isTeamMember reports whether user is an active member of team, as "org/slug".
+func (a *Action) isTeamMember(team, user string) (bool, error) {
+	key := team + "@" + user
+	now := time.Now()
+
+	teamMemberships.Lock()
+	cached, ok := teamMemberships.entries[key]
+	teamMemberships.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.member, nil
+	}
+
+	org, slug, _ := strings.Cut(team, "/")
+	membership, resp, err := a.client.Teams.GetTeamMembershipBySlug(a.globalContext, org, slug, user)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return false, fmt.Errorf("get membership of %v in %v: %w", user, team, err)
+	}
+	member := err == nil && membership.GetState() == "active"
+
+	teamMemberships.Lock()
+	teamMemberships.entries[key] = teamMembership{member: member, expiresAt: now.Add(teamMembershipTTL)}
+	teamMemberships.Unlock()
+	return member, nil
+}
+
+// isExemptAuthor reports whether the author of pr is a member of one of EXEMPT_TEAMS,
+// whose PRs skip the missing label check.
+func (a *Action) isExemptAuthor(pr *scm.PullRequest) (bool, error) {
+	if len(a.config.exemptTeams) == 0 || a.client == nil {
+		return false, nil
+	}
+	for _, team := range a.config.exemptTeams {
+		member, err := a.isTeamMember(team, pr.Author)
+		if err != nil {
+			return false, err
+		}
+		if member {
+			logger.Infof("%v is a member of %v, skip the missing label check\n", pr.Author, team)
+			return true, nil
+		}
+	}
+	return false, nil
+}
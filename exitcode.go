@@ -0,0 +1,122 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Failure classes, written to the failure output, and the exit code of each.
+// They tell a non-compliant PR apart from the bot itself breaking.
+const (
+	failureConfig        = "config"
+	failureAPI           = "api"
+	failureLabelMissing  = "label-missing"
+	failureLabelMultiple = "label-multiple"
+	failurePermission    = "permission"
+	failureNonCompliant  = "non-compliant"
+	failureTimeout       = "timeout"
+)
+
+var exitCodes = map[string]int{
+	failureConfig:        2,
+	failureAPI:           3,
+	failureLabelMissing:  4,
+	failureLabelMultiple: 5,
+	failurePermission:    6,
+	failureNonCompliant:  7,
+	failureTimeout:       8,
+}
+
+// complianceError is returned when the PR doesn't comply with the rules, rather than the bot failing.
+type complianceError struct {
+	class   string
+	message string
+}
+
+func (e *complianceError) Error() string {
+	return e.message
+}
+
+func newComplianceError(class, message string) error {
+	return &complianceError{class: class, message: message}
+}
+
+// failureClass classifies an error returned by the action.
+func failureClass(err error) string {
+	var ce *complianceError
+	if errors.As(err, &ce) {
+		return ce.class
+	}
+	if status := errorStatus(err); status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return failurePermission
+	}
+	return failureAPI
+}
+
+// errorStatus returns the HTTP status of the API response err wraps, or 0 if none.
+// Exceeded rate limits are answered with 403 too, but go-github returns them as a *RateLimitError
+// or *AbuseRateLimitError instead of an *ErrorResponse.
+func errorStatus(err error) int {
+	var ghErr *ghapi.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		return ghErr.Response.StatusCode
+	}
+	var glErr *scm.StatusError
+	if errors.As(err, &glErr) {
+		return glErr.StatusCode
+	}
+	return 0
+}
+
+// exit reports err of class in the failure output and exits with the code of class.
+func exit(class string, err error) {
+	logger.Errorf("%v\n", err)
+	if hint := samlHint(err); len(hint) > 0 {
+		githubactions.Errorf("%s", hint)
+	}
+	githubactions.SetOutput("failure", class)
+	os.Exit(exitCodes[class])
+}
+
+// fail exits with the class of err.
+func fail(err error) {
+	exit(failureClass(err), err)
+}
+
+// failRun exits with the class of err failing the run of ctx.
+func failRun(ctx context.Context, err error) {
+	exit(runFailureClass(ctx, err), err)
+}
+
+// runFailureClass classifies err failing the run of ctx, as timed out if ctx expired and failed the requests in flight.
+func runFailureClass(ctx context.Context, err error) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return failureTimeout
+	}
+	return failureClass(err)
+}
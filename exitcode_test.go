@@ -0,0 +1,56 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+func TestFailureClass(t *testing.T) {
+	response := func(status int) *ghapi.ErrorResponse {
+		return &ghapi.ErrorResponse{
+			Response: &http.Response{StatusCode: status, Request: &http.Request{Method: http.MethodGet}},
+			Message:  http.StatusText(status),
+		}
+	}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{newComplianceError(failureLabelMissing, MessageLabelMissing), failureLabelMissing},
+		{fmt.Errorf("add labels: %w", response(http.StatusForbidden)), failurePermission},
+		{fmt.Errorf("get PR: %w", response(http.StatusUnauthorized)), failurePermission},
+		{fmt.Errorf("get PR: %w", response(http.StatusNotFound)), failureAPI},
+		{fmt.Errorf("get MR: %w", &scm.StatusError{Method: http.MethodGet, Path: "/projects/1", StatusCode: http.StatusForbidden}), failurePermission},
+		// rate limits are answered with 403 too
+		{fmt.Errorf("add labels: %w", &ghapi.RateLimitError{Response: response(http.StatusForbidden).Response}), failureAPI},
+		// the status in the text of an error isn't trusted
+		{errors.New("parse config: 403 labels"), failureAPI},
+	}
+	for _, tt := range tests {
+		if got := failureClass(tt.err); got != tt.want {
+			t.Errorf("failureClass(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// runExpire removes the labels of LABEL_TTLS from the open PRs which carried them for longer than their TTL.
+func runExpire(ctx context.Context, ac *ActionConfig) error {
+	return applyExpiry(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+}
+
+// applyExpiry removes the expired labels from the open PRs of the repository with client.
+func applyExpiry(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	logger.Infoln("@List open PRs")
+	prs, err := listOpenPullRequests(ctx, client, ac.GetOwner(), ac.GetRepo())
+	if err != nil {
+		return fmt.Errorf("list open PRs: %w", err)
+	}
+
+	expired := 0
+	for _, pr := range prs {
+		action := newPullRequestAction(ctx, ac, client, ac.GetOwner(), ac.GetRepo(), pr)
+		n, err := action.expireLabels(pr)
+		if err != nil {
+			logger.Errorf("#%d: %v\n", pr.GetNumber(), err)
+			continue
+		}
+		expired += n
+	}
+	logger.Infof("Expired labels: %v\n", expired)
+	return nil
+}
+
+// expireLabels removes the labels of pr whose TTL elapsed since they were last applied,
+// returning the number of labels removed.
+func (a *Action) expireLabels(pr *ghapi.PullRequest) (int, error) {
+	hasTTL := false
+	for _, label := range pr.Labels {
+		if _, exist := a.config.labelTTLs[label.GetName()]; exist {
+			hasTTL = true
+		}
+	}
+	if !hasTTL {
+		return 0, nil
+	}
+
+	timeline, err := a.listTimeline()
+	if err != nil {
+		return 0, fmt.Errorf("list timeline: %w", err)
+	}
+	labeledAt := make(map[string]time.Time)
+	for _, event := range timeline {
+		if event.GetEvent() == "labeled" {
+			labeledAt[event.GetLabel().GetName()] = event.GetCreatedAt().Time
+		}
+	}
+
+	removed := 0
+	for _, label := range pr.Labels {
+		name := label.GetName()
+		ttl, exist := a.config.labelTTLs[name]
+		if !exist || labeledAt[name].IsZero() || time.Since(labeledAt[name]) < ttl {
+			continue
+		}
+
+		logger.Infof("@Remove expired label %v from #%d, applied at %v\n", name, pr.GetNumber(), labeledAt[name])
+		if err := a.provider.RemoveLabel(a.globalContext, pr.GetNumber(), name); err != nil {
+			return removed, fmt.Errorf("remove label %v: %w", name, err)
+		}
+		removed++
+
+		if len(a.config.GetLabelExpiryMessage()) > 0 {
+			err := a.provider.Comment(a.globalContext, pr.GetNumber(),
+				fmt.Sprintf("@%s %s", pr.GetUser().GetLogin(), strings.ReplaceAll(a.config.GetLabelExpiryMessage(), "{label}", name)))
+			if err != nil {
+				return removed, fmt.Errorf("create issue comment: %w", err)
+			}
+		}
+	}
+	return removed, nil
+}
+
+// parseTTL parses a TTL like 14d, or a Go duration like 36h.
+func parseTTL(s string) (time.Duration, error) {
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
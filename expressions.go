@@ -0,0 +1,194 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// ExpressionRule applies its actions when the CEL expression When evaluates to true, e.g.
+//
+//	when: pr.additions > 500 && !labels.exists(l, l == 'doc-required')
+//
+// The expression can refer to:
+//   - pr: number, title, body, author, draft, additions, deletions and changed_files of the PR
+//   - labels: the labels applied on the PR
+//   - files: the paths of the changed files
+type ExpressionRule struct {
+	When string `yaml:"when" schema:"required"`
+	// Labels are added to the expected labels
+	Labels []string `yaml:"labels"`
+	// Comment is posted once on the PR
+	Comment string `yaml:"comment"`
+	// Fail fails the check run with the message
+	Fail string `yaml:"fail"`
+}
+
+var (
+	celEnvOnce sync.Once
+	celEnv     *cel.Env
+	celEnvErr  error
+)
+
+// newCELEnv returns the environment of the expression rules, created once since the backfill workers
+// compile expressions concurrently.
+func newCELEnv() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(
+			cel.Variable("pr", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("labels", cel.ListType(cel.StringType)),
+			cel.Variable("files", cel.ListType(cel.StringType)),
+		)
+	})
+	return celEnv, celEnvErr
+}
+
+// compileExpression compiles a boolean CEL expression.
+func compileExpression(expr string) (cel.Program, error) {
+	env, err := newCELEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must be a bool, got %v", ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// applyExpressionRules evaluates the expression rules against the current PR, adding
+// their labels to the expected labels, posting their comments and recording their failures.
+func (a *Action) applyExpressionRules() error {
+	if a.client == nil {
+		return nil
+	}
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return err
+	}
+	if len(fc.ExpressionRules) == 0 {
+		return nil
+	}
+
+	vars, err := a.expressionVars()
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range fc.ExpressionRules {
+		prg, err := compileExpression(rule.When)
+		if err != nil {
+			return fmt.Errorf("expression %q: %w", rule.When, err)
+		}
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			return fmt.Errorf("evaluate %q: %w", rule.When, err)
+		}
+		if matched, _ := out.Value().(bool); !matched {
+			continue
+		}
+		logger.Infof("Expression rule matched: %v\n", rule.When)
+
+		if len(rule.Labels) > 0 {
+			if a.ruleLabels == nil {
+				a.ruleLabels = make(map[string]bool)
+			}
+			if a.config.labels == nil {
+				a.config.labels = make(map[string]bool)
+			}
+			for _, label := range rule.Labels {
+				a.ruleLabels[label] = true
+				a.config.labels[label] = true
+			}
+		}
+		if len(rule.Comment) > 0 {
+			if err := a.commentOnce(expressionMarker(rule.When), rule.Comment); err != nil {
+				return fmt.Errorf("comment: %w", err)
+			}
+		}
+		if len(rule.Fail) > 0 {
+			a.ruleFailures = append(a.ruleFailures, rule.Fail)
+		}
+	}
+	return nil
+}
+
+// expressionVars returns the variables of the current PR available to expressions.
+func (a *Action) expressionVars() (map[string]interface{}, error) {
+	pr, err := a.getPullRequest()
+	if err != nil {
+		return nil, fmt.Errorf("get PR: %w", err)
+	}
+	labels := make([]string, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+	files, err := a.listFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.GetFilename())
+	}
+
+	return map[string]interface{}{
+		"pr": map[string]interface{}{
+			"number":        int64(pr.GetNumber()),
+			"title":         pr.GetTitle(),
+			"body":          pr.GetBody(),
+			"author":        pr.GetUser().GetLogin(),
+			"draft":         pr.GetDraft(),
+			"additions":     int64(pr.GetAdditions()),
+			"deletions":     int64(pr.GetDeletions()),
+			"changed_files": int64(pr.GetChangedFiles()),
+		},
+		"labels": labels,
+		"files":  paths,
+	}, nil
+}
+
+// expressionMarker identifies the comment of the rule with expression expr.
+func expressionMarker(expr string) string {
+	return fmt.Sprintf("%srule %x -->", markerPrefix, sha1.Sum([]byte(expr)))
+}
+
+// commentOnce posts body with the hidden marker on the current PR, unless a comment
+// of the bot with the marker exists.
+func (a *Action) commentOnce(marker, body string) error {
+	comments, err := a.listBotComments()
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.Contains(c.GetBody(), marker) {
+			return nil
+		}
+	}
+	return a.provider.Comment(a.globalContext, a.config.GetNumber(), fmt.Sprintf("%s\n%s", marker, body))
+}
@@ -0,0 +1,71 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompileExpression(t *testing.T) {
+	vars := map[string]interface{}{
+		"pr":     map[string]interface{}{"additions": int64(600), "title": "[doc] Fix typo"},
+		"labels": []string{"doc"},
+		"files":  []string{"conf/broker.conf"},
+	}
+
+	for expr, want := range map[string]bool{
+		"pr.additions > 500 && !labels.exists(l, l == 'doc-required')": true,
+		"labels.exists(l, l == 'doc')":                                 true,
+		"files.exists(f, f.startsWith('site/'))":                       false,
+		"pr.title.contains('WIP')":                                     false,
+	} {
+		prg, err := compileExpression(expr)
+		if err != nil {
+			t.Fatalf("compile %q: %v", expr, err)
+		}
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			t.Fatalf("evaluate %q: %v", expr, err)
+		}
+		if got := out.Value().(bool); got != want {
+			t.Errorf("%q = %v, want %v", expr, got, want)
+		}
+	}
+
+	for _, expr := range []string{"pr.additions", "labels.exists(", "unknown > 1"} {
+		if _, err := compileExpression(expr); err == nil {
+			t.Errorf("compile %q: err = nil, want error", expr)
+		}
+	}
+}
+
+// TestCompileExpressionConcurrently compiles expressions from several goroutines, as the backfill workers do.
+func TestCompileExpressionConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := compileExpression("labels.exists(l, l == 'doc')"); err != nil {
+				t.Errorf("compile: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
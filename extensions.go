@@ -0,0 +1,93 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// extensionLabelPresets are the built-in labels of changed file extensions selectable by EXTENSION_PRESETS.
+var extensionLabelPresets = map[string]map[string]string{
+	"docs": {".md": "doc", ".mdx": "doc", ".rst": "doc", ".adoc": "doc"},
+	"api":  {".proto": "api", ".graphql": "api", ".thrift": "api", ".avsc": "api"},
+}
+
+// extensionLabelMap returns the labels keyed by lowercase file extension of the extensionLabelPresets named presets, overridden by those of the
+// extension_labels of fc. The extensions of fc may omit the leading ".".
+func extensionLabelMap(presets []string, fc *FileConfig) map[string]string {
+	labels := make(map[string]string)
+	for _, name := range presets {
+		for ext, label := range extensionLabelPresets[name] {
+			labels[ext] = label
+		}
+	}
+	for ext, label := range fc.ExtensionLabels {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		labels[ext] = label
+	}
+	return labels
+}
+
+// extensionLabels returns the labels of the extensions of the changed files of the current PR.
+// They are layered under the content rules: a file matching the paths of a content rule is left to the rule.
+func (a *Action) extensionLabels() (map[string]bool, error) {
+	labels := make(map[string]bool)
+
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	byExtension := extensionLabelMap(a.config.extensionPresets, fc)
+	if len(byExtension) == 0 {
+		return labels, nil
+	}
+
+	files, err := a.listFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	for _, file := range files {
+		name := file.GetFilename()
+		label, ok := byExtension[strings.ToLower(path.Ext(name))]
+		if !ok || labels[label] || hasPathRule(fc.ContentRules, name) {
+			continue
+		}
+		logger.Infof("Extension of %v maps to %v\n", name, label)
+		labels[label] = true
+	}
+
+	return labels, nil
+}
+
+// hasPathRule reports whether one of rules explicitly targets name by its paths.
+func hasPathRule(rules []ContentRule, name string) bool {
+	for _, rule := range rules {
+		if len(rule.Paths) > 0 && matchAny(rule.Paths, name) {
+			return true
+		}
+	}
+	return false
+}
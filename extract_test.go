@@ -0,0 +1,302 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFuzzAction returns an Action extracting labels with pattern, or all presets if empty.
+// It returns nil if pattern is rejected.
+func newFuzzAction(t *testing.T, pattern string) *Action {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_PATTERN_PRESET", "markdown,html,table")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if len(pattern) > 0 {
+		if ac.labelExtractors, err = compileLabelPresets(pattern, nil, false); err != nil {
+			return nil
+		}
+	}
+	return &Action{config: ac}
+}
+
+func FuzzExtractLabels(f *testing.F) {
+	f.Add("- [x] `doc`\r\n- [ ] `doc-required`")
+	f.Add("- [X] `doc-not-needed`\n- [] ``doc``\n- [x]`doc-complete`")
+	f.Add("- [x] ```doc```\n- [ `doc` ]\n- [[x]] `doc`")
+	f.Add("<input type=\"checkbox\" checked> `doc`<input type=checkbox>")
+	f.Add("| [x] | `doc` |\n| [ ] | doc-required |\n|[x]|")
+	f.Add("---\nlabels: [doc, [doc-required]]\n---\n")
+	f.Add(strings.Repeat("- [x] `doc` ", 10000))
+	f.Add(strings.Repeat("`", maxBodyLength+1))
+
+	f.Fuzz(func(t *testing.T, body string) {
+		action := newFuzzAction(t, "")
+		for label := range action.extractLabels(body) {
+			if _, exist := action.config.labelWatchSet[label]; !exist {
+				t.Fatalf("extracted unwatched label %q", label)
+			}
+		}
+	})
+}
+
+func FuzzLabelPattern(f *testing.F) {
+	f.Add("- \\[(.*?)\\] ?`(.+?)`", "- [x] `doc`")
+	f.Add("(x)", "x")
+	f.Add("((((((((((a*)*)*)*)*)*)*)*)*)*)", strings.Repeat("a", 1000))
+	f.Add("(?P<checked>.)(?P<label>.*)", "xdoc")
+	f.Add("[", "")
+
+	f.Fuzz(func(t *testing.T, pattern, body string) {
+		action := newFuzzAction(t, pattern)
+		if action == nil {
+			return // rejected by validation
+		}
+		action.extractLabels(body)
+	})
+}
+
+func TestNamedLabelPattern(t *testing.T) {
+	// the leading group would shift positional groups
+	action := newFuzzAction(t, "(?m)^(-|\\*) \\[(?P<checked>.)\\] (?P<label>\\S+)")
+	if action == nil {
+		t.Fatalf("named pattern is rejected")
+	}
+	want := map[string]bool{"doc": true, "doc-required": false}
+	if got := action.extractLabels("* [x] doc\n- [ ] doc-required"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("extractLabels = %v, want %v", got, want)
+	}
+
+	for _, pattern := range []string{"\\[(?P<checked>.)\\] (\\S+)", "\\[(.)\\] (?P<label>\\S+)", "\\[(.)\\]"} {
+		if _, err := compileLabelPresets(pattern, nil, false); err == nil {
+			t.Fatalf("compileLabelPresets(%q): err = nil, want missing group", pattern)
+		}
+	}
+}
+
+func TestLabelPatternErrors(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		column  int
+	}{
+		{"- \\[(.*\\] `(.+?)`", 5},
+		{"- \\[(.*)\\]) `(.+?)`", 11},
+		{"- [(]\\[(.*)\\] `(.+?)`)", 22},
+		{"- \\[(.*)\\] `(.+??+)`", 15},
+		{"- \\[([z-a])\\] `(.+)`", 7},
+	} {
+		_, err := compileLabelPresets(c.pattern, nil, false)
+		patternErr, ok := err.(*labelPatternError)
+		if !ok || patternErr.Column != c.column {
+			t.Fatalf("compileLabelPresets(%q): err = %v, want a syntax error at column %d", c.pattern, err, c.column)
+		}
+	}
+
+	_, err := compileLabelPresets("- \\[(.*\\] `(.+?)`", nil, false)
+	message := describeLabelPatternError("- \\[(.*\\] `(.+?)`", err)
+	if want := "\n- \\[(.*\\] `(.+?)`\n    ^"; !strings.HasSuffix(message, want) {
+		t.Fatalf("message = %q, want the position %q", message, want)
+	}
+
+	if _, err := compileLabelPresets(strings.Repeat("(.)", maxLabelPatternGroups+1), nil, false); err == nil {
+		t.Fatalf("compileLabelPresets: err = nil, want too many groups")
+	}
+	if _, err := compileLabelPresets("(.)(.)"+strings.Repeat("x", maxLabelPatternLength), nil, false); err == nil {
+		t.Fatalf("compileLabelPresets: err = nil, want too long")
+	}
+}
+
+func TestSetCheckboxes(t *testing.T) {
+	body := "### Documentation\r\n\r\n- [ ] `doc`\r\n- [x] `doc-required`\r\n\r\n### Notes\r\n"
+	got, err := setCheckboxes(body, map[string]bool{"doc": true, "doc-required": false, "doc-complete": true}, false)
+	if err != nil {
+		t.Fatalf("setCheckboxes: %v", err)
+	}
+	want := "### Documentation\r\n\r\n- [x] `doc`\r\n- [ ] `doc-required`\r\n- [x] `doc-complete`\r\n\r\n### Notes\r\n"
+	if got != want {
+		t.Fatalf("setCheckboxes = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("x", maxEditableBodyLength-30) + "\r\n- [ ] `doc`"
+	if _, err := setCheckboxes(long, map[string]bool{"doc": true}, false); err != nil {
+		t.Fatalf("setCheckboxes in place: %v", err)
+	}
+	if _, err := setCheckboxes(long, map[string]bool{"doc-complete": true}, false); err != errBodyTooLong {
+		t.Fatalf("setCheckboxes: err = %v, want %v", err, errBodyTooLong)
+	}
+}
+
+func TestLabelPatternPresets(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	for _, tt := range []struct {
+		presets, body string
+		want          map[string]bool
+	}{
+		{"html", `<input type="checkbox" checked> <code>doc-required</code><br><INPUT TYPE=checkbox disabled> ` + "`doc`",
+			map[string]bool{"doc-required": true, "doc": false}},
+		{"table", "| | Label | Why |\n| --- | --- | --- |\n| [x] | `doc-not-needed` | no docs |\n| [ ] | doc-complete |",
+			map[string]bool{"doc-not-needed": true, "doc-complete": false}},
+		// the presets are combined, and the markdown preset isn't enabled unless listed
+		{"html,table", "<input type=\"checkbox\" checked> `doc`\n| [x] | `doc-required` |\n- [x] `doc-complete`",
+			map[string]bool{"doc": true, "doc-required": true}},
+	} {
+		t.Setenv("LABEL_PATTERN_PRESET", tt.presets)
+		ac, err := NewActionConfig()
+		if err != nil {
+			t.Fatalf("NewActionConfig: %v", err)
+		}
+		action := &Action{config: ac}
+		if got := action.extractLabels(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v extractLabels(%q) = %v, want %v", tt.presets, tt.body, got, tt.want)
+		}
+	}
+
+	t.Setenv("LABEL_PATTERN_PRESET", "markdown,xml")
+	if _, err := NewActionConfig(); err == nil || !strings.Contains(err.Error(), "LABEL_PATTERN_PRESET") {
+		t.Fatalf("NewActionConfig: err = %v, want LABEL_PATTERN_PRESET is invalid", err)
+	}
+}
+
+func TestFrontMatterLabels(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		want []string
+	}{
+		{"---\nlabels: [doc-not-needed]\n---\nFix the broker", []string{"doc-not-needed"}},
+		{"\r\n---\r\nlabels:\r\n  - doc\r\n  - doc-required\r\n---", []string{"doc", "doc-required"}},
+		{"---\ntitle: Fix\n---\n", nil},
+		{"---\nlabels: [doc\n---\n", nil},
+		{"Fix the broker\n---\nlabels: [doc]\n---\n", nil},
+		{"- [x] `doc`", nil},
+	} {
+		if got := extractFrontMatterLabels(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractFrontMatterLabels(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	action := &Action{config: ac}
+	body := "---\nlabels: [doc-required, area/broker]\n---\n- [ ] `doc-required`\n- [x] `doc-complete`\n"
+	want := map[string]bool{"doc-required": true, "doc-complete": true}
+	if got := action.extractLabels(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("extractLabels = %v, want the front-matter and checked labels %v", got, want)
+	}
+}
+
+func TestLenientCheckboxes(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed,doc-complete")
+	t.Setenv("LABEL_TOLERANCE", "lenient")
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	action := &Action{config: ac}
+
+	body := "* [x] doc-required\n- [X] `doc`\n  + [ ] doc-not-needed: no docs\n- [x] `doc-complete` and more"
+	want := map[string]bool{"doc": true, "doc-required": true, "doc-not-needed": false, "doc-complete": true}
+	if got := action.extractLabels(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("extractLabels = %v, want %v", got, want)
+	}
+
+	got, err := setCheckboxes(body, map[string]bool{"doc-required": false, "doc-not-needed": true}, true)
+	if err != nil {
+		t.Fatalf("setCheckboxes: %v", err)
+	}
+	if want := "* [ ] doc-required\n- [X] `doc`\n  + [x] doc-not-needed: no docs\n- [x] `doc-complete` and more"; got != want {
+		t.Fatalf("setCheckboxes = %q, want %q", got, want)
+	}
+
+	t.Setenv("LABEL_TOLERANCE", "strict")
+	if ac, err = NewActionConfig(); err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	action = &Action{config: ac}
+	if got := action.extractLabels(body); !reflect.DeepEqual(got, map[string]bool{"doc": true, "doc-complete": true}) {
+		t.Fatalf("strict extractLabels = %v, want only backticked dash checkboxes", got)
+	}
+}
+
+// newLargeBody returns a body of about 60KB, like the PR templates of monorepos, with a checkbox for each label.
+func newLargeBody(labels []string) string {
+	b := &strings.Builder{}
+	for i, label := range labels {
+		if i%20 == 0 {
+			fmt.Fprintf(b, "\r\n### Section %d\r\n\r\n%s\r\n\r\n", i/20, strings.Repeat("Describe the change and how it was verified. ", 70))
+		}
+		fmt.Fprintf(b, "- [ ] `%s` applies to component %d\r\n", label, i)
+	}
+	return b.String()
+}
+
+func newLargeBodyAction(b *testing.B) (*Action, []string) {
+	labels := make([]string, 300)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("area/component-%03d", i)
+	}
+	b.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	b.Setenv("LABEL_WATCH_LIST", strings.Join(labels, ","))
+	ac, err := NewActionConfig()
+	if err != nil {
+		b.Fatalf("NewActionConfig: %v", err)
+	}
+	return &Action{config: ac}, labels
+}
+
+func BenchmarkExtractLabels(b *testing.B) {
+	action, labels := newLargeBodyAction(b)
+	body := newLargeBody(labels)
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		action.extractLabels(body)
+	}
+}
+
+func BenchmarkSetCheckboxes(b *testing.B) {
+	_, labels := newLargeBodyAction(b)
+	body := newLargeBody(labels)
+	changeList := make(map[string]bool)
+	for i, label := range labels {
+		if i%2 == 0 {
+			changeList[label] = true
+		}
+	}
+	changeList["area/new-component"] = true
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := setCheckboxes(body, changeList, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
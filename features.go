@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/maxsxu/action-labeler/pkg/glob"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// FeatureFlags enables or disables capabilities of the bot on the repos matching Repos, so that an org-wide
+// rollout can start in report-only mode. Capabilities are enabled unless a matching entry disables them,
+// the last matching entry setting a capability wins.
+type FeatureFlags struct {
+	// Repos are globs of owner/repo, e.g. apache/*
+	Repos     []string `yaml:"repos" schema:"required"`
+	Labels    *bool    `yaml:"labels" description:"Apply and remove labels"`
+	BodyEdits *bool    `yaml:"body_edits" description:"Edit the PR body, like its checkboxes, and the PR title"`
+	Comments  *bool    `yaml:"comments" description:"Post comments"`
+	CheckRuns *bool    `yaml:"check_runs" description:"Report check runs"`
+	PathRules *bool    `yaml:"path_rules" description:"Apply the content rules, extension labels and component files to the changed files"`
+}
+
+// features are the capabilities of the bot on a repo.
+type features struct {
+	labels    bool
+	bodyEdits bool
+	comments  bool
+	checkRuns bool
+	pathRules bool
+}
+
+// resolveFeatures returns the capabilities of the bot on the repo fullName.
+func resolveFeatures(flags []FeatureFlags, fullName string) features {
+	f := features{labels: true, bodyEdits: true, comments: true, checkRuns: true, pathRules: true}
+	for _, flag := range flags {
+		matched := false
+		for _, pattern := range flag.Repos {
+			matched = matched || glob.Match(pattern, fullName)
+		}
+		if !matched {
+			continue
+		}
+		for _, c := range []struct {
+			flag    *bool
+			feature *bool
+		}{
+			{flag.Labels, &f.labels},
+			{flag.BodyEdits, &f.bodyEdits},
+			{flag.Comments, &f.comments},
+			{flag.CheckRuns, &f.checkRuns},
+			{flag.PathRules, &f.pathRules},
+		} {
+			if c.flag != nil {
+				*c.feature = *c.flag
+			}
+		}
+	}
+	return f
+}
+
+// features returns the capabilities of the bot on the current repo, set by the features of the deployment config.
+func (ac *ActionConfig) features() features {
+	return resolveFeatures(ac.featureFlags, ac.GetOwner()+"/"+ac.GetRepo())
+}
+
+// featureProvider skips the changes of the capabilities disabled on the repo, logging them instead.
+type featureProvider struct {
+	scm.Provider
+	features features
+}
+
+// newFeatureProvider returns p restricted to the capabilities enabled on the repo of ac, or p if all are.
+func newFeatureProvider(p scm.Provider, ac *ActionConfig) scm.Provider {
+	f := ac.features()
+	if f.labels && f.bodyEdits && f.comments {
+		return p
+	}
+	return &featureProvider{Provider: p, features: f}
+}
+
+func (p *featureProvider) AddLabels(ctx context.Context, number int, labels []string) error {
+	if !p.features.labels {
+		logger.Infof("Labels are disabled, skip adding %v to #%d\n", labels, number)
+		return nil
+	}
+	return p.Provider.AddLabels(ctx, number, labels)
+}
+
+func (p *featureProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	if !p.features.labels {
+		logger.Infof("Labels are disabled, skip removing %v from #%d\n", label, number)
+		return nil
+	}
+	return p.Provider.RemoveLabel(ctx, number, label)
+}
+
+func (p *featureProvider) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	if !p.features.labels {
+		logger.Infof("Labels are disabled, skip adding %v to and removing %v from #%d\n", add, remove, number)
+		return nil
+	}
+	return p.Provider.EditLabels(ctx, number, add, remove)
+}
+
+func (p *featureProvider) Comment(ctx context.Context, number int, body string) error {
+	if !p.features.comments {
+		logger.Infof("Comments are disabled, skip commenting on #%d:\n%v\n", number, body)
+		return nil
+	}
+	return p.Provider.Comment(ctx, number, body)
+}
+
+func (p *featureProvider) EditComment(ctx context.Context, number int, id int64, body string) error {
+	if !p.features.comments {
+		logger.Infof("Comments are disabled, skip editing comment %d on #%d:\n%v\n", id, number, body)
+		return nil
+	}
+	return p.Provider.EditComment(ctx, number, id, body)
+}
+
+func (p *featureProvider) EditBody(ctx context.Context, number int, body string) error {
+	if !p.features.bodyEdits {
+		logger.Infof("Body edits are disabled, skip editing the body of #%d\n", number)
+		return nil
+	}
+	return p.Provider.EditBody(ctx, number, body)
+}
+
+func (p *featureProvider) EditTitle(ctx context.Context, number int, title string) error {
+	if !p.features.bodyEdits {
+		logger.Infof("Body edits are disabled, skip editing the title of #%d to %q\n", number, title)
+		return nil
+	}
+	return p.Provider.EditTitle(ctx, number, title)
+}
@@ -0,0 +1,105 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/schema"
+)
+
+// FileConfig is the optional YAML configuration stored in the repository at CONFIG_PATH.
+type FileConfig struct {
+	// Settings are the lowest layer of the action inputs, read by loadConfigLayers
+	Settings         map[string]string `yaml:"settings" description:"Action inputs keyed by name, overridden by environment variables and inputs"`
+	ContentRules     []ContentRule     `yaml:"content_rules" description:"Labels applied when a line added in the diff matches a pattern"`
+	ExtensionLabels  map[string]string `yaml:"extension_labels" description:"Labels applied when a file with the extension changes, overriding the extension presets"`
+	ExpressionRules  []ExpressionRule  `yaml:"expression_rules" description:"CEL expressions applying labels, commenting or failing the check run"`
+	CommitTrailers   []CommitTrailer   `yaml:"commit_trailers" description:"Commit trailer values mapped to labels"`
+	AssociationRules []AssociationRule `yaml:"author_associations" description:"Enforcement of the label checks by author association, the first match wins"`
+	Labels           []LabelDefinition `yaml:"labels" description:"Descriptions and colors of the repository labels, synced in label-sync mode"`
+	// ChecklistTemplate is a text/template replacing the generated checklist of watched labels
+	ChecklistTemplate string `yaml:"checklist_template" description:"Go template of the checklist offered to authors, with .WatchedLabels and the changed function"`
+	// Features are read by loadConfigLayers from the config files of the deployment only, not from each repo
+	Features []FeatureFlags `yaml:"features" description:"Capabilities of the bot per repository, read from the config of the deployment only"`
+}
+
+// fileConfigSchemaID is where the JSON Schema of FileConfig is published, generated into docbot.schema.json.
+const fileConfigSchemaID = "https://raw.githubusercontent.com/maxsxu/action-labeler/master/docbot.schema.json"
+
+// fileConfigSchema returns the JSON Schema of FileConfig.
+func fileConfigSchema() schema.Schema {
+	s := schema.Generate(reflect.TypeOf(FileConfig{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = fileConfigSchemaID
+	s["title"] = "docbot config"
+	return s
+}
+
+// unmarshalFileConfig validates data against the schema of FileConfig before decoding it into fc,
+// so that misspelled keys and mistyped values are reported with their path.
+func unmarshalFileConfig(data []byte, fc *FileConfig) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := schema.Validate(fileConfigSchema(), doc); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, fc)
+}
+
+// getFileConfig returns the repository configuration, loading it on first use.
+// A missing file results in an empty configuration.
+func (a *Action) getFileConfig() (*FileConfig, error) {
+	if a.fileConfig != nil {
+		return a.fileConfig, nil
+	}
+
+	fc := &FileConfig{}
+	if a.client != nil && len(a.config.GetConfigPath()) > 0 {
+		content, _, resp, err := a.client.Repositories.GetContents(a.globalContext, a.config.GetOwner(), a.config.GetRepo(),
+			a.config.GetConfigPath(), nil)
+		if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+			return nil, fmt.Errorf("get %v: %w", a.config.GetConfigPath(), err)
+		}
+		if err == nil {
+			if err := decodeFileConfig(content, fc); err != nil {
+				return nil, fmt.Errorf("parse %v: %w", a.config.GetConfigPath(), err)
+			}
+			logger.Infof("Loaded config from %v\n", a.config.GetConfigPath())
+		}
+	}
+
+	a.fileConfig = fc
+	return fc, nil
+}
+
+func decodeFileConfig(content *ghapi.RepositoryContent, fc *FileConfig) error {
+	data, err := content.GetContent()
+	if err != nil {
+		return err
+	}
+	return unmarshalFileConfig([]byte(data), fc)
+}
@@ -0,0 +1,67 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestFileConfigSchema checks that docbot.schema.json is up to date, and regenerates it with UPDATE_SCHEMA=1.
+func TestFileConfigSchema(t *testing.T) {
+	data, err := json.MarshalIndent(fileConfigSchema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	if os.Getenv("UPDATE_SCHEMA") == "1" {
+		if err := os.WriteFile("docbot.schema.json", data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	published, err := os.ReadFile("docbot.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(published) != string(data) {
+		t.Fatalf("docbot.schema.json is out of date, run UPDATE_SCHEMA=1 go test -run TestFileConfigSchema")
+	}
+}
+
+func TestUnmarshalFileConfig(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{"content_rules:\n  - label: doc-required\n    paths: ['conf/*.conf']\n    pattern: '^\\w+='\n", ""},
+		{"settings:\n  skip-label: wip\ncommit_trailers:\n  - key: Docs-Impact\n    labels: {yes: doc-required}\n", ""},
+		{"content_rule:\n  - label: doc-required\n",
+			"content_rule: unknown key, expected one of author_associations, checklist_template, commit_trailers, content_rules, expression_rules, extension_labels, features, labels, settings"},
+		{"content_rules:\n  - label: doc-required\n    paths: conf/broker.conf\n    pattern: x\n",
+			"content_rules[0].paths: expected array, got string"},
+		{"expression_rules:\n  - labels: [doc]\n", `expression_rules[0]: missing required key "when"`},
+		{"settings:\n  stale-days: 10\n", "settings.stale-days: expected string, got integer"},
+	}
+	for _, tt := range tests {
+		err := unmarshalFileConfig([]byte(tt.config), &FileConfig{})
+		if (err == nil && len(tt.err) > 0) || (err != nil && err.Error() != tt.err) {
+			t.Errorf("unmarshalFileConfig(%q) = %v, want %q", tt.config, err, tt.err)
+		}
+	}
+}
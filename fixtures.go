@@ -0,0 +1,66 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/maxsxu/action-labeler/pkg/fixture"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// newFixtureTransport returns the transport recording into RECORD_DIR, or replaying
+// from REPLAY_DIR in replay mode, or the configured transport to talk to GitHub directly.
+func newFixtureTransport(ac *ActionConfig) (http.RoundTripper, error) {
+	if ac.GetMode() == "replay" {
+		logger.Infof("@Replay fixtures from %v\n", ac.GetReplayDir())
+		return fixture.NewReplayer(ac.GetReplayDir())
+	}
+	if len(ac.GetRecordDir()) > 0 {
+		logger.Infof("@Record fixtures into %v\n", ac.GetRecordDir())
+		return fixture.NewRecorder(ac.GetRecordDir(), ac.transport())
+	}
+	return ac.transport(), nil
+}
+
+// prepareReplay points the GitHub context at the run recorded in dir.
+func prepareReplay(dir string) error {
+	if len(dir) == 0 {
+		return fmt.Errorf("REPLAY_DIR is not set")
+	}
+
+	c, err := fixture.LoadContext(dir)
+	if err != nil {
+		return fmt.Errorf("load context: %w", err)
+	}
+
+	env := map[string]string{
+		"GITHUB_EVENT_NAME": c.EventName,
+		"GITHUB_REPOSITORY": c.Repository,
+		"GITHUB_EVENT_PATH": filepath.Join(dir, "event.json"),
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
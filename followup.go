@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+const MessageDocFollowUp = "Opened %s to track the documentation of this PR."
+
+// followUpMarker identifies the follow-up issue of a PR, and the comment linking it.
+func followUpMarker(owner, repo string, number int) string {
+	return fmt.Sprintf("%sfollow-up %s/%s#%d -->", markerPrefix, owner, repo, number)
+}
+
+// followUpDocs opens a tracking issue in DOCS_REPO for a merged PR labeled FOLLOWUP_LABEL,
+// links it back in a comment, and replaces the label with PENDING_LABEL.
+func (a *Action) followUpDocs() error {
+	if len(a.config.GetDocsRepo()) == 0 || a.client == nil {
+		return nil
+	}
+	pr, err := a.getPullRequest()
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	if !pr.GetMerged() {
+		return nil
+	}
+	labeled := false
+	for _, label := range pr.Labels {
+		if label.GetName() == a.config.GetFollowUpLabel() {
+			labeled = true
+		}
+	}
+	if !labeled {
+		return nil
+	}
+
+	marker := followUpMarker(a.config.GetOwner(), a.config.GetRepo(), pr.GetNumber())
+	comments, err := a.listBotComments()
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	linked := false
+	for _, c := range comments {
+		if strings.Contains(c.GetBody(), marker) {
+			linked = true
+		}
+	}
+
+	if !linked {
+		docsOwner, docsRepo, _ := strings.Cut(a.config.GetDocsRepo(), "/")
+		logger.Infof("@Open follow-up issue in %v\n", a.config.GetDocsRepo())
+		issue, _, err := a.client.Issues.Create(a.globalContext, docsOwner, docsRepo, &ghapi.IssueRequest{
+			Title: ghapi.String(fmt.Sprintf("Document %s", pr.GetTitle())),
+			Body: ghapi.String(fmt.Sprintf("%s\n%s was merged with the `%s` label and needs documentation.",
+				marker, pr.GetHTMLURL(), a.config.GetFollowUpLabel())),
+		})
+		if err != nil {
+			return fmt.Errorf("create issue: %w", err)
+		}
+		err = a.provider.Comment(a.globalContext, pr.GetNumber(),
+			fmt.Sprintf("%s\n%s", marker, fmt.Sprintf(MessageDocFollowUp, issue.GetHTMLURL())))
+		if err != nil {
+			return fmt.Errorf("create issue comment: %w", err)
+		}
+	}
+
+	logger.Infof("@Replace label %v with %v\n", a.config.GetFollowUpLabel(), a.config.GetPendingLabel())
+	if err := a.provider.AddLabels(a.globalContext, pr.GetNumber(), []string{a.config.GetPendingLabel()}); err != nil {
+		return fmt.Errorf("add label %v: %w", a.config.GetPendingLabel(), err)
+	}
+	if err := a.provider.RemoveLabel(a.globalContext, pr.GetNumber(), a.config.GetFollowUpLabel()); err != nil {
+		return fmt.Errorf("remove label %v: %w", a.config.GetFollowUpLabel(), err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterRegexp matches a YAML front-matter block at the top of the PR body.
+var frontMatterRegexp = regexp.MustCompile(`(?s)\A\s*---\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+
+type frontMatter struct {
+	Labels []string `yaml:"labels"`
+}
+
+// extractFrontMatterLabels returns the labels declared in the front-matter of body, e.g.
+//
+//	---
+//	labels: [doc-required]
+//	---
+//
+// It returns nil if body has no valid front-matter.
+func extractFrontMatterLabels(body string) []string {
+	m := frontMatterRegexp.FindStringSubmatch(body)
+	if m == nil {
+		return nil
+	}
+
+	fm := &frontMatter{}
+	if err := yaml.Unmarshal([]byte(m[1]), fm); err != nil {
+		return nil
+	}
+	return fm.Labels
+}
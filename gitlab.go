@@ -0,0 +1,67 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/maxsxu/action-labeler/pkg/breaker"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// runGitLab reconciles the labels of the merge request of the current GitLab CI pipeline.
+func runGitLab(ctx context.Context, ac *ActionConfig) error {
+	apiURL := os.Getenv("CI_API_V4_URL")
+	if len(apiURL) == 0 {
+		apiURL = "https://gitlab.com/api/v4"
+	}
+
+	project := os.Getenv("CI_PROJECT_ID")
+	if len(project) == 0 {
+		return fmt.Errorf("CI_PROJECT_ID is not found")
+	}
+
+	number, err := strconv.Atoi(os.Getenv("CI_MERGE_REQUEST_IID"))
+	if err != nil {
+		return fmt.Errorf("CI_MERGE_REQUEST_IID is not found, the pipeline must run for a merge request")
+	}
+
+	ctx = breaker.WithBreaker(ctx, breaker.New(ac.GetMaxMutations()))
+	provider := newCachedProvider(&versionProvider{Provider: scm.NewGitLab(&http.Client{Transport: breaker.Transport(ac.transport())}, apiURL, project, ac.GetToken())})
+	action := &Action{
+		config:        ac.withNumber(number),
+		globalContext: ctx,
+		provider:      provider,
+		cache:         provider.cache,
+	}
+
+	logger.Infof("@Handle merge request !%d of project %v\n", number, project)
+	mr, err := action.provider.GetPR(ctx, number)
+	if err != nil {
+		return fmt.Errorf("get MR: %w", err)
+	}
+
+	action.config.labels = action.extractLabels(mr.Body)
+
+	return action.Run("edited")
+}
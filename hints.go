@@ -0,0 +1,55 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const MessageLabelHints = `Available labels:
+
+%s`
+
+// withHints appends the watched labels with the hints of the labels of the config file to message,
+// if any label has a hint, so that authors can pick one without opening the guide.
+func (a *Action) withHints(message string) string {
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return message
+	}
+	hints := make(map[string]string)
+	for _, label := range fc.Labels {
+		if len(label.Hint) > 0 {
+			hints[label.Name] = label.Hint
+		}
+	}
+	if len(hints) == 0 {
+		return message
+	}
+
+	list := &strings.Builder{}
+	for _, label := range a.watchedLabels() {
+		if hint, ok := hints[label]; ok {
+			fmt.Fprintf(list, "- `%s`: %s\n", label, hint)
+		} else {
+			fmt.Fprintf(list, "- `%s`\n", label)
+		}
+	}
+	return fmt.Sprintf("%s\n\n%s", strings.TrimRight(message, "\n"), fmt.Sprintf(MessageLabelHints, list))
+}
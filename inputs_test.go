@@ -0,0 +1,55 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestActionInputs(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_PATTERN", "- \\[(.*?)\\] ?\\*\\*(.+?)\\*\\*")
+	t.Setenv("BATCH_WORKERS", "2")
+
+	// the environment variables are the fallback of the inputs
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if ac.GetLabelPattern() != os.Getenv("LABEL_PATTERN") || ac.GetBatchWorkers() != 2 {
+		t.Fatalf("label pattern %q, batch workers %d, want the environment variables", ac.GetLabelPattern(), ac.GetBatchWorkers())
+	}
+
+	// the with: inputs win
+	t.Setenv("INPUT_LABEL-PATTERN", "- \\[(.*?)\\] ?`(.+?)`")
+	t.Setenv("INPUT_BATCH-WORKERS", "8")
+	ac, err = NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if ac.GetLabelPattern() != os.Getenv("INPUT_LABEL-PATTERN") || ac.GetBatchWorkers() != 8 {
+		t.Fatalf("label pattern %q, batch workers %d, want the inputs", ac.GetLabelPattern(), ac.GetBatchWorkers())
+	}
+
+	t.Setenv("INPUT_BATCH-WORKERS", "zero")
+	if _, err := NewActionConfig(); err == nil || !strings.Contains(err.Error(), "BATCH_WORKERS is invalid: zero") {
+		t.Fatalf("NewActionConfig: err = %v, want invalid BATCH_WORKERS", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// LabelDefinition declares the metadata of a repository label in the config file.
+type LabelDefinition struct {
+	Name string `yaml:"name" schema:"required"`
+	// Description and Color are left as they are in the repository if empty
+	Description string `yaml:"description"`
+	Color       string `yaml:"color" description:"Hex color without the leading #, e.g. d73a4a"`
+	// Hint tells authors when to pick the label, it isn't synced to the repository
+	Hint string `yaml:"hint" description:"One line telling authors when to pick the label, listed in the missing-label reminder"`
+}
+
+// labelDrift is the metadata of a repository label differing from its definition.
+type labelDrift struct {
+	name        string
+	description [2]string
+	color       [2]string
+}
+
+// runLabelSync updates the description and color of the repository labels drifted from the labels
+// of the config file, or only reports the diff with LABEL_SYNC_DRY_RUN. Labels missing from the repository are not created.
+func runLabelSync(ctx context.Context, ac *ActionConfig) error {
+	return syncLabels(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+}
+
+func syncLabels(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	action := &Action{
+		config:        ac,
+		globalContext: ctx,
+		client:        client,
+	}
+
+	fc, err := action.getFileConfig()
+	if err != nil {
+		return err
+	}
+	if len(fc.Labels) == 0 {
+		logger.Infof("No labels are defined in %v\n", ac.GetConfigPath())
+		return nil
+	}
+
+	logger.Infoln("@List repository labels")
+	labels, err := action.listRepoLabels()
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	drifts := labelDrifts(fc.Labels, labels)
+
+	diff := labelDriftDiff(drifts)
+	logger.Infof("Drifted labels: %v\n%v", len(drifts), diff)
+	if len(drifts) > 0 {
+		githubactions.AddStepSummary(fmt.Sprintf("### Drifted labels\n\n```diff\n%v```\n", diff))
+	}
+	if ac.GetLabelSyncDryRun() {
+		logger.Infoln("Dry run, labels are not updated")
+		return nil
+	}
+
+	for _, d := range drifts {
+		logger.Infof("@Update label %v\n", d.name)
+		_, _, err := client.Issues.EditLabel(ctx, ac.GetOwner(), ac.GetRepo(), d.name, &ghapi.Label{
+			Description: ghapi.String(d.description[1]),
+			Color:       ghapi.String(d.color[1]),
+		})
+		if err != nil {
+			return fmt.Errorf("update label %v: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// listRepoLabels lists all labels of the repository.
+func (a *Action) listRepoLabels() ([]*ghapi.Label, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	labels := make([]*ghapi.Label, 0)
+	for {
+		l, resp, err := a.client.Issues.ListLabels(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, l...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return labels, nil
+}
+
+// labelDrifts returns the repository labels whose description or color differs from their definition, in the order of definitions.
+func labelDrifts(definitions []LabelDefinition, labels []*ghapi.Label) []labelDrift {
+	repo := make(map[string]*ghapi.Label)
+	for _, l := range labels {
+		repo[l.GetName()] = l
+	}
+
+	drifts := []labelDrift{}
+	for _, def := range definitions {
+		l, exist := repo[def.Name]
+		if !exist {
+			logger.Infof("Label %v is not in the repository, skip it\n", def.Name)
+			continue
+		}
+
+		d := labelDrift{
+			name:        def.Name,
+			description: [2]string{l.GetDescription(), l.GetDescription()},
+			color:       [2]string{l.GetColor(), l.GetColor()},
+		}
+		if len(def.Description) > 0 {
+			d.description[1] = def.Description
+		}
+		if color := strings.ToLower(strings.TrimPrefix(def.Color, "#")); len(color) > 0 {
+			d.color[1] = color
+		}
+		if d.description[0] != d.description[1] || !strings.EqualFold(d.color[0], d.color[1]) {
+			drifts = append(drifts, d)
+		}
+	}
+	return drifts
+}
+
+// labelDriftDiff renders drifts as a diff of the label metadata, the repository first.
+func labelDriftDiff(drifts []labelDrift) string {
+	diff := &strings.Builder{}
+	for _, d := range drifts {
+		fmt.Fprintf(diff, "@@ %v @@\n", d.name)
+		if d.description[0] != d.description[1] {
+			fmt.Fprintf(diff, "-description: %q\n+description: %q\n", d.description[0], d.description[1])
+		}
+		if !strings.EqualFold(d.color[0], d.color[1]) {
+			fmt.Fprintf(diff, "-color: %v\n+color: %v\n", d.color[0], d.color[1])
+		}
+	}
+	return diff.String()
+}
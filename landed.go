@@ -0,0 +1,126 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// docsReferenceRegexp matches a PR referenced in a commit message, e.g. "Docs for #123" or "apache/pulsar#123".
+var docsReferenceRegexp = regexp.MustCompile(`(?:\b([\w.-]+)/([\w.-]+))?#(\d+)\b`)
+
+// docsReference is a PR referenced by a commit landing docs.
+type docsReference struct {
+	owner  string
+	repo   string
+	number int
+}
+
+// landedDocsReferences returns the PRs referenced by the commits of a push to the default branch
+// that change files matching DOCS_PATHS. A reference without repository is to the pushed repository.
+func landedDocsReferences(ac *ActionConfig, event *ghapi.PushEvent) []docsReference {
+	if event.GetRef() != "refs/heads/"+event.GetRepo().GetDefaultBranch() {
+		return nil
+	}
+
+	seen := make(map[docsReference]struct{})
+	refs := []docsReference{}
+	for _, commit := range event.Commits {
+		docs := false
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, file := range files {
+				if matchAny(ac.docsPaths, file) {
+					docs = true
+				}
+			}
+		}
+		if !docs {
+			continue
+		}
+		for _, m := range docsReferenceRegexp.FindAllStringSubmatch(commit.GetMessage(), -1) {
+			ref := docsReference{owner: ac.GetOwner(), repo: ac.GetRepo()}
+			if len(m[1]) > 0 {
+				ref.owner, ref.repo = m[1], m[2]
+			}
+			ref.number, _ = strconv.Atoi(m[3])
+			if _, exist := seen[ref]; !exist {
+				seen[ref] = struct{}{}
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// runLandedDocs flips the PRs referenced by docs landing on the default branch from PENDING_LABEL to COMPLETE_LABEL.
+func runLandedDocs(ctx context.Context, ac *ActionConfig, event *ghapi.PushEvent) error {
+	if len(ac.docsPaths) == 0 {
+		return nil
+	}
+	return completeLandedDocs(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()), event)
+}
+
+func completeLandedDocs(ctx context.Context, ac *ActionConfig, client *ghapi.Client, event *ghapi.PushEvent) error {
+	failed := 0
+	for _, ref := range landedDocsReferences(ac, event) {
+		if err := completePendingDocs(ctx, ac, client, ref); err != nil {
+			failed++
+			logger.Errorf("%v/%v#%d: %v\n", ref.owner, ref.repo, ref.number, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("complete docs failed for %v PRs", failed)
+	}
+	return nil
+}
+
+// completePendingDocs replaces PENDING_LABEL of the referenced PR with COMPLETE_LABEL.
+// References to issues, or to PRs without PENDING_LABEL, are ignored.
+func completePendingDocs(ctx context.Context, ac *ActionConfig, client *ghapi.Client, ref docsReference) error {
+	issue, _, err := client.Issues.Get(ctx, ref.owner, ref.repo, ref.number)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	if !issue.IsPullRequest() {
+		return nil
+	}
+	pending := false
+	for _, label := range issue.Labels {
+		if label.GetName() == ac.GetPendingLabel() {
+			pending = true
+		}
+	}
+	if !pending {
+		logger.Infof("%v/%v#%d has no %v label, skipping\n", ref.owner, ref.repo, ref.number, ac.GetPendingLabel())
+		return nil
+	}
+
+	pr, _, err := client.PullRequests.Get(ctx, ref.owner, ref.repo, ref.number)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	action := newPullRequestAction(ctx, ac, client, ref.owner, ref.repo, pr)
+	logger.Infof("@Replace label %v of %v/%v#%d with %v\n", ac.GetPendingLabel(), ref.owner, ref.repo, ref.number, ac.GetCompleteLabel())
+	return action.provider.EditLabels(action.globalContext, ref.number, []string{ac.GetCompleteLabel()}, []string{ac.GetPendingLabel()})
+}
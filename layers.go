@@ -0,0 +1,183 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+)
+
+// Settings are resolved from these layers, the later overriding the earlier:
+//
+//  1. file: the settings section of the config file at CONFIG_PATH in the workspace,
+//  2. org: the settings section of the config file at CONFIG_PATH in the .github repository
+//     of the owner, if ORG_DEFAULTS is true,
+//  3. env: the environment variable in upper snake case, e.g. LABEL_PATTERN,
+//  4. input: the action input, e.g. label-pattern.
+const (
+	sourceFile  = "file"
+	sourceOrg   = "org"
+	sourceEnv   = "env"
+	sourceInput = "input"
+)
+
+// configLayers are the file and org settings, loaded by loadConfigLayers.
+var configLayers = map[string]map[string]string{}
+
+// layerFeatures are the features of the org and file layers, the file ones last.
+var layerFeatures = []FeatureFlags{}
+
+// inputSources records where each setting read through getInput came from, for printConfig.
+var inputSources = map[string]string{}
+
+// getInput reads the setting name from the highest layer defining it.
+func getInput(name string) string {
+	value, source := lookupInput(name)
+	inputSources[name] = source
+	return value
+}
+
+func lookupInput(name string) (string, string) {
+	if v := githubactions.GetInput(name); len(v) > 0 {
+		return v, sourceInput
+	}
+	if v := os.Getenv(strings.ToUpper(strings.ReplaceAll(name, "-", "_"))); len(v) > 0 {
+		return v, sourceEnv
+	}
+	for _, source := range []string{sourceOrg, sourceFile} {
+		if v, exist := configLayers[source][name]; exist && len(v) > 0 {
+			return v, source
+		}
+	}
+	return "", ""
+}
+
+// loadConfigLayers loads the file and org layers. It is called before reading any other setting,
+// so that CONFIG_PATH and ORG_DEFAULTS themselves can only come from the env and input layers.
+func loadConfigLayers() error {
+	configLayers = map[string]map[string]string{}
+	layerFeatures = []FeatureFlags{}
+	inputSources = map[string]string{}
+
+	configPath := getInput("config-path")
+	if len(configPath) == 0 {
+		configPath = ".github/docbot.yml"
+	}
+
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if len(workspace) == 0 {
+		workspace = "."
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, configPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %v: %w", configPath, err)
+	}
+	if err == nil {
+		fc, err := parseLayer(data)
+		if err != nil {
+			return fmt.Errorf("parse %v: %w", configPath, err)
+		}
+		configLayers[sourceFile], layerFeatures = fc.Settings, fc.Features
+	}
+
+	if getInput("org-defaults") != "true" {
+		return nil
+	}
+	owner, _, _ := strings.Cut(os.Getenv("GITHUB_REPOSITORY"), "/")
+	if len(owner) == 0 {
+		return fmt.Errorf("GITHUB_REPOSITORY is required for ORG_DEFAULTS")
+	}
+	token, err := githubToken()
+	if err != nil {
+		return err
+	}
+	data, err = fetchOrgDefaults(owner, token, configPath)
+	if err != nil {
+		return fmt.Errorf("fetch org defaults: %w", err)
+	}
+	if data != nil {
+		fc, err := parseLayer(data)
+		if err != nil {
+			return fmt.Errorf("parse org defaults: %w", err)
+		}
+		configLayers[sourceOrg] = fc.Settings
+		layerFeatures = append(fc.Features, layerFeatures...)
+	}
+	return nil
+}
+
+func parseLayer(data []byte) (*FileConfig, error) {
+	fc := &FileConfig{}
+	if err := unmarshalFileConfig(data, fc); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// fetchOrgDefaults downloads the config file at path from the .github repository of owner,
+// or returns nil if there's none.
+func fetchOrgDefaults(owner, token, path string) ([]byte, error) {
+	ctx := context.Background()
+	client := newGitHubClient(ctx, token, nil)
+	rc, resp, err := client.Repositories.DownloadContents(ctx, owner, ".github", path, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// printConfig writes the settings read by NewActionConfig with their source layer, redacting secrets.
+func printConfig(w io.Writer) {
+	names := make([]string, 0, len(inputSources))
+	for name := range inputSources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, source := lookupInput(name)
+		if len(source) == 0 {
+			continue
+		}
+		if isSecretSetting(name) {
+			value = "***"
+		}
+		fmt.Fprintf(w, "%s: %q # %s\n", name, value, source)
+	}
+}
+
+func isSecretSetting(name string) bool {
+	for _, s := range []string{"token", "password", "secret"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
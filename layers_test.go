@@ -0,0 +1,63 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigLayers(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	settings := "settings:\n  label-missing: from-file\n  skip-label: from-file\n  stale-days: '10'\n"
+	if err := os.WriteFile(filepath.Join(workspace, ".github", "docbot.yml"), []byte(settings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("STALE_DAYS", "20")
+	t.Setenv("SKIP_LABEL", "from-env")
+	t.Setenv("INPUT_SKIP-LABEL", "from-input")
+	t.Setenv("INPUT_GITHUB-TOKEN", "ghp_secret")
+
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+	if ac.GetLabelMissing() != "from-file" || ac.GetSkipLabel() != "from-input" || ac.GetStaleDays() != 20 {
+		t.Errorf("label missing = %v, skip label = %v, stale days = %v", ac.GetLabelMissing(), ac.GetSkipLabel(), ac.GetStaleDays())
+	}
+
+	out := &strings.Builder{}
+	printConfig(out)
+	for _, want := range []string{
+		"github-token: \"***\" # input\n",
+		"label-missing: \"from-file\" # file\n",
+		"skip-label: \"from-input\" # input\n",
+		"stale-days: \"20\" # env\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printConfig = %q, want %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// runLint checks that the checkbox labels of the PR template match the watch list,
+// failing with a diff of the labels otherwise.
+func runLint(ac *ActionConfig) error {
+	logger.Infof("@Lint %v\n", ac.GetTemplatePath())
+	data, err := os.ReadFile(ac.GetTemplatePath())
+	if err != nil {
+		return newComplianceError(failureConfig, fmt.Sprintf("read PR template: %v", err))
+	}
+
+	action := &Action{config: ac}
+	diff := templateDiff(action.watchedLabels(), action.templateLabels(string(data)), ac)
+	if len(diff) == 0 {
+		logger.Infoln("PR template is in sync with the watch list")
+		return nil
+	}
+	return newComplianceError(failureConfig, fmt.Sprintf("PR template is out of sync with the watch list:\n--- LABEL_WATCH_LIST\n+++ %v\n%v",
+		ac.GetTemplatePath(), diff))
+}
+
+// templateLabels returns the labels of all checkboxes in template, watched or not.
+func (a *Action) templateLabels(template string) []string {
+	set := make(map[string]struct{})
+	for _, preset := range a.config.labelExtractors {
+		for _, v := range preset.re.FindAllStringSubmatch(template, -1) {
+			_, label := preset.submatches(v)
+			set[strings.Trim(strings.TrimSpace(label), "`")] = struct{}{}
+		}
+	}
+	return a.labelsSetToString(set)
+}
+
+// templateDiff lists the watched labels without checkbox in the template prefixed by "-",
+// and the checkboxes of unwatched labels prefixed by "+". Labels in a namespace needn't be in the template.
+func templateDiff(watched, template []string, ac *ActionConfig) string {
+	inTemplate := make(map[string]struct{})
+	for _, label := range template {
+		inTemplate[label] = struct{}{}
+	}
+
+	lines := []string{}
+	for _, label := range watched {
+		if _, exist := inTemplate[label]; !exist {
+			lines = append(lines, "-"+label)
+		}
+	}
+	for _, label := range template {
+		if !ac.isWatchedLabel(label) {
+			lines = append(lines, "+"+label)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][1:] < lines[j][1:] })
+	return strings.Join(lines, "\n")
+}
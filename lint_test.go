@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "PULL_REQUEST_TEMPLATE.md")
+	t.Setenv("GITHUB_REPOSITORY", "apache/pulsar")
+	t.Setenv("LABEL_WATCH_LIST", "doc,doc-required,doc-not-needed")
+	t.Setenv("LABEL_NAMESPACES", "area/")
+	t.Setenv("TEMPLATE_PATH", path)
+	ac, err := NewActionConfig()
+	if err != nil {
+		t.Fatalf("NewActionConfig: %v", err)
+	}
+
+	template := "- [ ] `doc`\n- [ ] `doc-required`\n- [ ] `doc-not-needed`\n- [ ] `area/broker`\n"
+	if err := os.WriteFile(path, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runLint(ac); err != nil {
+		t.Fatalf("runLint: %v", err)
+	}
+
+	template = "- [ ] `doc`\n- [ ] `doc-not-needed`\n- [ ] `doc-complete`\n"
+	if err := os.WriteFile(path, []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = runLint(ac)
+	if err == nil || failureClass(err) != failureConfig || !strings.HasSuffix(err.Error(), "\n+doc-complete\n-doc-required") {
+		t.Fatalf("runLint: err = %v, want a diff", err)
+	}
+}
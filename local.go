@@ -0,0 +1,96 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// isLocalRun reports whether the bot runs outside of a GitHub-hosted workflow, as under nektos/act,
+// which sets ACT, or straight from a shell, where GITHUB_ACTIONS isn't set.
+func isLocalRun() bool {
+	return os.Getenv("ACT") == "true" || os.Getenv("GITHUB_ACTIONS") != "true"
+}
+
+// prepareLocalRun fills in the GitHub context missing in a local run from the --repo, --event and --event-name flags,
+// the event being a pull_request if there's a payload or a PR number. The context set by the runner, if any, wins.
+func prepareLocalRun(repo string, number int, eventPath, eventName string) error {
+	if len(repo) > 0 && len(strings.Split(repo, "/")) != 2 {
+		return fmt.Errorf("--repo is invalid: %v", repo)
+	}
+	if (len(eventPath) > 0 || number > 0) && len(eventName) == 0 {
+		eventName = "pull_request"
+	}
+	env := map[string]string{
+		"GITHUB_REPOSITORY": repo,
+		"GITHUB_EVENT_PATH": eventPath,
+		"GITHUB_EVENT_NAME": eventName,
+	}
+	for k, v := range env {
+		if len(v) == 0 || len(os.Getenv(k)) > 0 {
+			continue
+		}
+		logger.Infof("%v is not set, using %v\n", k, v)
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disableLocalFeatures turns off the features needing a workflow token of GitHub Actions, which a local run lacks:
+// check runs can only be created by GitHub Apps, so the check-run state backend falls back to comments.
+func (ac *ActionConfig) disableLocalFeatures() {
+	logger.Infoln("Running outside of GitHub Actions, check runs are disabled")
+	disabled := false
+	ac.featureFlags = append(ac.featureFlags, FeatureFlags{Repos: []string{"**"}, CheckRuns: &disabled})
+	if ac.GetStateBackend() == "check-run" {
+		stateBackend := "comment"
+		ac.stateBackend = &stateBackend
+	}
+}
+
+// localPullRequestEvent returns event, or an edited event of PR number if event has no PR,
+// as when running from a shell without payload, or under act with an empty one.
+func localPullRequestEvent(action *Action, event interface{}, number int) (interface{}, error) {
+	if number == 0 {
+		return event, nil
+	}
+	switch e := event.(type) {
+	case nil:
+	case *ghapi.PullRequestEvent:
+		if e.GetPullRequest() != nil {
+			return event, nil
+		}
+	default:
+		return event, nil
+	}
+
+	logger.Infof("@Get PR #%d for the local run\n", number)
+	pr, _, err := action.client.PullRequests.Get(action.globalContext, action.config.GetOwner(), action.config.GetRepo(), number)
+	if err != nil {
+		return nil, fmt.Errorf("get PR: %w", err)
+	}
+	actionType := "edited"
+	return &ghapi.PullRequestEvent{Action: &actionType, Number: &number, PullRequest: pr}, nil
+}
@@ -19,24 +19,39 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
-	"github.com/google/go-github/v45/github"
 	"github.com/sethvargo/go-githubactions"
 	"golang.org/x/oauth2"
 
+	"github.com/maxsxu/action-labeler/pkg/breaker"
+	"github.com/maxsxu/action-labeler/pkg/fixture"
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/hook"
 	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/notify"
+	"github.com/maxsxu/action-labeler/pkg/scm"
 )
 
 const (
-	MessageLabelMissing = `Please provide a correct documentation label for your PR.
-Instructions see [Pulsar Documentation Label Guide](https://docs.google.com/document/d/1Qw7LHQdXWBW9t2-r-A7QdFDBwmZh6ytB4guwMoXHqc0).`
-	MessageLabelMultiple = `Please select only one documentation label for your PR.
-Instructions see [Pulsar Documentation Label Guide](https://docs.google.com/document/d/1Qw7LHQdXWBW9t2-r-A7QdFDBwmZh6ytB4guwMoXHqc0).`
+	MessageLabelMissing           = `Please provide a correct documentation label for your PR.`
+	MessageLabelMultiple          = `Please select only one documentation label for your PR.`
+	MessageLabelMissingOnboarding = `Thanks for your first contribution! :tada:
+
+Every PR here needs a documentation label, so that reviewers know whether docs are affected.
+You don't need permissions to add it: just check one of the boxes in the PR description,
+and the label will be applied automatically once the description is saved:`
+	MessageGuide = `Instructions see [%s](%s).`
 )
 
 type ActionConfig struct {
@@ -46,193 +61,1961 @@ type ActionConfig struct {
 	number *int
 
 	labelPattern        *string
-	labelWatchSet       map[string]struct{}
+	labelPatternPresets []string
+	labelExtractors     []labelPreset
+	labelTolerance      *string
+	// names of the extensionLabelPresets labeling the changed files
+	extensionPresets []string
+	// names of the metadata files, like OWNERS, declaring the component labels of their directory
+	componentFiles []string
+	// features of the deployment config, see ActionConfig.features
+	featureFlags    []FeatureFlags
+	labelWatchSet   map[string]struct{}
+	labelNamespaces []labelNamespace
+	// emptyWatchList is what to do if neither LABEL_WATCH_LIST nor LABEL_NAMESPACES is set
+	emptyWatchList      *string
 	labelMissing        *string
+	labelMissingMap     map[string]string
 	enableLabelMissing  *bool
 	enableLabelMultiple *bool
 
+	mode           *string
+	batchRepos     []string
+	batchNumbers   []int
+	batchWorkers   *int
+	batchRateLimit *float64
+
+	serverAddr    *string
+	webhookSecret *string
+	stateDB       *string
+
+	scmProvider *string
+
+	reviewerMatrix     map[string][]string
+	reviewerAssignment *string
+
+	staleDays         *int
+	staleWarningDays  *int
+	staleAction       *string
+	staleExemptLabels map[string]struct{}
+	staleDryRun       *bool
+
+	enableOnboarding *bool
+
+	notifyMode *string
+
+	enableLabelPicker *bool
+
+	configPath *string
+
+	recordDir *string
+	replayDir *string
+
+	maxMutations *int
+
+	pingPongThreshold *int
+	pingPongWindow    *time.Duration
+
+	forceManage *bool
+	botLogin    *string
+
+	guideURL               *string
+	projectName            *string
+	messageLabelMissing    *string
+	messageLabelMultiple   *string
+	messageLabelOnboarding *string
+
+	commentCooldown *time.Duration
+
+	skipLabel *string
+
+	enableReleaseNote  *bool
+	releaseNoteHeading *string
+
+	// enableUndo records the labels before each run changing them, restored by the undo command
+	enableUndo *bool
+	// enableAuditLog appends the changes of each run to a collapsed comment on the PR
+	enableAuditLog *bool
+
+	titlePrefix *string
+
+	digestLabel *string
+	digestDays  *int
+	digestTitle *string
+
+	reportSince  *string
+	reportUntil  *string
+	reportFormat *string
+	reportOutput *string
+
+	hooks *hook.Hooks
+
+	policyPath *string
+	opaPath    *string
+
+	caBundle *string
+	// httpClient sends the requests of the run, through the transport set up by configureTransport
+	httpClient *http.Client
+	// apiTransport sends the GitHub API requests, as set up by installAPITransport
+	apiTransport http.RoundTripper
+	// httpTimeout limits each API request, if not 0
+	httpTimeout *time.Duration
+	// runTimeout limits the whole run outside of server mode, if not 0
+	runTimeout   *time.Duration
+	maxIdleConns *int
+	// tlsMinVersion is the minimum TLS version of API connections, as a tls.VersionTLS* constant
+	tlsMinVersion *uint16
+
+	logPayload      *string
+	logRedactFields map[string]struct{}
+
+	debugHTTP *bool
+
+	commentChannel *string
+	// commentUnknownLabels tells the author about the checked labels missing from the repository
+	commentUnknownLabels *bool
+	// autocorrectLabels applies the label one edit away from a checked label missing from the repository instead
+	autocorrectLabels *bool
+
+	reviewResolution *string
+
+	botAuthors      map[string]struct{}
+	botAuthorLabels []string
+
+	labelTTLs          map[string]time.Duration
+	labelExpiryMessage *string
+
+	conflictLabel   *string
+	conflictMessage *string
+
+	docsRepo      *string
+	followUpLabel *string
+	pendingLabel  *string
+
+	templatePath *string
+
+	planOnly *bool
+
+	missingOnUnlabeled *string
+	missingGracePeriod *time.Duration
+	// how long labels stay after their checkbox is unchecked, 0 removes them right away
+	removalGracePeriod *time.Duration
+
+	docsTeam          *string
+	docsApprovalLabel *string
+
+	reportWebhookURL      *string
+	reportWebhookAudience *string
+
+	reportUploadURL *string
+
+	// notifiers are sent the results of the stale and digest modes
+	notifiers []notify.Notifier
+
+	// teams, as "org/slug", whose members' PRs are exempt from the missing label check
+	exemptTeams []string
+
+	// assign the PR author while the missing label is applied
+	assignAuthorOnMissing *bool
+
+	// Projects v2 board, as "org/number", whose Status follows the labels of the PR
+	project *string
+	// columns of the project keyed by label, in order of the workflow stages
+	projectColumns     []projectColumn
+	projectStatusField *string
+
+	// globs of the docs files whose landing on the default branch completes the referenced PRs
+	docsPaths     []string
+	completeLabel *string
+
+	// events and their activity types the action processes, all if empty
+	events  map[string]struct{}
+	actions map[string]struct{}
+
+	// where the bookkeeping of PRs is persisted: comment, check-run or db
+	stateBackend *string
+
+	labelSyncDryRun *bool
+
+	labelUsageDays *int
+	labelHues      []labelHue
+
+	// GitHub App authenticating per installation in server mode, if appID is not 0
+	appID         *int64
+	appPrivateKey *string
+
+	// edited events of a PR in server mode are handled once none arrived for debounceWindow, if not 0
+	debounceWindow *time.Duration
+
 	// labels extracted from PR body
 	labels map[string]bool
 }
 
-func NewActionConfig() (*ActionConfig, error) {
-	ownerRepoSlug := os.Getenv("GITHUB_REPOSITORY")
-	ownerRepo := strings.Split(ownerRepoSlug, "/")
-	if len(ownerRepo) != 2 {
-		return nil, fmt.Errorf("GITHUB_REPOSITORY is not found")
+func NewActionConfig() (*ActionConfig, error) {
+	if err := loadConfigLayers(); err != nil {
+		return nil, err
+	}
+
+	mode := getInput("mode")
+
+	scmProvider := getInput("scm-provider")
+	if len(scmProvider) == 0 {
+		scmProvider = "github"
+		if os.Getenv("GITLAB_CI") == "true" {
+			scmProvider = "gitlab"
+		}
+	}
+
+	// Server mode learns the repo from each webhook
+	ownerRepoSlug := os.Getenv("GITHUB_REPOSITORY")
+	ownerRepo := strings.Split(ownerRepoSlug, "/")
+	if len(ownerRepo) != 2 && mode != "server" && scmProvider == "github" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not found, pass --repo owner/repo to run locally")
+	}
+	owner, repo := "", ""
+	if len(ownerRepo) == 2 {
+		owner, repo = ownerRepo[0], ownerRepo[1]
+	}
+
+	token, err := githubToken()
+	if err != nil {
+		return nil, err
+	}
+	if scmProvider == "gitlab" {
+		token = getInput("gitlab-token")
+	}
+
+	labelPattern := getInput("label-pattern")
+
+	labelPatternPresets := []string{}
+	for _, p := range strings.Split(getInput("label-pattern-preset"), ",") {
+		if p = strings.TrimSpace(p); len(p) == 0 {
+			continue
+		}
+		if _, exist := labelPresets[p]; !exist {
+			return nil, fmt.Errorf("LABEL_PATTERN_PRESET is invalid: %v", p)
+		}
+		labelPatternPresets = append(labelPatternPresets, p)
+	}
+	if len(labelPatternPresets) == 0 {
+		labelPatternPresets = []string{"markdown"}
+	}
+
+	extensionPresets := []string{}
+	for _, p := range strings.Split(getInput("extension-presets"), ",") {
+		if p = strings.TrimSpace(p); len(p) == 0 {
+			continue
+		}
+		if _, exist := extensionLabelPresets[p]; !exist {
+			return nil, fmt.Errorf("EXTENSION_PRESETS is invalid: %v", p)
+		}
+		extensionPresets = append(extensionPresets, p)
+	}
+	componentFiles := []string{}
+	for _, p := range strings.Split(getInput("component-files"), ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			componentFiles = append(componentFiles, p)
+		}
+	}
+	labelTolerance := getInput("label-tolerance")
+	if len(labelTolerance) == 0 {
+		labelTolerance = "strict"
+	}
+	if labelTolerance != "strict" && labelTolerance != "lenient" {
+		return nil, fmt.Errorf("LABEL_TOLERANCE is invalid: %v", labelTolerance)
+	}
+	labelExtractors, err := compileLabelPresets(labelPattern, labelPatternPresets, labelTolerance == "lenient")
+	if err != nil {
+		githubactions.Errorf("%s", describeLabelPatternError(labelPattern, err))
+		return nil, fmt.Errorf("LABEL_PATTERN is invalid: %w", err)
+	}
+
+	labelWatchListSlug := getInput("label-watch-list")
+	labelWatchList := strings.Split(strings.TrimSpace(labelWatchListSlug), ",")
+	labelWatchSet := make(map[string]struct{})
+	for _, l := range labelWatchList {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			labelWatchSet[l] = struct{}{}
+		}
+	}
+
+	labelNamespaces, err := parseLabelNamespaces(getInput("label-namespaces"))
+	if err != nil {
+		return nil, fmt.Errorf("LABEL_NAMESPACES is invalid: %w", err)
+	}
+
+	emptyWatchList := getInput("empty-watch-list")
+	if len(emptyWatchList) == 0 {
+		emptyWatchList = "noop"
+	}
+	switch emptyWatchList {
+	case "watch-all-from-pattern", "noop":
+	case "fail":
+		if len(labelWatchSet) == 0 && len(labelNamespaces) == 0 {
+			return nil, fmt.Errorf("LABEL_WATCH_LIST is empty")
+		}
+	default:
+		return nil, fmt.Errorf("EMPTY_WATCH_LIST is invalid: %v", emptyWatchList)
+	}
+
+	enableLabelMissingSlug := getInput("enable-label-missing")
+	enableLabelMissing := true
+	if enableLabelMissingSlug == "false" {
+		enableLabelMissing = false
+	}
+
+	labelMissing, labelMissingMap, err := parseLabelMissing(getInput("label-missing"))
+	if err != nil {
+		return nil, fmt.Errorf("LABEL_MISSING is invalid: %w", err)
+	}
+
+	enableLabelMultipleSlug := getInput("enable-label-multiple")
+	enableLabelMultiple := false
+	if enableLabelMultipleSlug == "true" {
+		enableLabelMultiple = true
+	}
+
+	batchReposSlug := getInput("batch-repos")
+	batchRepos := []string{ownerRepoSlug}
+	if len(strings.TrimSpace(batchReposSlug)) > 0 {
+		batchRepos = batchRepos[:0]
+		for _, r := range strings.Split(batchReposSlug, ",") {
+			if r = strings.TrimSpace(r); len(r) > 0 {
+				batchRepos = append(batchRepos, r)
+			}
+		}
+	}
+
+	batchNumbers := []int{}
+	for _, n := range strings.Split(getInput("batch-numbers"), ",") {
+		if n = strings.TrimPrefix(strings.TrimSpace(n), "#"); len(n) == 0 {
+			continue
+		}
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("BATCH_NUMBERS is invalid: %v", n)
+		}
+		batchNumbers = append(batchNumbers, v)
+	}
+
+	batchWorkers := 4
+	if batchWorkersSlug := getInput("batch-workers"); len(batchWorkersSlug) > 0 {
+		v, err := strconv.Atoi(batchWorkersSlug)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("BATCH_WORKERS is invalid: %v", batchWorkersSlug)
+		}
+		batchWorkers = v
+	}
+
+	batchRateLimit := 10.0
+	if batchRateLimitSlug := getInput("batch-rate-limit"); len(batchRateLimitSlug) > 0 {
+		v, err := strconv.ParseFloat(batchRateLimitSlug, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("BATCH_RATE_LIMIT is invalid: %v", batchRateLimitSlug)
+		}
+		batchRateLimit = v
+	}
+
+	serverAddr := getInput("server-addr")
+	if len(serverAddr) == 0 {
+		serverAddr = ":8080"
+	}
+
+	webhookSecret := getInput("webhook-secret")
+
+	stateDB := getInput("state-db")
+
+	reviewerMatrix := make(map[string][]string)
+	for _, entry := range strings.Split(getInput("reviewer-matrix"), ";") {
+		labelReviewers := strings.SplitN(entry, ":", 2)
+		if len(labelReviewers) != 2 {
+			continue
+		}
+		label := strings.TrimSpace(labelReviewers[0])
+		for _, r := range strings.Split(labelReviewers[1], ",") {
+			if r = strings.TrimSpace(r); len(r) > 0 {
+				reviewerMatrix[label] = append(reviewerMatrix[label], r)
+			}
+		}
+	}
+
+	reviewerAssignment := getInput("reviewer-assignment")
+	if len(reviewerAssignment) == 0 {
+		reviewerAssignment = "round-robin"
+	}
+	if reviewerAssignment != "round-robin" && reviewerAssignment != "least-assigned" {
+		return nil, fmt.Errorf("REVIEWER_ASSIGNMENT is invalid: %v", reviewerAssignment)
+	}
+
+	staleDays := 14
+	if staleDaysSlug := getInput("stale-days"); len(staleDaysSlug) > 0 {
+		v, err := strconv.Atoi(staleDaysSlug)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("STALE_DAYS is invalid: %v", staleDaysSlug)
+		}
+		staleDays = v
+	}
+
+	staleWarningDays := 7
+	if staleWarningDaysSlug := getInput("stale-warning-days"); len(staleWarningDaysSlug) > 0 {
+		v, err := strconv.Atoi(staleWarningDaysSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("STALE_WARNING_DAYS is invalid: %v", staleWarningDaysSlug)
+		}
+		staleWarningDays = v
+	}
+
+	staleAction := getInput("stale-action")
+	if len(staleAction) == 0 {
+		staleAction = "close"
+	}
+	if staleAction != "close" && staleAction != "draft" {
+		return nil, fmt.Errorf("STALE_ACTION is invalid: %v", staleAction)
+	}
+
+	staleExemptLabels := make(map[string]struct{})
+	for _, l := range strings.Split(getInput("stale-exempt-labels"), ",") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			staleExemptLabels[l] = struct{}{}
+		}
+	}
+
+	staleDryRun := getInput("stale-dry-run") == "true"
+
+	enableOnboarding := true
+	if getInput("enable-onboarding") == "false" {
+		enableOnboarding = false
+	}
+
+	notifyMode := getInput("notify-mode")
+	if len(notifyMode) == 0 {
+		notifyMode = "comment"
+	}
+	if notifyMode != "comment" && notifyMode != "reaction" {
+		return nil, fmt.Errorf("NOTIFY_MODE is invalid: %v", notifyMode)
+	}
+
+	enableLabelPicker := getInput("enable-label-picker") == "true"
+
+	configPath := getInput("config-path")
+	if len(configPath) == 0 {
+		configPath = ".github/docbot.yml"
+	}
+
+	recordDir := getInput("record-dir")
+
+	replayDir := getInput("replay-dir")
+
+	maxMutations := 20
+	if maxMutationsSlug := getInput("max-mutations"); len(maxMutationsSlug) > 0 {
+		v, err := strconv.Atoi(maxMutationsSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("MAX_MUTATIONS is invalid: %v", maxMutationsSlug)
+		}
+		maxMutations = v
+	}
+
+	pingPongThreshold := 6
+	if pingPongThresholdSlug := getInput("ping-pong-threshold"); len(pingPongThresholdSlug) > 0 {
+		v, err := strconv.Atoi(pingPongThresholdSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("PING_PONG_THRESHOLD is invalid: %v", pingPongThresholdSlug)
+		}
+		pingPongThreshold = v
+	}
+
+	pingPongWindow := 10 * time.Minute
+	if pingPongWindowSlug := getInput("ping-pong-window"); len(pingPongWindowSlug) > 0 {
+		v, err := time.ParseDuration(pingPongWindowSlug)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("PING_PONG_WINDOW is invalid: %v", pingPongWindowSlug)
+		}
+		pingPongWindow = v
+	}
+
+	forceManage := getInput("force-manage") == "true"
+
+	// resolved from the token if empty, see resolveBotLogin
+	botLogin := getInput("bot-login")
+
+	guideURL := getInput("guide-url")
+	projectName := getInput("project-name")
+
+	messageLabelMissing := getInput("message-label-missing")
+	if len(messageLabelMissing) == 0 {
+		messageLabelMissing = MessageLabelMissing
+	}
+	messageLabelMultiple := getInput("message-label-multiple")
+	if len(messageLabelMultiple) == 0 {
+		messageLabelMultiple = MessageLabelMultiple
+	}
+	messageLabelOnboarding := getInput("message-label-onboarding")
+	if len(messageLabelOnboarding) == 0 {
+		messageLabelOnboarding = MessageLabelMissingOnboarding
+	}
+
+	commentCooldown := 10 * time.Minute
+	if commentCooldownSlug := getInput("comment-cooldown"); len(commentCooldownSlug) > 0 {
+		v, err := time.ParseDuration(commentCooldownSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("COMMENT_COOLDOWN is invalid: %v", commentCooldownSlug)
+		}
+		commentCooldown = v
+	}
+
+	skipLabel := getInput("skip-label")
+
+	enableReleaseNote := getInput("enable-release-note") == "true"
+	enableUndo := getInput("enable-undo") == "true"
+	enableAuditLog := getInput("enable-audit-log") == "true"
+	releaseNoteHeading := getInput("release-note-heading")
+	if len(releaseNoteHeading) == 0 {
+		releaseNoteHeading = "Release note"
+	}
+
+	titlePrefix := getInput("title-prefix")
+	if titlePrefix != "" && titlePrefix != "check" && titlePrefix != "fix" {
+		return nil, fmt.Errorf("TITLE_PREFIX is invalid: %v", titlePrefix)
+	}
+
+	digestLabel := getInput("digest-label")
+	if len(digestLabel) == 0 {
+		digestLabel = "doc-required"
+	}
+	digestDays := 7
+	if digestDaysSlug := getInput("digest-days"); len(digestDaysSlug) > 0 {
+		v, err := strconv.Atoi(digestDaysSlug)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("DIGEST_DAYS is invalid: %v", digestDaysSlug)
+		}
+		digestDays = v
+	}
+	digestTitle := getInput("digest-title")
+	if len(digestTitle) == 0 {
+		digestTitle = "Documentation digest"
+	}
+
+	reportUntil := getInput("report-until")
+	if len(reportUntil) == 0 {
+		reportUntil = time.Now().Format("2006-01-02")
+	}
+	reportUntilTime, err := time.Parse("2006-01-02", reportUntil)
+	if err != nil {
+		return nil, fmt.Errorf("REPORT_UNTIL is invalid: %v", reportUntil)
+	}
+	reportSince := getInput("report-since")
+	if len(reportSince) == 0 {
+		reportSince = reportUntilTime.AddDate(0, 0, -30).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", reportSince); err != nil {
+		return nil, fmt.Errorf("REPORT_SINCE is invalid: %v", reportSince)
+	}
+	reportFormat := getInput("report-format")
+	if len(reportFormat) == 0 {
+		reportFormat = "json"
+	}
+	if reportFormat != "json" && reportFormat != "csv" {
+		return nil, fmt.Errorf("REPORT_FORMAT is invalid: %v", reportFormat)
+	}
+	reportOutput := getInput("report-output")
+
+	hookPlugins := []string{}
+	for _, p := range strings.Split(getInput("hook-plugins"), ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			hookPlugins = append(hookPlugins, p)
+		}
+	}
+	hooks, err := hook.Load(hookPlugins)
+	if err != nil {
+		return nil, fmt.Errorf("HOOK_PLUGINS is invalid: %w", err)
+	}
+
+	policyPath := getInput("policy-path")
+	opaPath := getInput("opa-path")
+	if len(opaPath) == 0 {
+		opaPath = "opa"
+	}
+
+	caBundle := getInput("ca-bundle")
+
+	httpTimeout := 30 * time.Second
+	if httpTimeoutSlug := getInput("http-timeout"); len(httpTimeoutSlug) > 0 {
+		v, err := time.ParseDuration(httpTimeoutSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("HTTP_TIMEOUT is invalid: %v", httpTimeoutSlug)
+		}
+		httpTimeout = v
+	}
+
+	runTimeout := time.Duration(0)
+	if runTimeoutSlug := getInput("run-timeout"); len(runTimeoutSlug) > 0 {
+		v, err := time.ParseDuration(runTimeoutSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("RUN_TIMEOUT is invalid: %v", runTimeoutSlug)
+		}
+		runTimeout = v
+	}
+
+	maxIdleConns := 100
+	if maxIdleConnsSlug := getInput("max-idle-conns"); len(maxIdleConnsSlug) > 0 {
+		v, err := strconv.Atoi(maxIdleConnsSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("MAX_IDLE_CONNS is invalid: %v", maxIdleConnsSlug)
+		}
+		maxIdleConns = v
+	}
+
+	tlsMinVersion := uint16(tls.VersionTLS12)
+	if tlsMinVersionSlug := getInput("tls-min-version"); len(tlsMinVersionSlug) > 0 {
+		v, ok := tlsVersions[tlsMinVersionSlug]
+		if !ok {
+			return nil, fmt.Errorf("TLS_MIN_VERSION is invalid: %v", tlsMinVersionSlug)
+		}
+		tlsMinVersion = v
+	}
+
+	logPayload := getInput("log-payload")
+	if len(logPayload) == 0 {
+		logPayload = "redacted"
+	}
+	if logPayload != "redacted" && logPayload != "full" && logPayload != "none" {
+		return nil, fmt.Errorf("LOG_PAYLOAD is invalid: %v", logPayload)
+	}
+	logRedactFieldsSlug := getInput("log-redact-fields")
+	if len(logRedactFieldsSlug) == 0 {
+		logRedactFieldsSlug = "pull_request.body,issue.body,comment.body"
+	}
+	logRedactFields := make(map[string]struct{})
+	for _, f := range strings.Split(logRedactFieldsSlug, ",") {
+		if f = strings.TrimSpace(f); len(f) > 0 {
+			logRedactFields[f] = struct{}{}
+		}
+	}
+
+	debugHTTP := getInput("debug-http") == "true"
+	commentUnknownLabels := getInput("comment-unknown-labels") == "true"
+	autocorrectLabels := getInput("autocorrect-labels") == "true"
+
+	commentChannel := getInput("comment-channel")
+	if len(commentChannel) == 0 {
+		commentChannel = "comment"
+	}
+	if commentChannel != "comment" && commentChannel != "review" && commentChannel != "description" {
+		return nil, fmt.Errorf("COMMENT_CHANNEL is invalid: %v", commentChannel)
+	}
+
+	reviewResolution := getInput("review-resolution")
+	if len(reviewResolution) == 0 {
+		reviewResolution = "dismiss"
+	}
+	if reviewResolution != "dismiss" && reviewResolution != "approve" {
+		return nil, fmt.Errorf("REVIEW_RESOLUTION is invalid: %v", reviewResolution)
+	}
+
+	botAuthorsSlug := getInput("bot-authors")
+	if len(botAuthorsSlug) == 0 {
+		botAuthorsSlug = "dependabot[bot],renovate[bot]"
+	}
+	botAuthors := make(map[string]struct{})
+	for _, a := range strings.Split(botAuthorsSlug, ",") {
+		if a = strings.TrimSpace(a); len(a) > 0 {
+			botAuthors[a] = struct{}{}
+		}
+	}
+	botAuthorLabels := []string{}
+	for _, l := range strings.Split(getInput("bot-author-labels"), ",") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			botAuthorLabels = append(botAuthorLabels, l)
+		}
+	}
+
+	labelTTLs := make(map[string]time.Duration)
+	for _, entry := range strings.Split(getInput("label-ttls"), ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		label, ttlSlug, found := strings.Cut(entry, "=")
+		ttl, err := parseTTL(strings.TrimSpace(ttlSlug))
+		if !found || err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("LABEL_TTLS is invalid: %v", entry)
+		}
+		labelTTLs[strings.TrimSpace(label)] = ttl
+	}
+	labelExpiryMessage := getInput("label-expiry-message")
+
+	conflictLabel := getInput("conflict-label")
+	conflictMessage := getInput("conflict-message")
+
+	docsRepo := getInput("docs-repo")
+	if len(docsRepo) > 0 && len(strings.Split(docsRepo, "/")) != 2 {
+		return nil, fmt.Errorf("DOCS_REPO is invalid: %v", docsRepo)
+	}
+	followUpLabel := getInput("followup-label")
+	if len(followUpLabel) == 0 {
+		followUpLabel = "doc-required"
+	}
+	pendingLabel := getInput("pending-label")
+	if len(pendingLabel) == 0 {
+		pendingLabel = "doc-pending"
+	}
+
+	templatePath := getInput("template-path")
+	if len(templatePath) == 0 {
+		templatePath = ".github/PULL_REQUEST_TEMPLATE.md"
+	}
+
+	planOnly := getInput("plan-only") == "true"
+
+	missingOnUnlabeled := getInput("missing-on-unlabeled")
+	if len(missingOnUnlabeled) == 0 {
+		missingOnUnlabeled = "readd"
+	}
+	if missingOnUnlabeled != "readd" && missingOnUnlabeled != "grace" && missingOnUnlabeled != "none" {
+		return nil, fmt.Errorf("MISSING_ON_UNLABELED is invalid: %v", missingOnUnlabeled)
+	}
+	missingGracePeriod := time.Minute
+	if missingGracePeriodSlug := getInput("missing-grace-period"); len(missingGracePeriodSlug) > 0 {
+		v, err := time.ParseDuration(missingGracePeriodSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("MISSING_GRACE_PERIOD is invalid: %v", missingGracePeriodSlug)
+		}
+		missingGracePeriod = v
+	}
+	removalGracePeriod := time.Duration(0)
+	if removalGracePeriodSlug := getInput("removal-grace-period"); len(removalGracePeriodSlug) > 0 {
+		v, err := time.ParseDuration(removalGracePeriodSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("REMOVAL_GRACE_PERIOD is invalid: %v", removalGracePeriodSlug)
+		}
+		removalGracePeriod = v
+	}
+
+	docsTeam := getInput("docs-team")
+	if len(docsTeam) > 0 && len(strings.Split(docsTeam, "/")) != 2 {
+		return nil, fmt.Errorf("DOCS_TEAM is invalid: %v", docsTeam)
+	}
+	docsApprovalLabel := getInput("docs-approval-label")
+	if len(docsApprovalLabel) == 0 {
+		docsApprovalLabel = "doc-required"
+	}
+
+	reportWebhookURL := getInput("report-webhook-url")
+	reportWebhookAudience := getInput("report-webhook-audience")
+	if len(reportWebhookAudience) == 0 {
+		reportWebhookAudience = reportWebhookURL
+	}
+
+	reportUploadURL := getInput("report-upload-url")
+	if len(reportUploadURL) > 0 && !strings.HasPrefix(reportUploadURL, "s3://") && !strings.HasPrefix(reportUploadURL, "gs://") {
+		return nil, fmt.Errorf("REPORT_UPLOAD_URL is invalid, expected s3:// or gs://: %v", reportUploadURL)
+	}
+
+	// the transport of the client is set up by configureTransport once the config is read
+	httpClient := &http.Client{}
+
+	notifiers := []notify.Notifier{}
+	emailTo := []string{}
+	for _, r := range strings.Split(getInput("email-to"), ",") {
+		if r = strings.TrimSpace(r); len(r) > 0 {
+			emailTo = append(emailTo, r)
+		}
+	}
+	if len(emailTo) > 0 {
+		smtpServer := getInput("smtp-server")
+		if len(smtpServer) == 0 {
+			return nil, fmt.Errorf("SMTP_SERVER is required to send emails to %v", strings.Join(emailTo, ", "))
+		}
+		email, err := notify.NewEmail(smtpServer, getInput("smtp-username"), getInput("smtp-password"), getInput("email-from"),
+			emailTo, getInput("email-subject"), getInput("email-body"))
+		if err != nil {
+			return nil, fmt.Errorf("EMAIL_SUBJECT or EMAIL_BODY is invalid: %w", err)
+		}
+		notifiers = append(notifiers, email)
+	}
+	for _, entry := range strings.Split(getInput("notify-webhooks"), ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		format, url, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOKS is invalid, expected format=url: %v", entry)
+		}
+		webhook, err := notify.NewWebhook(strings.TrimSpace(format), strings.TrimSpace(url), httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOKS is invalid: %w", err)
+		}
+		notifiers = append(notifiers, webhook)
+	}
+
+	exemptTeams := []string{}
+	for _, t := range strings.Split(getInput("exempt-teams"), ",") {
+		if t = strings.TrimSpace(t); len(t) == 0 {
+			continue
+		}
+		if len(strings.Split(t, "/")) != 2 {
+			return nil, fmt.Errorf("EXEMPT_TEAMS is invalid: %v", t)
+		}
+		exemptTeams = append(exemptTeams, t)
+	}
+
+	assignAuthorOnMissing := getInput("assign-author-on-missing") == "true"
+
+	project := getInput("project")
+	if len(project) > 0 {
+		if _, number, ok := strings.Cut(project, "/"); !ok {
+			return nil, fmt.Errorf("PROJECT is invalid: %v", project)
+		} else if _, err := strconv.Atoi(number); err != nil {
+			return nil, fmt.Errorf("PROJECT is invalid: %v", project)
+		}
+	}
+	projectColumns, err := parseProjectColumns(getInput("project-columns"))
+	if err != nil {
+		return nil, fmt.Errorf("PROJECT_COLUMNS is invalid: %w", err)
+	}
+	projectStatusField := getInput("project-status-field")
+	if len(projectStatusField) == 0 {
+		projectStatusField = "Status"
+	}
+
+	docsPaths := []string{}
+	for _, p := range strings.Split(getInput("docs-paths"), ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			docsPaths = append(docsPaths, p)
+		}
+	}
+	completeLabel := getInput("complete-label")
+	if len(completeLabel) == 0 {
+		completeLabel = "doc-complete"
+	}
+
+	events := make(map[string]struct{})
+	for _, e := range strings.Split(getInput("events"), ",") {
+		if e = strings.TrimSpace(e); len(e) == 0 {
+			continue
+		}
+		if _, handled := handledEvents[e]; !handled {
+			return nil, fmt.Errorf("EVENTS is invalid: %v is not handled", e)
+		}
+		events[e] = struct{}{}
+	}
+	actions := make(map[string]struct{})
+	for _, a := range strings.Split(getInput("actions"), ",") {
+		if a = strings.TrimSpace(a); len(a) > 0 {
+			actions[a] = struct{}{}
+		}
+	}
+
+	stateBackend := getInput("state-backend")
+	if len(stateBackend) == 0 {
+		stateBackend = "comment"
+	}
+	if stateBackend != "comment" && stateBackend != "check-run" && stateBackend != "db" {
+		return nil, fmt.Errorf("STATE_BACKEND is invalid: %v", stateBackend)
+	}
+	if mode == "server" && len(webhookSecret) == 0 {
+		return nil, fmt.Errorf("MODE server needs WEBHOOK_SECRET")
+	}
+	if stateBackend == "db" && (mode != "server" || len(stateDB) == 0) {
+		return nil, fmt.Errorf("STATE_BACKEND db needs MODE server and STATE_DB")
+	}
+
+	labelSyncDryRun := getInput("label-sync-dry-run") == "true"
+
+	labelUsageDays := 30
+	if labelUsageDaysSlug := getInput("label-usage-days"); len(labelUsageDaysSlug) > 0 {
+		v, err := strconv.Atoi(labelUsageDaysSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("LABEL_USAGE_DAYS is invalid: %v", labelUsageDaysSlug)
+		}
+		labelUsageDays = v
+	}
+	labelHues, err := parseLabelHues(getInput("label-hues"))
+	if err != nil {
+		return nil, fmt.Errorf("LABEL_HUES is invalid: %w", err)
+	}
+
+	appID := int64(0)
+	if appIDSlug := getInput("app-id"); len(appIDSlug) > 0 {
+		v, err := strconv.ParseInt(appIDSlug, 10, 64)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("APP_ID is invalid: %v", appIDSlug)
+		}
+		appID = v
+	}
+	appPrivateKey := getInput("app-private-key")
+	if appID > 0 && (mode != "server" || len(appPrivateKey) == 0) {
+		return nil, fmt.Errorf("APP_ID needs MODE server and APP_PRIVATE_KEY")
+	}
+
+	debounceWindow := time.Duration(0)
+	if debounceWindowSlug := getInput("debounce-window"); len(debounceWindowSlug) > 0 {
+		v, err := time.ParseDuration(debounceWindowSlug)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("DEBOUNCE_WINDOW is invalid: %v", debounceWindowSlug)
+		}
+		debounceWindow = v
+	}
+
+	return &ActionConfig{
+		token:                  &token,
+		repo:                   &repo,
+		owner:                  &owner,
+		labelPattern:           &labelPattern,
+		labelPatternPresets:    labelPatternPresets,
+		extensionPresets:       extensionPresets,
+		componentFiles:         componentFiles,
+		labelExtractors:        labelExtractors,
+		labelTolerance:         &labelTolerance,
+		featureFlags:           layerFeatures,
+		labelWatchSet:          labelWatchSet,
+		labelNamespaces:        labelNamespaces,
+		emptyWatchList:         &emptyWatchList,
+		labelMissing:           &labelMissing,
+		labelMissingMap:        labelMissingMap,
+		enableLabelMissing:     &enableLabelMissing,
+		enableLabelMultiple:    &enableLabelMultiple,
+		mode:                   &mode,
+		batchRepos:             batchRepos,
+		batchNumbers:           batchNumbers,
+		batchWorkers:           &batchWorkers,
+		batchRateLimit:         &batchRateLimit,
+		serverAddr:             &serverAddr,
+		webhookSecret:          &webhookSecret,
+		stateDB:                &stateDB,
+		scmProvider:            &scmProvider,
+		reviewerMatrix:         reviewerMatrix,
+		reviewerAssignment:     &reviewerAssignment,
+		staleDays:              &staleDays,
+		staleWarningDays:       &staleWarningDays,
+		staleAction:            &staleAction,
+		staleExemptLabels:      staleExemptLabels,
+		staleDryRun:            &staleDryRun,
+		enableOnboarding:       &enableOnboarding,
+		notifyMode:             &notifyMode,
+		enableLabelPicker:      &enableLabelPicker,
+		configPath:             &configPath,
+		recordDir:              &recordDir,
+		replayDir:              &replayDir,
+		maxMutations:           &maxMutations,
+		pingPongThreshold:      &pingPongThreshold,
+		pingPongWindow:         &pingPongWindow,
+		forceManage:            &forceManage,
+		botLogin:               &botLogin,
+		guideURL:               &guideURL,
+		projectName:            &projectName,
+		messageLabelMissing:    &messageLabelMissing,
+		messageLabelMultiple:   &messageLabelMultiple,
+		messageLabelOnboarding: &messageLabelOnboarding,
+		commentCooldown:        &commentCooldown,
+		skipLabel:              &skipLabel,
+		enableReleaseNote:      &enableReleaseNote,
+		enableUndo:             &enableUndo,
+		enableAuditLog:         &enableAuditLog,
+		releaseNoteHeading:     &releaseNoteHeading,
+		titlePrefix:            &titlePrefix,
+		digestLabel:            &digestLabel,
+		digestDays:             &digestDays,
+		digestTitle:            &digestTitle,
+		reportSince:            &reportSince,
+		reportUntil:            &reportUntil,
+		reportFormat:           &reportFormat,
+		reportOutput:           &reportOutput,
+		hooks:                  hooks,
+		policyPath:             &policyPath,
+		opaPath:                &opaPath,
+		caBundle:               &caBundle,
+		httpClient:             httpClient,
+		httpTimeout:            &httpTimeout,
+		runTimeout:             &runTimeout,
+		maxIdleConns:           &maxIdleConns,
+		tlsMinVersion:          &tlsMinVersion,
+		logPayload:             &logPayload,
+		logRedactFields:        logRedactFields,
+		debugHTTP:              &debugHTTP,
+		commentChannel:         &commentChannel,
+		commentUnknownLabels:   &commentUnknownLabels,
+		autocorrectLabels:      &autocorrectLabels,
+		reviewResolution:       &reviewResolution,
+		botAuthors:             botAuthors,
+		botAuthorLabels:        botAuthorLabels,
+		labelTTLs:              labelTTLs,
+		labelExpiryMessage:     &labelExpiryMessage,
+		conflictLabel:          &conflictLabel,
+		conflictMessage:        &conflictMessage,
+		docsRepo:               &docsRepo,
+		followUpLabel:          &followUpLabel,
+		pendingLabel:           &pendingLabel,
+		templatePath:           &templatePath,
+		planOnly:               &planOnly,
+		missingOnUnlabeled:     &missingOnUnlabeled,
+		missingGracePeriod:     &missingGracePeriod,
+		removalGracePeriod:     &removalGracePeriod,
+		docsTeam:               &docsTeam,
+		docsApprovalLabel:      &docsApprovalLabel,
+		reportWebhookURL:       &reportWebhookURL,
+		reportWebhookAudience:  &reportWebhookAudience,
+		reportUploadURL:        &reportUploadURL,
+		notifiers:              notifiers,
+		exemptTeams:            exemptTeams,
+		assignAuthorOnMissing:  &assignAuthorOnMissing,
+		project:                &project,
+		projectColumns:         projectColumns,
+		projectStatusField:     &projectStatusField,
+		docsPaths:              docsPaths,
+		completeLabel:          &completeLabel,
+		events:                 events,
+		actions:                actions,
+		stateBackend:           &stateBackend,
+		labelSyncDryRun:        &labelSyncDryRun,
+		labelUsageDays:         &labelUsageDays,
+		labelHues:              labelHues,
+		appID:                  &appID,
+		appPrivateKey:          &appPrivateKey,
+		debounceWindow:         &debounceWindow,
+	}, nil
+}
+
+func (ac *ActionConfig) GetToken() string {
+	if ac == nil || ac.token == nil {
+		return ""
+	}
+	return *ac.token
+}
+
+func (ac *ActionConfig) GetOwner() string {
+	if ac == nil || ac.owner == nil {
+		return ""
+	}
+	return *ac.owner
+}
+
+func (ac *ActionConfig) GetRepo() string {
+	if ac == nil || ac.repo == nil {
+		return ""
+	}
+	return *ac.repo
+}
+
+func (ac *ActionConfig) GetNumber() int {
+	if ac == nil || ac.number == nil {
+		return 0
+	}
+	return *ac.number
+}
+
+// withNumber returns a copy of ac bound to PR number, leaving ac untouched for the other PRs of the run.
+func (ac *ActionConfig) withNumber(number int) *ActionConfig {
+	config := *ac
+	config.number = &number
+	config.labels = nil
+	return &config
+}
+
+func (ac *ActionConfig) GetLabelTolerance() string {
+	if ac == nil || ac.labelTolerance == nil {
+		return "strict"
+	}
+	return *ac.labelTolerance
+}
+
+func (ac *ActionConfig) GetLabelPattern() string {
+	if ac == nil || ac.labelPattern == nil {
+		return ""
+	}
+	return *ac.labelPattern
+}
+
+func (ac *ActionConfig) GetLabelMissing() string {
+	if ac == nil || ac.labelMissing == nil {
+		return ""
+	}
+	return *ac.labelMissing
+}
+
+func (ac *ActionConfig) GetEnableLabelMissing() bool {
+	if ac == nil || ac.enableLabelMissing == nil {
+		return false
+	}
+	return *ac.enableLabelMissing
+}
+
+func (ac *ActionConfig) GetEnableLabelMultiple() bool {
+	if ac == nil || ac.enableLabelMultiple == nil {
+		return false
+	}
+	return *ac.enableLabelMultiple
+}
+
+func (ac *ActionConfig) GetMode() string {
+	if ac == nil || ac.mode == nil {
+		return ""
+	}
+	return *ac.mode
+}
+
+func (ac *ActionConfig) GetBatchWorkers() int {
+	if ac == nil || ac.batchWorkers == nil {
+		return 1
+	}
+	return *ac.batchWorkers
+}
+
+func (ac *ActionConfig) GetBatchRateLimit() float64 {
+	if ac == nil || ac.batchRateLimit == nil {
+		return 0
+	}
+	return *ac.batchRateLimit
+}
+
+func (ac *ActionConfig) GetServerAddr() string {
+	if ac == nil || ac.serverAddr == nil {
+		return ""
+	}
+	return *ac.serverAddr
+}
+
+func (ac *ActionConfig) GetWebhookSecret() string {
+	if ac == nil || ac.webhookSecret == nil {
+		return ""
+	}
+	return *ac.webhookSecret
+}
+
+func (ac *ActionConfig) GetStateDB() string {
+	if ac == nil || ac.stateDB == nil {
+		return ""
+	}
+	return *ac.stateDB
+}
+
+func (ac *ActionConfig) GetSCMProvider() string {
+	if ac == nil || ac.scmProvider == nil {
+		return ""
+	}
+	return *ac.scmProvider
+}
+
+func (ac *ActionConfig) GetReviewerAssignment() string {
+	if ac == nil || ac.reviewerAssignment == nil {
+		return ""
+	}
+	return *ac.reviewerAssignment
+}
+
+func (ac *ActionConfig) GetStaleDays() int {
+	if ac == nil || ac.staleDays == nil {
+		return 0
+	}
+	return *ac.staleDays
+}
+
+func (ac *ActionConfig) GetStaleWarningDays() int {
+	if ac == nil || ac.staleWarningDays == nil {
+		return 0
+	}
+	return *ac.staleWarningDays
+}
+
+func (ac *ActionConfig) GetStaleAction() string {
+	if ac == nil || ac.staleAction == nil {
+		return ""
+	}
+	return *ac.staleAction
+}
+
+func (ac *ActionConfig) GetStaleDryRun() bool {
+	if ac == nil || ac.staleDryRun == nil {
+		return false
+	}
+	return *ac.staleDryRun
+}
+
+func (ac *ActionConfig) GetEnableOnboarding() bool {
+	if ac == nil || ac.enableOnboarding == nil {
+		return false
+	}
+	return *ac.enableOnboarding
+}
+
+func (ac *ActionConfig) GetNotifyMode() string {
+	if ac == nil || ac.notifyMode == nil {
+		return ""
+	}
+	return *ac.notifyMode
+}
+
+func (ac *ActionConfig) GetEnableLabelPicker() bool {
+	if ac == nil || ac.enableLabelPicker == nil {
+		return false
+	}
+	return *ac.enableLabelPicker
+}
+
+func (ac *ActionConfig) GetConfigPath() string {
+	if ac == nil || ac.configPath == nil {
+		return ""
+	}
+	return *ac.configPath
+}
+
+func (ac *ActionConfig) GetRecordDir() string {
+	if ac == nil || ac.recordDir == nil {
+		return ""
+	}
+	return *ac.recordDir
+}
+
+func (ac *ActionConfig) GetReplayDir() string {
+	if ac == nil || ac.replayDir == nil {
+		return ""
+	}
+	return *ac.replayDir
+}
+
+func (ac *ActionConfig) GetMaxMutations() int {
+	if ac == nil || ac.maxMutations == nil {
+		return 0
+	}
+	return *ac.maxMutations
+}
+
+func (ac *ActionConfig) GetPingPongThreshold() int {
+	if ac == nil || ac.pingPongThreshold == nil {
+		return 0
+	}
+	return *ac.pingPongThreshold
+}
+
+func (ac *ActionConfig) GetPingPongWindow() time.Duration {
+	if ac == nil || ac.pingPongWindow == nil {
+		return 0
+	}
+	return *ac.pingPongWindow
+}
+
+func (ac *ActionConfig) GetForceManage() bool {
+	if ac == nil || ac.forceManage == nil {
+		return false
+	}
+	return *ac.forceManage
+}
+
+func (ac *ActionConfig) GetBotLogin() string {
+	if ac == nil || ac.botLogin == nil || len(*ac.botLogin) == 0 {
+		return defaultBotLogin
+	}
+	return *ac.botLogin
+}
+
+func (ac *ActionConfig) GetGuideURL() string {
+	if ac == nil || ac.guideURL == nil {
+		return ""
+	}
+	return *ac.guideURL
+}
+
+func (ac *ActionConfig) GetProjectName() string {
+	if ac == nil || ac.projectName == nil {
+		return ""
+	}
+	return *ac.projectName
+}
+
+func (ac *ActionConfig) GetMessageLabelMissing() string {
+	if ac == nil || ac.messageLabelMissing == nil {
+		return MessageLabelMissing
+	}
+	return *ac.messageLabelMissing
+}
+
+func (ac *ActionConfig) GetMessageLabelMultiple() string {
+	if ac == nil || ac.messageLabelMultiple == nil {
+		return MessageLabelMultiple
+	}
+	return *ac.messageLabelMultiple
+}
+
+func (ac *ActionConfig) GetMessageLabelOnboarding() string {
+	if ac == nil || ac.messageLabelOnboarding == nil {
+		return MessageLabelMissingOnboarding
+	}
+	return *ac.messageLabelOnboarding
+}
+
+func (ac *ActionConfig) GetCommentCooldown() time.Duration {
+	if ac == nil || ac.commentCooldown == nil {
+		return 0
+	}
+	return *ac.commentCooldown
+}
+
+func (ac *ActionConfig) GetSkipLabel() string {
+	if ac == nil || ac.skipLabel == nil {
+		return ""
+	}
+	return *ac.skipLabel
+}
+
+func (ac *ActionConfig) GetEmptyWatchList() string {
+	if ac == nil || ac.emptyWatchList == nil {
+		return "noop"
 	}
-	owner, repo := ownerRepo[0], ownerRepo[1]
+	return *ac.emptyWatchList
+}
 
-	token := os.Getenv("GITHUB_TOKEN")
+func (ac *ActionConfig) GetEnableUndo() bool {
+	if ac == nil || ac.enableUndo == nil {
+		return false
+	}
+	return *ac.enableUndo
+}
 
-	labelPattern := os.Getenv("LABEL_PATTERN")
-	if len(labelPattern) == 0 {
-		labelPattern = "- \\[(.*?)\\] ?`(.+?)`"
+func (ac *ActionConfig) GetEnableAuditLog() bool {
+	if ac == nil || ac.enableAuditLog == nil {
+		return false
 	}
+	return *ac.enableAuditLog
+}
 
-	labelWatchListSlug := os.Getenv("LABEL_WATCH_LIST")
-	labelWatchList := strings.Split(strings.TrimSpace(labelWatchListSlug), ",")
-	labelWatchSet := make(map[string]struct{})
-	for _, l := range labelWatchList {
-		labelWatchSet[l] = struct{}{}
+func (ac *ActionConfig) GetEnableReleaseNote() bool {
+	if ac == nil || ac.enableReleaseNote == nil {
+		return false
 	}
+	return *ac.enableReleaseNote
+}
 
-	enableLabelMissingSlug := os.Getenv("ENABLE_LABEL_MISSING")
-	enableLabelMissing := true
-	if enableLabelMissingSlug == "false" {
-		enableLabelMissing = false
+func (ac *ActionConfig) GetReleaseNoteHeading() string {
+	if ac == nil || ac.releaseNoteHeading == nil {
+		return "Release note"
 	}
+	return *ac.releaseNoteHeading
+}
 
-	labelMissing := os.Getenv("LABEL_MISSING")
-	if len(labelMissing) == 0 {
-		labelMissing = "label-missing"
+func (ac *ActionConfig) GetTitlePrefix() string {
+	if ac == nil || ac.titlePrefix == nil {
+		return ""
 	}
+	return *ac.titlePrefix
+}
 
-	enableLabelMultipleSlug := os.Getenv("ENABLE_LABEL_MULTIPLE")
-	enableLabelMultiple := false
-	if enableLabelMultipleSlug == "true" {
-		enableLabelMultiple = true
+func (ac *ActionConfig) GetDigestLabel() string {
+	if ac == nil || ac.digestLabel == nil {
+		return "doc-required"
 	}
+	return *ac.digestLabel
+}
 
-	return &ActionConfig{
-		token:               &token,
-		repo:                &repo,
-		owner:               &owner,
-		labelPattern:        &labelPattern,
-		labelWatchSet:       labelWatchSet,
-		labelMissing:        &labelMissing,
-		enableLabelMissing:  &enableLabelMissing,
-		enableLabelMultiple: &enableLabelMultiple,
-	}, nil
+func (ac *ActionConfig) GetDigestDays() int {
+	if ac == nil || ac.digestDays == nil {
+		return 7
+	}
+	return *ac.digestDays
 }
 
-func (ac *ActionConfig) GetToken() string {
-	if ac == nil || ac.token == nil {
+func (ac *ActionConfig) GetDigestTitle() string {
+	if ac == nil || ac.digestTitle == nil {
+		return "Documentation digest"
+	}
+	return *ac.digestTitle
+}
+
+func (ac *ActionConfig) GetReportSince() string {
+	if ac == nil || ac.reportSince == nil {
 		return ""
 	}
-	return *ac.token
+	return *ac.reportSince
 }
 
-func (ac *ActionConfig) GetOwner() string {
-	if ac == nil || ac.owner == nil {
+func (ac *ActionConfig) GetReportUntil() string {
+	if ac == nil || ac.reportUntil == nil {
 		return ""
 	}
-	return *ac.owner
+	return *ac.reportUntil
 }
 
-func (ac *ActionConfig) GetRepo() string {
-	if ac == nil || ac.repo == nil {
+func (ac *ActionConfig) GetReportFormat() string {
+	if ac == nil || ac.reportFormat == nil {
+		return "json"
+	}
+	return *ac.reportFormat
+}
+
+func (ac *ActionConfig) GetReportOutput() string {
+	if ac == nil || ac.reportOutput == nil {
 		return ""
 	}
-	return *ac.repo
+	return *ac.reportOutput
 }
 
-func (ac *ActionConfig) GetNumber() int {
-	if ac == nil || ac.number == nil {
+func (ac *ActionConfig) GetHooks() *hook.Hooks {
+	if ac == nil {
+		return nil
+	}
+	return ac.hooks
+}
+
+func (ac *ActionConfig) GetPolicyPath() string {
+	if ac == nil || ac.policyPath == nil {
+		return ""
+	}
+	return *ac.policyPath
+}
+
+func (ac *ActionConfig) GetOPAPath() string {
+	if ac == nil || ac.opaPath == nil {
+		return "opa"
+	}
+	return *ac.opaPath
+}
+
+func (ac *ActionConfig) GetCABundle() string {
+	if ac == nil || ac.caBundle == nil {
+		return ""
+	}
+	return *ac.caBundle
+}
+
+func (ac *ActionConfig) GetHTTPTimeout() time.Duration {
+	if ac == nil || ac.httpTimeout == nil {
+		return 30 * time.Second
+	}
+	return *ac.httpTimeout
+}
+
+func (ac *ActionConfig) GetRunTimeout() time.Duration {
+	if ac == nil || ac.runTimeout == nil {
 		return 0
 	}
-	return *ac.number
+	return *ac.runTimeout
 }
 
-func (ac *ActionConfig) GetLabelPattern() string {
-	if ac == nil || ac.labelPattern == nil {
+func (ac *ActionConfig) GetMaxIdleConns() int {
+	if ac == nil || ac.maxIdleConns == nil {
+		return 100
+	}
+	return *ac.maxIdleConns
+}
+
+func (ac *ActionConfig) GetTLSMinVersion() uint16 {
+	if ac == nil || ac.tlsMinVersion == nil {
+		return tls.VersionTLS12
+	}
+	return *ac.tlsMinVersion
+}
+
+func (ac *ActionConfig) GetLogPayload() string {
+	if ac == nil || ac.logPayload == nil {
+		return "redacted"
+	}
+	return *ac.logPayload
+}
+
+func (ac *ActionConfig) GetDebugHTTP() bool {
+	if ac == nil || ac.debugHTTP == nil {
+		return false
+	}
+	return *ac.debugHTTP
+}
+
+func (ac *ActionConfig) GetCommentUnknownLabels() bool {
+	if ac == nil || ac.commentUnknownLabels == nil {
+		return false
+	}
+	return *ac.commentUnknownLabels
+}
+
+func (ac *ActionConfig) GetAutocorrectLabels() bool {
+	if ac == nil || ac.autocorrectLabels == nil {
+		return false
+	}
+	return *ac.autocorrectLabels
+}
+
+func (ac *ActionConfig) GetCommentChannel() string {
+	if ac == nil || ac.commentChannel == nil {
+		return "comment"
+	}
+	return *ac.commentChannel
+}
+
+func (ac *ActionConfig) GetReviewResolution() string {
+	if ac == nil || ac.reviewResolution == nil {
+		return "dismiss"
+	}
+	return *ac.reviewResolution
+}
+
+func (ac *ActionConfig) GetLabelExpiryMessage() string {
+	if ac == nil || ac.labelExpiryMessage == nil {
 		return ""
 	}
-	return *ac.labelPattern
+	return *ac.labelExpiryMessage
 }
 
-func (ac *ActionConfig) GetLabelMissing() string {
-	if ac == nil || ac.labelMissing == nil {
+func (ac *ActionConfig) GetConflictLabel() string {
+	if ac == nil || ac.conflictLabel == nil {
 		return ""
 	}
-	return *ac.labelMissing
+	return *ac.conflictLabel
 }
 
-func (ac *ActionConfig) GetEnableLabelMissing() bool {
-	if ac == nil || ac.enableLabelMissing == nil {
+func (ac *ActionConfig) GetConflictMessage() string {
+	if ac == nil || ac.conflictMessage == nil {
+		return ""
+	}
+	return *ac.conflictMessage
+}
+
+func (ac *ActionConfig) GetDocsRepo() string {
+	if ac == nil || ac.docsRepo == nil {
+		return ""
+	}
+	return *ac.docsRepo
+}
+
+func (ac *ActionConfig) GetFollowUpLabel() string {
+	if ac == nil || ac.followUpLabel == nil {
+		return "doc-required"
+	}
+	return *ac.followUpLabel
+}
+
+func (ac *ActionConfig) GetPendingLabel() string {
+	if ac == nil || ac.pendingLabel == nil {
+		return "doc-pending"
+	}
+	return *ac.pendingLabel
+}
+
+func (ac *ActionConfig) GetTemplatePath() string {
+	if ac == nil || ac.templatePath == nil {
+		return ".github/PULL_REQUEST_TEMPLATE.md"
+	}
+	return *ac.templatePath
+}
+
+func (ac *ActionConfig) GetPlanOnly() bool {
+	if ac == nil || ac.planOnly == nil {
 		return false
 	}
-	return *ac.enableLabelMissing
+	return *ac.planOnly
 }
 
-func (ac *ActionConfig) GetEnableLabelMultiple() bool {
-	if ac == nil || ac.enableLabelMultiple == nil {
+func (ac *ActionConfig) GetMissingOnUnlabeled() string {
+	if ac == nil || ac.missingOnUnlabeled == nil {
+		return "readd"
+	}
+	return *ac.missingOnUnlabeled
+}
+
+func (ac *ActionConfig) GetMissingGracePeriod() time.Duration {
+	if ac == nil || ac.missingGracePeriod == nil {
+		return time.Minute
+	}
+	return *ac.missingGracePeriod
+}
+
+func (ac *ActionConfig) GetRemovalGracePeriod() time.Duration {
+	if ac == nil || ac.removalGracePeriod == nil {
+		return 0
+	}
+	return *ac.removalGracePeriod
+}
+
+func (ac *ActionConfig) GetDocsTeam() string {
+	if ac == nil || ac.docsTeam == nil {
+		return ""
+	}
+	return *ac.docsTeam
+}
+
+func (ac *ActionConfig) GetDocsApprovalLabel() string {
+	if ac == nil || ac.docsApprovalLabel == nil {
+		return "doc-required"
+	}
+	return *ac.docsApprovalLabel
+}
+
+func (ac *ActionConfig) GetReportWebhookURL() string {
+	if ac == nil || ac.reportWebhookURL == nil {
+		return ""
+	}
+	return *ac.reportWebhookURL
+}
+
+func (ac *ActionConfig) GetReportWebhookAudience() string {
+	if ac == nil || ac.reportWebhookAudience == nil {
+		return ""
+	}
+	return *ac.reportWebhookAudience
+}
+
+func (ac *ActionConfig) GetReportUploadURL() string {
+	if ac == nil || ac.reportUploadURL == nil {
+		return ""
+	}
+	return *ac.reportUploadURL
+}
+
+func (ac *ActionConfig) GetAssignAuthorOnMissing() bool {
+	if ac == nil || ac.assignAuthorOnMissing == nil {
 		return false
 	}
-	return *ac.enableLabelMultiple
+	return *ac.assignAuthorOnMissing
+}
+
+func (ac *ActionConfig) GetProject() string {
+	if ac == nil || ac.project == nil {
+		return ""
+	}
+	return *ac.project
+}
+
+func (ac *ActionConfig) GetProjectStatusField() string {
+	if ac == nil || ac.projectStatusField == nil {
+		return ""
+	}
+	return *ac.projectStatusField
+}
+
+func (ac *ActionConfig) GetCompleteLabel() string {
+	if ac == nil || ac.completeLabel == nil {
+		return "doc-complete"
+	}
+	return *ac.completeLabel
+}
+
+func (ac *ActionConfig) GetStateBackend() string {
+	if ac == nil || ac.stateBackend == nil {
+		return "comment"
+	}
+	return *ac.stateBackend
+}
+
+func (ac *ActionConfig) GetLabelSyncDryRun() bool {
+	if ac == nil || ac.labelSyncDryRun == nil {
+		return false
+	}
+	return *ac.labelSyncDryRun
+}
+
+func (ac *ActionConfig) GetLabelUsageDays() int {
+	if ac == nil || ac.labelUsageDays == nil {
+		return 30
+	}
+	return *ac.labelUsageDays
+}
+
+func (ac *ActionConfig) GetAppID() int64 {
+	if ac == nil || ac.appID == nil {
+		return 0
+	}
+	return *ac.appID
+}
+
+func (ac *ActionConfig) GetAppPrivateKey() string {
+	if ac == nil || ac.appPrivateKey == nil {
+		return ""
+	}
+	return *ac.appPrivateKey
+}
+
+func (ac *ActionConfig) GetDebounceWindow() time.Duration {
+	if ac == nil || ac.debounceWindow == nil {
+		return 0
+	}
+	return *ac.debounceWindow
 }
 
 type Action struct {
 	config *ActionConfig
 
 	globalContext context.Context
-	client        *github.Client
+	client        *ghapi.Client
+	provider      scm.Provider
 
-	// opened, edited, labeled, unlabeled
+	// opened, edited, synchronize, labeled, unlabeled
 	event string
+
+	// loaded on first use by getFileConfig
+	fileConfig *FileConfig
+
+	// labels deduced by rules rather than the PR body
+	ruleLabels map[string]bool
+	// failure messages of the matched expression rules and policy
+	ruleFailures []string
+
+	// fetched once per run by listTimeline
+	timeline []*ghapi.Timeline
+
+	// resources fetched during the run, shared with the provider
+	cache *runCache
+
+	// plan records the decision of the run, if not nil
+	plan *Plan
+
+	// set on first use by getState
+	state State
+
+	// non-fatal problems of the run, reported at its end
+	warnings []error
 }
 
-func NewAction(ac *ActionConfig) *Action {
-	ctx := context.Background()
+// NewAction creates an Action, sending GitHub API requests through base if not nil.
+func NewAction(ctx context.Context, ac *ActionConfig, base http.RoundTripper) *Action {
+	ctx = breaker.WithBreaker(ctx, breaker.New(ac.GetMaxMutations()))
+	client := newGitHubClient(ctx, ac.GetToken(), base)
+	provider := newCachedProvider(&versionProvider{Provider: newFeatureProvider(scm.NewGitHub(client, ac.GetOwner(), ac.GetRepo()), ac)})
+
+	return &Action{
+		config:        ac,
+		globalContext: ctx,
+		client:        client,
+		provider:      provider,
+		cache:         provider.cache,
+	}
+}
+
+// newPullRequestAction creates an Action bound to a single PR, sharing client with other actions.
+func newPullRequestAction(ctx context.Context, ac *ActionConfig, client *ghapi.Client, owner, repo string, pr *ghapi.PullRequest) *Action {
+	config := ac.withNumber(pr.GetNumber())
+	config.owner = &owner
+	config.repo = &repo
+	provider := newCachedProvider(&versionProvider{Provider: newFeatureProvider(scm.NewGitHub(client, owner, repo), config)})
+
+	action := &Action{
+		config:        config,
+		globalContext: breaker.WithBreaker(ctx, breaker.New(ac.GetMaxMutations())),
+		client:        client,
+		provider:      provider,
+		cache:         provider.cache,
+	}
+	config.labels = action.extractLabels(pr.GetBody())
+
+	return action
+}
+
+// newGitHubClient creates an authenticated client, sending requests through base if not nil.
+// Mutating requests are capped by the breaker of the request context.
+func newGitHubClient(ctx context.Context, token string, base http.RoundTripper) *ghapi.Client {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: breaker.Transport(base)})
+
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: ac.GetToken()},
+		&oauth2.Token{AccessToken: token},
 	)
 
 	tc := oauth2.NewClient(ctx, ts)
 
-	return &Action{
-		config:        ac,
-		globalContext: ctx,
-		client:        github.NewClient(tc),
+	return ghapi.NewClient(tc)
+}
+
+func (a *Action) Run(actionType string) error {
+	a.event = actionType
+	defer a.reportWarnings()
+	if a.config.GetEnableAuditLog() {
+		defer a.auditChanges(actionType)()
+	}
+
+	if a.config.watchesNothing() && a.config.GetEmptyWatchList() == "noop" {
+		logger.Infoln("LABEL_WATCH_LIST is empty, nothing to do")
+		return nil
+	}
+
+	switch actionType {
+	case "opened", "edited", "synchronize", "labeled", "unlabeled", "closed":
+		if accessible, err := a.checkAccessible(); err != nil || !accessible {
+			return err
+		}
+	}
+
+	switch actionType {
+	case "opened", "edited", "synchronize", "labeled", "unlabeled":
+		if skipped, err := a.isSkipped(); err != nil || skipped {
+			return err
+		}
+		if paused, err := a.checkPingPong(); err != nil || paused {
+			return err
+		}
+		if automated, err := a.handleAutomatedPR(); err != nil || automated {
+			return err
+		}
+		if err := a.resumeLabelChanges(); err != nil {
+			return err
+		}
+		if a.config.GetEnableUndo() {
+			defer a.snapshotLabels()()
+		}
+	}
+
+	var err error
+	switch actionType {
+	case "opened", "edited", "synchronize":
+		if err := a.applyContentRules(); err != nil {
+			return fmt.Errorf("apply content rules: %w", err)
+		}
+		if err := a.applyCommitTrailers(); err != nil {
+			return fmt.Errorf("apply commit trailers: %w", err)
+		}
+		if err := a.checkConflicts(); err != nil {
+			return fmt.Errorf("check conflicts: %w", err)
+		}
+		if err := a.applyExpressionRules(); err != nil {
+			return fmt.Errorf("apply expression rules: %w", err)
+		}
+		if err := a.applyPolicy(); err != nil {
+			return fmt.Errorf("apply policy: %w", err)
+		}
+		if a.config.GetEnableReleaseNote() {
+			if err := a.checkReleaseNote(); err != nil {
+				return fmt.Errorf("check release note: %w", err)
+			}
+		}
+		err = a.onPullRequestOpenedOrEdited()
+	case "labeled", "unlabeled":
+		err = a.onPullRequestLabeledOrUnlabeled()
+	case "closed":
+		return a.onPullRequestClosed()
+	default:
+		logger.Infof("Action %v is not handled, skip it\n", actionType)
+		return nil
+	}
+
+	if err == nil {
+		if err := a.checkDocsApproval(); err != nil {
+			return fmt.Errorf("check docs approval: %w", err)
+		}
+	}
+
+	if err == nil {
+		if err := a.syncProjectStatus(); err != nil {
+			a.warn("Sync project status", err)
+		}
+	}
+
+	if err == nil && len(a.config.GetTitlePrefix()) > 0 {
+		err = a.checkTitlePrefix()
+	}
+
+	if err == nil && len(a.ruleFailures) > 0 {
+		err = a.failRuleCheck()
+	}
+
+	if err == nil {
+		if err := a.resolveGuidance(); err != nil {
+			a.warn("Resolve guidance", err)
+		}
+	}
+
+	// Supersede the failed check run of a previous reminder
+	if err == nil && (a.config.GetNotifyMode() == "reaction" || a.config.GetTitlePrefix() == "check") {
+		if err := a.passCheckRun(); err != nil {
+			a.warn("Pass check run", err)
+		}
+	}
+	return err
+}
+
+// failRuleCheck reports the failures of the matched expression rules and the policy in a failed check run.
+func (a *Action) failRuleCheck() error {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	message := strings.Join(a.ruleFailures, "\n")
+	if err := a.createCheckRun(pr.HeadSHA, "failure", "PR violates the repository rules", message); err != nil {
+		return err
 	}
+	return newComplianceError(failureNonCompliant, message)
 }
 
-func (a *Action) Run(actionType string) error {
-	a.event = actionType
-	switch actionType {
-	case "opened", "edited":
-		return a.onPullRequestOpenedOrEdited()
-	case "labeled", "unlabeled":
-		return a.onPullRequestLabeledOrUnlabeled()
+// applyContentRules adds the labels deduced by content rules, file extensions and component files to the expected labels.
+func (a *Action) applyContentRules() error {
+	if a.client == nil || !a.config.features().pathRules {
+		return nil
+	}
+	labels, err := a.contentRuleLabels()
+	if err != nil {
+		return err
+	}
+	extensionLabels, err := a.extensionLabels()
+	if err != nil {
+		return err
+	}
+	mergeLabels(labels, extensionLabels)
+	componentLabels, err := a.componentLabels()
+	if err != nil {
+		return err
+	}
+	mergeLabels(labels, componentLabels)
+	a.ruleLabels = labels
+	if a.config.labels == nil {
+		a.config.labels = make(map[string]bool)
 	}
+	mergeLabels(a.config.labels, labels)
 	return nil
 }
 
+// checkRuleLabels ticks the checkboxes in the PR body of the labels deduced by rules,
+// so that the body stays in sync with the applied labels.
+func (a *Action) checkRuleLabels(pr *scm.PullRequest) error {
+	bodyLabels := a.extractLabels(pr.Body)
+	changeList := make(map[string]bool)
+	for label, checked := range a.ruleLabels {
+		if checked && !bodyLabels[label] {
+			changeList[label] = true
+		}
+	}
+	if len(changeList) == 0 {
+		return nil
+	}
+
+	logger.Infoln("@Update PR body")
+	logger.Infof("ChangeList: %v\n", changeList)
+	return a.editCheckboxes(pr, changeList)
+}
+
 func (a *Action) onPullRequestOpenedOrEdited() error {
-	pr, _, err := a.client.PullRequests.Get(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber())
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
 	if err != nil {
-		return fmt.Errorf("get PR: %v", err)
+		return fmt.Errorf("get PR: %w", err)
+	}
+
+	if a.config.labels == nil {
+		a.config.labels = make(map[string]bool)
+	}
+	if err := a.config.GetHooks().PrePlan(pr, a.config.labels); err != nil {
+		return fmt.Errorf("pre-plan hook: %w", err)
 	}
 
 	// Get repo labels
 	logger.Infoln("@List repo labels")
-	repoLabels, err := a.getRepoLabels()
+	repoLabels, err := a.provider.ListRepoLabels(a.globalContext)
 	if err != nil {
-		return fmt.Errorf("list repo labels: %v", err)
+		return fmt.Errorf("list repo labels: %w", err)
 	}
-	logger.Infof("Repo labels: %v\n", a.labelsToString(repoLabels))
+	logger.Infof("Repo labels: %v\n", repoLabels)
 
 	repoLabelsSet := make(map[string]struct{})
 	for _, label := range repoLabels {
-		repoLabelsSet[label.GetName()] = struct{}{}
+		repoLabelsSet[label] = struct{}{}
 	}
 
 	// Get current labels on this PR
 	logger.Infoln("@List issue labels")
-	issueLabels, err := a.getIssueLabels()
+	issueLabels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
 	if err != nil {
-		return fmt.Errorf("list current issue labels: %v", err)
+		return fmt.Errorf("list current issue labels: %w", err)
 	}
-	logger.Infof("Issue labels: %v\n", a.labelsToString(issueLabels))
+	logger.Infof("Issue labels: %v\n", issueLabels)
 
-	// Get the intersection of issueLabels and labelWatchSet, including labelMissing
+	// Get the intersection of issueLabels and labelWatchSet, including missing labels
 	logger.Infoln("@List current labels")
 	currentLabelsSet := make(map[string]struct{})
 	for _, label := range issueLabels {
-		if _, exist := a.config.labelWatchSet[label.GetName()]; !exist && label.GetName() != a.config.GetLabelMissing() {
+		if !a.config.isWatchedLabel(label) && !a.config.isMissingLabel(label) {
 			continue
 		}
-		currentLabelsSet[label.GetName()] = struct{}{}
+		currentLabelsSet[label] = struct{}{}
 	}
 	logger.Infof("Current labels: %v\n", a.labelsSetToString(currentLabelsSet))
 
@@ -247,20 +2030,25 @@ func (a *Action) onPullRequestOpenedOrEdited() error {
 		}
 		expectedLabelsMap[label] = checked
 	}
+	unknownLabels := a.findUnknownLabels(pr, repoLabels)
+	if a.config.GetAutocorrectLabels() {
+		autocorrectLabels(unknownLabels, repoLabelsSet, expectedLabelsMap)
+	}
 	logger.Infof("Expected labels: %v\n", expectedLabelsMap)
+	a.reportUnknownLabels(pr, unknownLabels)
 
 	// Remove labels
 	logger.Infoln("@Remove labels")
 	labelsToRemove := make(map[string]struct{})
 	if len(expectedLabelsMap) == 0 { // Remove current labels when PR body is empty
-		for l := range a.config.labelWatchSet {
-			if _, exist := currentLabelsSet[l]; exist {
+		for l := range currentLabelsSet {
+			if !a.config.isMissingLabel(l) {
 				labelsToRemove[l] = struct{}{}
 			}
 		}
 	} else {
 		for label := range currentLabelsSet {
-			if label == a.config.GetLabelMissing() {
+			if a.config.isMissingLabel(label) {
 				continue
 			}
 			if checked, exist := expectedLabelsMap[label]; exist && checked {
@@ -270,41 +2058,42 @@ func (a *Action) onPullRequestOpenedOrEdited() error {
 		}
 	}
 
+	// Keep labels deliberately applied by humans
+	if err := a.keepHumanLabels(labelsToRemove); err != nil {
+		return fmt.Errorf("attribute labels: %w", err)
+	}
+	// Keep labels unchecked within the removal grace period
+	if err := a.deferRemovals(labelsToRemove); err != nil {
+		return fmt.Errorf("defer removals: %w", err)
+	}
+
 	// Remove missing label
-	checkedCount := 0
-	for _, checked := range expectedLabelsMap {
+	checkedLabels := []string{}
+	for label, checked := range expectedLabelsMap {
 		if checked {
-			checkedCount++
+			checkedLabels = append(checkedLabels, label)
 		}
 	}
+	lacking, multiple := a.checkCardinality(checkedLabels)
 
-	if !a.config.GetEnableLabelMultiple() && checkedCount > 1 {
+	if len(multiple) > 0 {
 		logger.Infoln("Multiple labels detected")
-		_, _, err = a.client.Issues.CreateComment(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			&github.IssueComment{
-				Body: func(v string) *string { return &v }(fmt.Sprintf("@%s %s", pr.User.GetLogin(), MessageLabelMultiple))})
-		if err != nil {
-			return fmt.Errorf("create issue comment: %v", err)
-		}
-		return fmt.Errorf("%s", MessageLabelMultiple)
+		return a.enforceLabelCheck(pr, failureLabelMultiple, "eyes", checkedLabels, nil, multiple)
 	}
 
-	if _, exist := currentLabelsSet[a.config.GetLabelMissing()]; exist && checkedCount > 0 {
-		labelsToRemove[a.config.GetLabelMissing()] = struct{}{}
-	}
-
-	logger.Infof("Labels to remove: %v\n", a.labelsSetToString(labelsToRemove))
-
-	for label := range labelsToRemove {
-		_, err := a.client.Issues.RemoveLabelForIssue(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), label)
+	missingLabels := a.config.missingLabelsOf(lacking)
+	if len(missingLabels) > 0 {
+		exempt, err := a.isExemptAuthor(pr)
 		if err != nil {
-			return fmt.Errorf("remove label %v: %v", label, err)
+			return fmt.Errorf("check exempt teams: %w", err)
+		}
+		if exempt {
+			missingLabels = nil
 		}
 	}
+	a.removeSatisfiedMissingLabels(currentLabelsSet, missingLabels, labelsToRemove)
 
-	// Add labels
-	logger.Infoln("@Add labels")
+	logger.Infof("Labels to remove: %v\n", a.labelsSetToString(labelsToRemove))
 
 	labelsToAdd := []string{}
 	for label, checked := range expectedLabelsMap {
@@ -316,76 +2105,94 @@ func (a *Action) onPullRequestOpenedOrEdited() error {
 		}
 	}
 
-	if len(labelsToAdd) == 0 {
+	plan := &hook.Plan{LabelsToAdd: labelsToAdd, LabelsToRemove: []string{}}
+	for label := range labelsToRemove {
+		plan.LabelsToRemove = append(plan.LabelsToRemove, label)
+	}
+	sort.Strings(plan.LabelsToRemove)
+	if err := a.config.GetHooks().PostPlan(pr, plan); err != nil {
+		return fmt.Errorf("post-plan hook: %w", err)
+	}
+
+	// Add labels
+	logger.Infoln("@Add labels")
+
+	if len(plan.LabelsToAdd) == 0 {
 		logger.Infoln("No labels to add.")
 	} else {
-		logger.Infof("Labels to add: %v\n", labelsToAdd)
+		logger.Infof("Labels to add: %v\n", plan.LabelsToAdd)
+	}
+	if err := a.editLabels(plan.LabelsToAdd, plan.LabelsToRemove); err != nil {
+		return fmt.Errorf("edit labels: %w", err)
+	}
+	a.observeLabelLatency(pr, currentLabelsSet, plan.LabelsToAdd)
 
-		_, _, err = a.client.Issues.AddLabelsToIssue(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), labelsToAdd)
-		if err != nil {
-			logger.Infof("Add labels %v: %v\n", labelsToAdd, err)
-		}
+	if a.config.GetEnableLabelMissing() {
+		a.assignMissingAuthor(pr, currentLabelsSet, missingLabels)
 	}
 
 	// Add missing label
-	if a.config.GetEnableLabelMissing() && checkedCount == 0 {
+	if a.config.GetEnableLabelMissing() && len(missingLabels) > 0 {
 		logger.Infoln("@Add missing label")
-		_, _, err = a.client.Issues.AddLabelsToIssue(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			[]string{a.config.GetLabelMissing()})
-		if err != nil {
-			return fmt.Errorf("add missing label %v: %v", a.config.GetLabelMissing(), err)
+		if err := a.provider.AddLabels(a.globalContext, a.config.GetNumber(), missingLabels); err != nil {
+			a.warn(fmt.Sprintf("Add missing label %v", missingLabels), err)
 		}
 
-		_, _, err = a.client.Issues.CreateComment(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			&github.IssueComment{
-				Body: func(v string) *string { return &v }(fmt.Sprintf("@%s %s", pr.User.GetLogin(), MessageLabelMissing))})
-		if err != nil {
-			logger.Infof("Create issue comment: %v\n", err)
-		}
+		return a.enforceLabelCheck(pr, failureLabelMissing, "confused", checkedLabels, lacking, nil)
+	}
 
-		return fmt.Errorf("%s", MessageLabelMissing)
+	if err := a.checkRuleLabels(pr); err != nil {
+		return fmt.Errorf("check rule labels: %w", err)
+	}
+
+	checkedLabelsSet := make(map[string]struct{})
+	for label, checked := range expectedLabelsMap {
+		if checked {
+			checkedLabelsSet[label] = struct{}{}
+		}
+	}
+	if err := a.assignReviewers(pr, checkedLabelsSet); err != nil {
+		return fmt.Errorf("assign reviewers: %w", err)
 	}
 
 	return nil
 }
 
 func (a *Action) onPullRequestLabeledOrUnlabeled() error {
-	pr, _, err := a.client.PullRequests.Get(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber())
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
 	if err != nil {
-		return fmt.Errorf("get PR: %v", err)
+		return fmt.Errorf("get PR: %w", err)
 	}
 
 	// Get repo labels
 	logger.Infoln("@List repo labels")
-	repoLabels, err := a.getRepoLabels()
+	repoLabels, err := a.provider.ListRepoLabels(a.globalContext)
 	if err != nil {
-		return fmt.Errorf("list repo labels: %v", err)
+		return fmt.Errorf("list repo labels: %w", err)
 	}
-	logger.Infof("Repo labels: %v\n", a.labelsToString(repoLabels))
+	logger.Infof("Repo labels: %v\n", repoLabels)
 
 	repoLabelsSet := make(map[string]struct{})
 	for _, label := range repoLabels {
-		repoLabelsSet[label.GetName()] = struct{}{}
+		repoLabelsSet[label] = struct{}{}
 	}
 
 	// Get current labels on this PR
 	logger.Infoln("@List issue labels")
-	issueLabels, err := a.getIssueLabels()
+	issueLabels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
 	if err != nil {
-		return fmt.Errorf("list current issue labels: %v", err)
+		return fmt.Errorf("list current issue labels: %w", err)
 	}
-	logger.Infof("Issue labels: %v\n", a.labelsToString(issueLabels))
+	logger.Infof("Issue labels: %v\n", issueLabels)
 
-	// Get the intersection of issueLabels and labelWatchSet, including labelMissing
+	// Get the intersection of issueLabels and labelWatchSet, including missing labels
 	logger.Infoln("@List current labels")
 	currentLabelsSet := make(map[string]struct{})
 	for _, label := range issueLabels {
-		if _, exist := a.config.labelWatchSet[label.GetName()]; !exist && label.GetName() != a.config.GetLabelMissing() {
+		if !a.config.isWatchedLabel(label) && !a.config.isMissingLabel(label) {
 			continue
 		}
-		currentLabelsSet[label.GetName()] = struct{}{}
+		currentLabelsSet[label] = struct{}{}
 	}
 	logger.Infof("Current labels: %v\n", a.labelsSetToString(currentLabelsSet))
 
@@ -404,57 +2211,56 @@ func (a *Action) onPullRequestLabeledOrUnlabeled() error {
 
 	// Remove missing label
 	labelsToRemove := make(map[string]struct{})
-	checkedCount := 0
-	for label := range currentLabelsSet {
-		if label != a.config.GetLabelMissing() {
-			checkedCount++
-		}
-	}
+	currentLabels := a.labelsSetToString(currentLabelsSet)
+	lacking, multiple := a.checkCardinality(currentLabels)
 
-	if !a.config.GetEnableLabelMultiple() && checkedCount > 1 {
+	if len(multiple) > 0 {
 		logger.Infoln("Multiple labels detected")
-		_, _, err = a.client.Issues.CreateComment(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			&github.IssueComment{
-				Body: func(v string) *string { return &v }(fmt.Sprintf("@%s %s", pr.User.GetLogin(), MessageLabelMultiple))})
-		if err != nil {
-			return fmt.Errorf("create issue comment: %v", err)
-		}
-		return fmt.Errorf("%s", MessageLabelMultiple)
+		return a.enforceLabelCheck(pr, failureLabelMultiple, "eyes", nil, nil, multiple)
 	}
 
-	if _, exist := currentLabelsSet[a.config.GetLabelMissing()]; exist && checkedCount > 0 {
-		labelsToRemove[a.config.GetLabelMissing()] = struct{}{}
+	missingLabels := a.config.missingLabelsOf(lacking)
+	if len(missingLabels) > 0 {
+		exempt, err := a.isExemptAuthor(pr)
+		if err != nil {
+			return fmt.Errorf("check exempt teams: %w", err)
+		}
+		if exempt {
+			missingLabels = nil
+		}
 	}
+	a.removeSatisfiedMissingLabels(currentLabelsSet, missingLabels, labelsToRemove)
 
 	logger.Infof("Labels to remove: %v\n", labelsToRemove)
 
 	for label := range labelsToRemove {
-		_, err := a.client.Issues.RemoveLabelForIssue(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), label)
-		if err != nil {
-			return fmt.Errorf("remove label %v: %v", label, err)
+		if err := a.provider.RemoveLabel(a.globalContext, a.config.GetNumber(), label); err != nil {
+			a.warn(fmt.Sprintf("Remove label %v", label), err)
+		}
+	}
+
+	if a.config.GetEnableLabelMissing() && len(missingLabels) > 0 && a.event == "unlabeled" {
+		if missingLabels, err = a.missingLabelsOnUnlabeled(missingLabels); err != nil {
+			return err
 		}
 	}
 
+	if a.config.GetEnableLabelMissing() {
+		a.assignMissingAuthor(pr, currentLabelsSet, missingLabels)
+	}
+
 	// Add missing label
-	if a.config.GetEnableLabelMissing() && checkedCount == 0 {
+	if a.config.GetEnableLabelMissing() && len(missingLabels) > 0 {
 		logger.Infoln("@Add missing label")
-		_, _, err = a.client.Issues.AddLabelsToIssue(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			[]string{a.config.GetLabelMissing()})
-		if err != nil {
-			return fmt.Errorf("add missing label %v: %v", a.config.GetLabelMissing(), err)
+		if err := a.provider.AddLabels(a.globalContext, a.config.GetNumber(), missingLabels); err != nil {
+			a.warn(fmt.Sprintf("Add missing label %v", missingLabels), err)
 		}
 
-		_, _, err = a.client.Issues.CreateComment(a.globalContext,
-			a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			&github.IssueComment{
-				Body: func(v string) *string { return &v }(fmt.Sprintf("@%s %s", pr.User.GetLogin(), MessageLabelMissing))})
-		if err != nil {
-			logger.Infof("Create issue comment: %v\n", err)
-		}
+		return a.enforceLabelCheck(pr, failureLabelMissing, "confused", nil, lacking, nil)
+	}
 
-		return fmt.Errorf("%s", MessageLabelMissing)
+	if err := a.assignReviewers(pr, currentLabelsSet); err != nil {
+		return fmt.Errorf("assign reviewers: %w", err)
 	}
 
 	// Update PR Body
@@ -482,39 +2288,219 @@ func (a *Action) onPullRequestLabeledOrUnlabeled() error {
 		}
 	}
 
-	body := pr.GetBody()
+	if len(changeList) > 0 {
+		logger.Infoln("@Update PR body")
+		logger.Infof("ChangeList: %v\n", changeList)
+
+		if err := a.editCheckboxes(pr, changeList); err != nil {
+			return fmt.Errorf("edit PR: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// labelMissingMessage returns the reminder for a PR without label,
+// which is more detailed for first-time contributors.
+func (a *Action) labelMissingMessage(pr *scm.PullRequest) string {
+	if !a.config.GetEnableOnboarding() {
+		return a.withGuide(a.withHints(a.config.GetMessageLabelMissing()))
+	}
+	switch pr.AuthorAssociation {
+	case "FIRST_TIME_CONTRIBUTOR", "FIRST_TIMER":
+	default:
+		return a.withGuide(a.withHints(a.config.GetMessageLabelMissing()))
+	}
+
+	return a.withGuide(a.withHints(fmt.Sprintf("%s\n\n%s", a.config.GetMessageLabelOnboarding(), a.checklist())))
+}
+
+// withGuide appends the link to the label guide to message, if GUIDE_URL is set.
+func (a *Action) withGuide(message string) string {
+	if len(a.config.GetGuideURL()) == 0 {
+		return message
+	}
+	title := "Documentation Label Guide"
+	if len(a.config.GetProjectName()) > 0 {
+		title = a.config.GetProjectName() + " " + title
+	}
+	return fmt.Sprintf("%s\n%s", strings.TrimRight(message, "\n"), fmt.Sprintf(MessageGuide, title, a.config.GetGuideURL()))
+}
+
+// watchedLabels returns the sorted watched labels an author can select.
+func (a *Action) watchedLabels() []string {
+	labels := []string{}
+	for label := range a.config.labelWatchSet {
+		if len(label) > 0 && !a.config.isMissingLabel(label) {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// renderChecklist renders labels as unchecked task list items.
+func renderChecklist(labels []string) string {
+	checklist := &strings.Builder{}
+	for _, label := range labels {
+		fmt.Fprintf(checklist, "- [ ] `%s`\n", label)
+	}
+	return checklist.String()
+}
+
+// editCheckboxes updates the checkboxes of pr to changeList. A body that can't be safely modified
+// is left untouched with a warning, as the labels are already applied.
+func (a *Action) editCheckboxes(pr *scm.PullRequest, changeList map[string]bool) error {
+	body, err := setCheckboxes(pr.Body, changeList, a.config.GetLabelTolerance() == "lenient")
+	if err == errBodyTooLong {
+		githubactions.Warningf("Checkboxes of PR #%d are not updated: %v", pr.Number, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.plan.addBodyEdits(changeList)
+	return a.provider.EditBody(a.globalContext, pr.Number, body)
+}
+
+// errBodyTooLong is returned by setCheckboxes when the edited body would exceed the length GitHub allows.
+var errBodyTooLong = fmt.Errorf("PR body would exceed %d characters", maxEditableBodyLength)
+
+// setCheckboxes checks or unchecks the checkbox of each label in changeList in place,
+// inserting the checkboxes not found in body after the last checkbox line, or at the end if there's none.
+// If lenient, the checkbox lines tolerated by LABEL_TOLERANCE lenient are set too.
+// It edits body in a single pass over its lines, so that large PR templates stay fast.
+// It fails with errBodyTooLong rather than returning a body GitHub would reject.
+func setCheckboxes(body string, changeList map[string]bool, lenient bool) (string, error) {
+	pending := make(map[string]bool, len(changeList))
 	for label, checked := range changeList {
-		src := fmt.Sprintf("- [ ] `%s`", label)
-		dst := fmt.Sprintf("- [x] `%s`", label)
-		if !checked {
-			src = fmt.Sprintf("- [x] `%s`", label)
-			dst = fmt.Sprintf("- [ ] `%s`", label)
+		pending[label] = checked
+	}
+
+	b := &strings.Builder{}
+	b.Grow(len(body) + len(changeList)*16)
+	// insertAt is the offset in b after the last checkbox line, where the missing checkboxes go
+	insertAt := -1
+	for len(body) > 0 {
+		line := body
+		if i := strings.IndexByte(body, '\n'); i >= 0 {
+			line = body[:i+1]
 		}
+		body = body[len(line):]
 
-		if strings.Contains(body, src) { // Update the label
-			body = strings.Replace(body, src, dst, 1)
-		} else { // Add the label
-			body = fmt.Sprintf("%s\r\n%s\r\n", body, dst)
+		if len(pending) > 0 {
+			line = toggleCheckboxes(line, pending)
+		}
+		if lenient && len(pending) > 0 {
+			line = toggleLenientCheckbox(line, pending)
+		}
+		if isCheckboxLine(line) || lenient && lenientCheckboxRegexp.MatchString(line) {
+			insertAt = b.Len() + strings.IndexAny(line+"\n", "\r\n")
 		}
+		b.WriteString(line)
 	}
 
-	if len(changeList) > 0 {
-		logger.Infoln("@Update PR body")
-		logger.Infof("ChangeList: %v\n", changeList)
+	edited := b.String()
+	if len(pending) > 0 {
+		labels := make([]string, 0, len(pending))
+		for label := range pending {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		inserted := &strings.Builder{}
+		for _, label := range labels {
+			mark := " "
+			if pending[label] {
+				mark = "x"
+			}
+			fmt.Fprintf(inserted, "\r\n- [%s] `%s`", mark, label)
+		}
+		if insertAt >= 0 {
+			edited = edited[:insertAt] + inserted.String() + edited[insertAt:]
+		} else {
+			edited = edited + inserted.String() + "\r\n"
+		}
+	}
 
-		_, _, err = a.client.PullRequests.Edit(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(),
-			&github.PullRequest{Body: &body})
-		if err != nil {
-			return fmt.Errorf("edit PR: %v", err)
+	if utf8.RuneCountInString(edited) > maxEditableBodyLength {
+		return "", errBodyTooLong
+	}
+	return edited, nil
+}
+
+// toggleCheckboxes sets the checkboxes "- [ ] `label`" of line to the state of their label in pending,
+// removing the labels it finds from pending, so that only the first checkbox of a label is set.
+func toggleCheckboxes(line string, pending map[string]bool) string {
+	var edited []byte
+	for offset := 0; ; {
+		i := strings.Index(line[offset:], "- [")
+		if i < 0 {
+			break
+		}
+		i += offset
+		offset = i + 3
+		rest := line[i:]
+		if len(rest) < 7 || !strings.Contains(" xX", rest[3:4]) || rest[4:7] != "] `" {
+			continue
+		}
+		end := strings.IndexByte(rest[7:], '`')
+		if end < 0 {
+			continue
+		}
+		label := rest[7 : 7+end]
+		checked, exist := pending[label]
+		if !exist {
+			continue
+		}
+		delete(pending, label)
+		if edited == nil {
+			edited = []byte(line)
 		}
+		edited[i+3] = ' '
+		if checked {
+			edited[i+3] = 'x'
+		}
+	}
+	if edited == nil {
+		return line
+	}
+	return string(edited)
+}
+
+// toggleLenientCheckbox sets the checkbox of line matching lenientCheckboxRegexp to the state of its label
+// in pending, removing the label from pending.
+func toggleLenientCheckbox(line string, pending map[string]bool) string {
+	m := lenientCheckboxRegexp.FindStringSubmatchIndex(line)
+	if m == nil {
+		return line
+	}
+	label := strings.Trim(line[m[4]:m[5]], "`")
+	checked, exist := pending[label]
+	if !exist {
+		return line
+	}
+	delete(pending, label)
+	mark := " "
+	if checked {
+		mark = "x"
 	}
+	return line[:m[2]] + mark + line[m[3]:]
+}
 
-	return nil
+// isCheckboxLine reports whether line is a checkbox line of a label, like "- [x] `label` description".
+func isCheckboxLine(line string) bool {
+	if len(line) < 8 || line[:3] != "- [" || !strings.Contains(" xX", line[3:4]) || line[4] != ']' {
+		return false
+	}
+	rest := strings.TrimPrefix(line[5:], " ")
+	if len(rest) < 3 || rest[0] != '`' {
+		return false
+	}
+	end := strings.IndexAny(rest[1:], "`\r\n")
+	return end > 0 && rest[1+end] == '`'
 }
 
 func (a *Action) extractLabels(prBody string) map[string]bool {
-	r := regexp.MustCompile(a.config.GetLabelPattern())
-	targets := r.FindAllStringSubmatch(prBody, -1)
 	labels := make(map[string]bool)
 
 	//// Init labels from watch list
@@ -522,63 +2508,46 @@ func (a *Action) extractLabels(prBody string) map[string]bool {
 	//	labels[label] = false
 	//}
 
-	for _, v := range targets {
-		checked := strings.ToLower(strings.TrimSpace(v[1])) == "x"
-		name := strings.TrimSpace(v[2])
-
+	for name, checked := range a.extractCheckboxes(prBody) {
 		// Filter uninterested labels
-		if _, exist := a.config.labelWatchSet[name]; !exist {
-			continue
+		if a.config.isWatchedLabel(name) {
+			labels[name] = checked
 		}
-
-		labels[name] = checked
 	}
-
 	return labels
 }
 
-func (a *Action) getRepoLabels() ([]*github.Label, error) {
-	ctx := context.Background()
-	listOptions := &github.ListOptions{PerPage: 100}
-	repoLabels := make([]*github.Label, 0)
-	for {
-		rLabels, resp, err := a.client.Issues.ListLabels(ctx, a.config.GetOwner(), a.config.GetRepo(), listOptions)
-		if err != nil {
-			return nil, err
-		}
-		repoLabels = append(repoLabels, rLabels...)
-		if resp.NextPage == 0 {
-			break
-		}
-		listOptions.Page = resp.NextPage
+// extractCheckboxes returns the labels of all the checkboxes of prBody, watched or not, and whether they are checked.
+func (a *Action) extractCheckboxes(prBody string) map[string]bool {
+	labels := make(map[string]bool)
+
+	if len(prBody) > maxBodyLength {
+		logger.Infof("PR body is longer than %d, only the beginning is parsed\n", maxBodyLength)
+		prBody = prBody[:maxBodyLength]
 	}
-	return repoLabels, nil
-}
 
-func (a *Action) getIssueLabels() ([]*github.Label, error) {
-	ctx := context.Background()
-	listOptions := &github.ListOptions{PerPage: 100}
-	issueLabels := make([]*github.Label, 0)
-	for {
-		iLabels, resp, err := a.client.Issues.ListLabelsByIssue(ctx, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
-		if err != nil {
-			return nil, err
-		}
-		issueLabels = append(issueLabels, iLabels...)
-		if resp.NextPage == 0 {
-			break
+	for _, preset := range a.config.labelExtractors {
+		targets := preset.re.FindAllStringSubmatch(prBody, maxLabelMatches)
+
+		for _, v := range targets {
+			state, label := preset.submatches(v)
+			checked := preset.checked(state)
+			// the lenient preset captures labels with their backticks, if any
+			name := strings.Trim(strings.TrimSpace(label), "`")
+			if len(name) == 0 {
+				continue
+			}
+
+			labels[name] = labels[name] || checked
 		}
-		listOptions.Page = resp.NextPage
 	}
-	return issueLabels, nil
-}
 
-func (a *Action) labelsToString(labels []*github.Label) []string {
-	result := []string{}
-	for _, label := range labels {
-		result = append(result, label.GetName())
+	// Labels declared in front-matter are checked
+	for _, name := range extractFrontMatterLabels(prBody) {
+		labels[name] = true
 	}
-	return result
+
+	return labels
 }
 
 func (a *Action) labelsSetToString(labels map[string]struct{}) []string {
@@ -590,56 +2559,269 @@ func (a *Action) labelsSetToString(labels map[string]struct{}) []string {
 }
 
 func main() {
-	logger.Infoln("@Start docbot")
+	printConfigOnly := flag.Bool("print-config", false, "print the effective configuration with the source of each setting, and exit")
+	printVersion := flag.Bool("version", false, "print the version, commit and build date, and exit")
+	localRepo := flag.String("repo", "", "repository as owner/repo if GITHUB_REPOSITORY is not set, as in a local run")
+	localNumber := flag.Int("pr", 0, "PR to handle as if it was edited if the event has none, as in a local run")
+	localEventPath := flag.String("event", "", "path of the event payload if GITHUB_EVENT_PATH is not set")
+	localEventName := flag.String("event-name", "", "name of the event if GITHUB_EVENT_NAME is not set, pull_request by default")
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(buildInfo())
+		return
+	}
+
+	if flag.Arg(0) == "selftest" {
+		if err := runSelftestCommand(flag.Args()[1:]); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	logger.Infof("@Start docbot %v\n", buildInfo())
+
+	if err := prepareLocalRun(*localRepo, *localNumber, *localEventPath, *localEventName); err != nil {
+		exit(failureConfig, err)
+	}
+
+	if getInput("mode") == "replay" {
+		if err := prepareReplay(getInput("replay-dir")); err != nil {
+			exit(failureConfig, fmt.Errorf("prepare replay: %w", err))
+		}
+	}
 
 	actionConfig, err := NewActionConfig()
 	if err != nil {
-		logger.Fatalf("Get action config: %v\n", err)
+		exit(failureConfig, fmt.Errorf("get action config: %w", err))
+	}
+	if *printConfigOnly {
+		printConfig(os.Stdout)
+		return
+	}
+
+	if len(actionConfig.GetToken()) > 0 {
+		githubactions.AddMask(actionConfig.GetToken())
+	}
+	if len(actionConfig.GetAppPrivateKey()) > 0 {
+		githubactions.AddMask(actionConfig.GetAppPrivateKey())
+	}
+
+	if err := configureTransport(actionConfig); err != nil {
+		exit(failureConfig, fmt.Errorf("configure transport: %w", err))
+	}
+
+	// every mode records fixtures and plans only
+	transport, err := installAPITransport(actionConfig)
+	if err != nil {
+		exit(failureConfig, err)
+	}
+
+	// the requests in flight when the run times out fail, and so does the run
+	ctx := context.Background()
+	if timeout := actionConfig.GetRunTimeout(); timeout > 0 && actionConfig.GetMode() != "server" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if actionConfig.GetSCMProvider() != "gitlab" {
+		resolveBotLogin(ctx, actionConfig, newGitHubClient(ctx, actionConfig.GetToken(), actionConfig.transport()))
+	}
+
+	if actionConfig.GetSCMProvider() == "gitlab" {
+		if err := runGitLab(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	}
+
+	switch actionConfig.GetMode() {
+	case "backfill":
+		if err := runBackfill(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "server":
+		if err := runServer(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "stale":
+		if err := runStale(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "digest":
+		if err := runDigest(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "report":
+		if err := runReport(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "expire":
+		if err := runExpire(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "lint":
+		if err := runLint(actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "label-sync":
+		if err := runLabelSync(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	case "label-usage":
+		if err := runLabelUsage(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		return
+	}
+
+	if isLocalRun() && actionConfig.GetMode() != "replay" {
+		actionConfig.disableLocalFeatures()
 	}
 
-	action := NewAction(actionConfig)
+	action := NewAction(ctx, actionConfig, actionConfig.transport())
+	planner := newPlanProvider(action.provider)
+	action.provider = planner
+	action.plan = planner.plan
 
 	githubContext, err := githubactions.Context()
 	if err != nil {
-		logger.Fatalf("Get github context: %v\n", err)
+		exit(failureConfig, fmt.Errorf("get github context: %w", err))
+	}
+
+	if recorder, ok := transport.(*fixture.Recorder); ok {
+		err := recorder.RecordEvent(githubContext.EventName, os.Getenv("GITHUB_REPOSITORY"), githubContext.EventPath)
+		if err != nil {
+			logger.Fatalf("Record event: %v\n", err)
+		}
 	}
 
-	if githubContextBytes, err := json.Marshal(githubContext); err == nil {
-		logger.Infof("githubContext: %v\n", string(githubContextBytes))
+	switch actionConfig.GetLogPayload() {
+	case "full":
+		if githubContextBytes, err := json.Marshal(githubContext); err == nil {
+			logger.Infof("githubContext: %v\n", maskTokens(string(githubContextBytes)))
+		}
+	case "redacted":
+		redactedContext := *githubContext
+		redactedContext.Event, _ = redactPayload(githubContext.Event, actionConfig.logRedactFields).(map[string]interface{})
+		if githubContextBytes, err := json.Marshal(redactedContext); err == nil {
+			logger.Infof("githubContext: %v\n", string(githubContextBytes))
+		}
+	}
+
+	event, err := readEvent(githubContext.EventName, githubContext.EventPath)
+	if err != nil {
+		exit(failureConfig, fmt.Errorf("read event: %w", err))
+	}
+	if event, err = localPullRequestEvent(action, event, *localNumber); err != nil {
+		exit(failureConfig, fmt.Errorf("read local event: %w", err))
+	}
+	if !actionConfig.isEventEnabled(githubContext.EventName, event) {
+		return
 	}
 
-	switch githubContext.EventName {
-	case "issues":
+	switch event := event.(type) {
+	case *ghapi.IssuesEvent:
 		logger.Infoln("@EventName is issues")
-	case "pull_request", "pull_request_target":
+	case *ghapi.PushEvent:
+		logger.Infoln("@EventName is push")
+
+		if err := runConflicts(ctx, actionConfig); err != nil {
+			failRun(ctx, err)
+		}
+		if err := runLandedDocs(ctx, actionConfig, event); err != nil {
+			failRun(ctx, err)
+		}
+	case *ghapi.RepositoryDispatchEvent:
+		logger.Infoln("@EventName is repository dispatch")
+
+		req, err := parseDispatchPayload(event.ClientPayload)
+		if err != nil {
+			exit(failureConfig, fmt.Errorf("parse dispatch payload: %w", err))
+		}
+		if err := runDispatch(ctx, actionConfig, req); err != nil {
+			failRun(ctx, err)
+		}
+	case *ghapi.PullRequestEvent:
 		logger.Infoln("@EventName is PR")
 
-		actionType, ok := githubContext.Event["action"].(string)
-		if !ok {
-			logger.Fatalln("Action type is not string")
+		pr := event.GetPullRequest()
+		if pr == nil {
+			exit(failureConfig, fmt.Errorf("%v event has no pull_request", event.GetAction()))
+		}
+		number := pr.GetNumber()
+		if number == 0 {
+			number = event.GetNumber()
 		}
 
-		pr := githubContext.Event["pull_request"]
-		pullRequest, ok := pr.(map[string]interface{})
-		if !ok {
-			logger.Fatalln("PR event is not map")
+		// Get expected labels
+		labels := action.extractLabels(pr.GetBody())
+
+		action.config = actionConfig.withNumber(number)
+		action.config.labels = labels
+
+		if actionConfig.GetEnableLabelPicker() {
+			picker, err := action.ensureLabelPicker()
+			if err != nil {
+				failRun(ctx, fmt.Errorf("ensure label picker: %w", err))
+			}
+			mergeLabels(labels, action.extractLabels(picker.GetBody()))
 		}
 
-		number := int(githubContext.Event["number"].(float64))
+		err := action.Run(event.GetAction())
+		planner.setPlanOutput(number, err)
+		if err != nil {
+			failRun(ctx, err)
+		}
+	case *ghapi.PullRequestReviewEvent:
+		logger.Infoln("@EventName is PR review")
 
-		prBody, ok := pullRequest["body"].(string)
-		if !ok {
-			logger.Fatalln("PR body is not string")
+		action.config = actionConfig.withNumber(event.GetPullRequest().GetNumber())
+
+		if err := action.checkDocsApproval(); err != nil {
+			failRun(ctx, fmt.Errorf("check docs approval: %w", err))
 		}
+	case *ghapi.IssueCommentEvent:
+		logger.Infoln("@EventName is issue comment")
 
-		// Get expected labels
-		labels := action.extractLabels(prBody)
+		if event.GetIssue() == nil || !event.GetIssue().IsPullRequest() {
+			return
+		}
+		commentBody := event.GetComment().GetBody()
+		number := event.GetIssue().GetNumber()
+		action.config = actionConfig.withNumber(number)
+
+		if command := parseCommand(commentBody); event.GetAction() == "created" && len(command) > 0 {
+			err := action.runCommand(command, event.GetComment())
+			planner.setPlanOutput(number, err)
+			if err != nil {
+				failRun(ctx, err)
+			}
+			return
+		}
 
-		actionConfig.number = &number
-		actionConfig.labels = labels
+		if !actionConfig.GetEnableLabelPicker() || event.GetAction() != "edited" {
+			return
+		}
+		if !strings.Contains(commentBody, labelPickerMarker) {
+			return
+		}
 
-		if err := action.Run(actionType); err != nil {
-			logger.Fatalln(err)
+		err := action.onLabelPickerEdited(event.GetComment())
+		planner.setPlanOutput(number, err)
+		if err != nil {
+			failRun(ctx, err)
 		}
+	default:
+		logger.Infof("@EventName %v is not handled\n", githubContext.EventName)
 	}
 }
@@ -0,0 +1,105 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// labelLatency is the time from PR creation until its first watched label, observed by the runs of the process
+// and served at /metrics in server mode.
+var labelLatency = newHistogram([]float64{
+	time.Minute.Seconds(), (10 * time.Minute).Seconds(), time.Hour.Seconds(), (6 * time.Hour).Seconds(),
+	(24 * time.Hour).Seconds(), (3 * 24 * time.Hour).Seconds(), (7 * 24 * time.Hour).Seconds(),
+})
+
+// histogram counts observations in cumulative buckets, as a Prometheus histogram.
+type histogram struct {
+	mu sync.Mutex
+	// bounds are the upper bounds of the buckets, in ascending order
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// write writes h as metric name in the Prometheus text format.
+func (h *histogram) write(w io.Writer, name, help string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64), name, h.count)
+}
+
+// handleMetrics serves the metrics of the process in the Prometheus text format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	labelLatency.write(w, "docbot_label_latency_seconds", "Time from PR creation until its first watched label was applied.")
+}
+
+// observeLabelLatency records the label latency of pr, if the run applies its first watched label:
+// none of current is watched but some of added is. The latency is also set as the label-latency output, in seconds.
+func (a *Action) observeLabelLatency(pr *scm.PullRequest, current map[string]struct{}, added []string) {
+	if pr.CreatedAt.IsZero() || a.config.GetPlanOnly() {
+		return
+	}
+	for label := range current {
+		if a.config.isWatchedLabel(label) && !a.config.isMissingLabel(label) {
+			return
+		}
+	}
+	for _, label := range added {
+		if a.config.isWatchedLabel(label) && !a.config.isMissingLabel(label) {
+			latency := now().Sub(pr.CreatedAt)
+			logger.Infof("First watched label of PR #%d applied %v after its creation\n", pr.Number, latency.Round(time.Second))
+			labelLatency.observe(latency.Seconds())
+			githubactions.SetOutput("label-latency", strconv.FormatInt(int64(latency.Seconds()), 10))
+			return
+		}
+	}
+}
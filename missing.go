@@ -0,0 +1,162 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const (
+	// missingCategoryDefault is the category of the labels in the watch list
+	missingCategoryDefault = "default"
+	// missingCategoryReleaseNote is the category of the release note section
+	missingCategoryReleaseNote = "release-note"
+)
+
+// parseLabelMissing parses LABEL_MISSING, either the single missing label, or missing labels keyed by category
+// separated by ",", e.g. `default=doc-label-missing,area/=area-label-missing,release-note=release-note-missing`.
+// A category is default, the prefix of a namespace, or release-note. It returns the missing label of default,
+// and those of the other categories.
+func parseLabelMissing(slug string) (string, map[string]string, error) {
+	labelMissingMap := make(map[string]string)
+	if !strings.Contains(slug, "=") {
+		slug = strings.TrimSpace(slug)
+		if len(slug) == 0 {
+			slug = "label-missing"
+		}
+		return slug, labelMissingMap, nil
+	}
+
+	for _, item := range strings.Split(slug, ",") {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+		category, label, _ := strings.Cut(item, "=")
+		category, label = strings.TrimSpace(category), strings.TrimSpace(label)
+		if len(category) == 0 || len(label) == 0 {
+			return "", nil, fmt.Errorf("invalid missing label %v", item)
+		}
+		labelMissingMap[category] = label
+	}
+	labelMissing, exist := labelMissingMap[missingCategoryDefault]
+	if !exist {
+		labelMissing = "label-missing"
+	}
+	delete(labelMissingMap, missingCategoryDefault)
+	return labelMissing, labelMissingMap, nil
+}
+
+// labelMissingOf returns the missing label of category, which is LABEL_MISSING unless configured per category.
+func (ac *ActionConfig) labelMissingOf(category string) string {
+	if label, exist := ac.labelMissingMap[category]; exist {
+		return label
+	}
+	return ac.GetLabelMissing()
+}
+
+// isMissingLabel reports whether label is the missing label of any category.
+func (ac *ActionConfig) isMissingLabel(label string) bool {
+	if label == ac.GetLabelMissing() {
+		return true
+	}
+	for _, l := range ac.labelMissingMap {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMissingLabels returns the missing labels of the watch list and namespaces, which checkCardinality manages.
+func (ac *ActionConfig) groupMissingLabels() map[string]struct{} {
+	labels := map[string]struct{}{ac.GetLabelMissing(): {}}
+	for _, ns := range ac.labelNamespaces {
+		labels[ac.labelMissingOf(ns.prefix)] = struct{}{}
+	}
+	return labels
+}
+
+// missingLabelsOf returns the sorted missing labels of the lacking categories.
+func (ac *ActionConfig) missingLabelsOf(lacking []string) []string {
+	set := make(map[string]struct{})
+	for _, category := range lacking {
+		set[ac.labelMissingOf(category)] = struct{}{}
+	}
+	labels := []string{}
+	for label := range set {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// removeSatisfiedMissingLabels plans to remove the current missing labels of the watch list and namespaces
+// that are not in missingLabels, as their category got a label selected.
+func (a *Action) removeSatisfiedMissingLabels(currentLabelsSet map[string]struct{}, missingLabels []string, labelsToRemove map[string]struct{}) {
+	keep := make(map[string]struct{})
+	for _, label := range missingLabels {
+		keep[label] = struct{}{}
+	}
+	for label := range a.config.groupMissingLabels() {
+		if _, exist := currentLabelsSet[label]; !exist {
+			continue
+		}
+		if _, lacking := keep[label]; !lacking {
+			labelsToRemove[label] = struct{}{}
+		}
+	}
+}
+
+// assignMissingAuthor assigns the author of pr when a missing label is newly applied, and unassigns them once
+// no missing label is left, if ASSIGN_AUTHOR_ON_MISSING is enabled. It surfaces the PR in the author's
+// assignment filters while it waits on them. Failures are only logged.
+func (a *Action) assignMissingAuthor(pr *scm.PullRequest, currentLabelsSet map[string]struct{}, missingLabels []string) {
+	if !a.config.GetAssignAuthorOnMissing() || a.client == nil {
+		return
+	}
+
+	applied, hadMissing := false, false
+	for _, label := range missingLabels {
+		if _, exist := currentLabelsSet[label]; !exist {
+			applied = true
+		}
+	}
+	for label := range currentLabelsSet {
+		if a.config.isMissingLabel(label) {
+			hadMissing = true
+		}
+	}
+
+	var err error
+	switch {
+	case applied:
+		logger.Infof("@Assign %v\n", pr.Author)
+		_, _, err = a.client.Issues.AddAssignees(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.Number, []string{pr.Author})
+	case hadMissing && len(missingLabels) == 0:
+		logger.Infof("@Unassign %v\n", pr.Author)
+		_, _, err = a.client.Issues.RemoveAssignees(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.Number, []string{pr.Author})
+	}
+	if err != nil {
+		a.warn("Update assignees", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// labelNamespace is a group of labels sharing a prefix, like `area/`, enforced on its own
+// so that its labels don't need to be enumerated in the watch list.
+type labelNamespace struct {
+	prefix string
+	// required means a label of the namespace must be selected
+	required bool
+	// multiple allows more than one label of the namespace
+	multiple bool
+}
+
+// namespaceCardinalities are the cardinalities a namespace can be declared with.
+var namespaceCardinalities = map[string]labelNamespace{
+	"one":      {required: true},
+	"many":     {required: true, multiple: true},
+	"optional": {},
+	"any":      {multiple: true},
+}
+
+// parseLabelNamespaces parses namespaces separated by ",", each a prefix optionally followed by
+// "=" and its cardinality, e.g. `doc/=one,area/=many,release/=optional`. The default cardinality is one.
+func parseLabelNamespaces(slug string) ([]labelNamespace, error) {
+	namespaces := []labelNamespace{}
+	for _, item := range strings.Split(slug, ",") {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+		prefix, cardinality, found := strings.Cut(item, "=")
+		if !found {
+			cardinality = "one"
+		}
+		ns, ok := namespaceCardinalities[strings.TrimSpace(cardinality)]
+		if !ok {
+			return nil, fmt.Errorf("invalid cardinality of %v: %v", prefix, cardinality)
+		}
+		ns.prefix = strings.TrimSpace(prefix)
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+// labelNamespace returns the namespace of label, or nil if it isn't in any.
+func (ac *ActionConfig) labelNamespace(label string) *labelNamespace {
+	for i := range ac.labelNamespaces {
+		if strings.HasPrefix(label, ac.labelNamespaces[i].prefix) {
+			return &ac.labelNamespaces[i]
+		}
+	}
+	return nil
+}
+
+// isWatchedLabel reports whether label is in the watch list or in a namespace,
+// or is any label but the missing ones if EMPTY_WATCH_LIST watches all the checkboxes.
+func (ac *ActionConfig) isWatchedLabel(label string) bool {
+	if _, exist := ac.labelWatchSet[label]; exist {
+		return true
+	}
+	if ac.watchesAll() {
+		return len(label) > 0 && !ac.isMissingLabel(label)
+	}
+	return ac.labelNamespace(label) != nil
+}
+
+// watchesNothing reports whether neither LABEL_WATCH_LIST nor LABEL_NAMESPACES is set.
+func (ac *ActionConfig) watchesNothing() bool {
+	return len(ac.labelWatchSet) == 0 && len(ac.labelNamespaces) == 0
+}
+
+// watchesAll reports whether every checkbox matching the label pattern is managed, as the watch list is empty.
+func (ac *ActionConfig) watchesAll() bool {
+	return ac.watchesNothing() && ac.GetEmptyWatchList() == "watch-all-from-pattern"
+}
+
+// checkCardinality returns the categories lacking a required label in checked, and those with multiple labels in checked
+// where only one is allowed. The category of a namespace is its prefix, and that of the labels outside namespaces is
+// default. They are enforced as a group by ENABLE_LABEL_MULTIPLE, and always need one label selected unless only
+// namespaces are watched. Whether a lacking category adds its missing label is up to ENABLE_LABEL_MISSING.
+func (a *Action) checkCardinality(checked []string) (lacking []string, multiple []string) {
+	counts := make(map[string]int)
+	others := 0
+	for _, label := range checked {
+		if ns := a.config.labelNamespace(label); ns != nil {
+			counts[ns.prefix]++
+		} else if !a.config.isMissingLabel(label) {
+			others++
+		}
+	}
+
+	for _, ns := range a.config.labelNamespaces {
+		if ns.required && counts[ns.prefix] == 0 {
+			logger.Infof("No label selected in namespace %v\n", ns.prefix)
+			lacking = append(lacking, ns.prefix)
+		}
+		if !ns.multiple && counts[ns.prefix] > 1 {
+			logger.Infof("Multiple labels selected in namespace %v\n", ns.prefix)
+			multiple = append(multiple, ns.prefix)
+		}
+	}
+
+	if len(a.config.labelNamespaces) == 0 || len(a.watchedLabels()) > 0 {
+		if others == 0 {
+			lacking = append(lacking, missingCategoryDefault)
+		}
+		if !a.config.GetEnableLabelMultiple() && others > 1 {
+			multiple = append(multiple, missingCategoryDefault)
+		}
+	}
+	return lacking, multiple
+}
+
+// labelCategory returns the category of label, the prefix of its namespace or default.
+func (ac *ActionConfig) labelCategory(label string) string {
+	if ns := ac.labelNamespace(label); ns != nil {
+		return ns.prefix
+	}
+	return missingCategoryDefault
+}
@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/notify"
+)
+
+// notifyAll sends msg to every configured notifier, for teams which don't follow the step summaries
+// or the digest issue. It does nothing if msg lists no PRs, and a failing notifier doesn't stop the others.
+// With PLAN_ONLY, nothing is sent either, as the PRs weren't actually changed.
+func notifyAll(ctx context.Context, ac *ActionConfig, msg *notify.Message) error {
+	if len(ac.notifiers) == 0 || len(msg.Items) == 0 {
+		return nil
+	}
+	if ac.GetPlanOnly() {
+		logger.Infof("Plan only, skip notifying %s to %v targets\n", msg.Event, len(ac.notifiers))
+		return nil
+	}
+	msg.Repo = fmt.Sprintf("%s/%s", ac.GetOwner(), ac.GetRepo())
+
+	logger.Infof("@Notify %s to %v targets\n", msg.Event, len(ac.notifiers))
+	errs := []string{}
+	for _, n := range ac.notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			logger.Errorf("Notify %T: %v\n", n, err)
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("notify: %v", strings.Join(errs, "; "))
+	}
+	return nil
+}
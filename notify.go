@@ -0,0 +1,109 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// stateReminderAt is the state key of when the author was last reminded, kept as
+// the <!-- docbot:reminder at="..." --> marker of the reminder comments by the comment state.
+const stateReminderAt = "reminder at"
+
+// remind tells the PR author about a label problem. In reaction mode, the first violation
+// only gets a reaction on the PR description plus a failed check run, and comments are
+// reserved for repeated violations.
+func (a *Action) remind(pr *scm.PullRequest, reaction, message string) error {
+	if a.config.GetNotifyMode() != "reaction" || a.client == nil {
+		return a.deliver(pr, message)
+	}
+
+	reacted, err := a.hasBotReaction(pr.Number, reaction)
+	if err != nil {
+		return fmt.Errorf("list reactions: %w", err)
+	}
+	if reacted {
+		return a.deliver(pr, message)
+	}
+
+	_, _, err = a.client.Reactions.CreateIssueReaction(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.Number, reaction)
+	if err != nil {
+		return fmt.Errorf("create reaction: %w", err)
+	}
+
+	return a.createCheckRun(pr.HeadSHA, "action_required", "Documentation label needs attention", message)
+}
+
+// comment posts a reminder comment mentioning the PR author, unless the previous
+// reminder was posted within COMMENT_COOLDOWN.
+func (a *Action) comment(pr *scm.PullRequest, message string) error {
+	now := time.Now().UTC()
+	if cooldown := a.config.GetCommentCooldown(); cooldown > 0 {
+		last, err := a.lastReminderAt()
+		if err != nil {
+			return fmt.Errorf("load state: %w", err)
+		}
+		if !last.IsZero() && now.Sub(last) < cooldown {
+			logger.Infof("Last reminder was posted at %v, skip reminding within %v\n", last, cooldown)
+			return nil
+		}
+	}
+
+	return a.getState().Save(stateReminderAt, now.Format(time.RFC3339), fmt.Sprintf("@%s %s", pr.Author, message))
+}
+
+// lastReminderAt returns when the latest reminder was posted on the current PR,
+// or the zero time if there's none.
+func (a *Action) lastReminderAt() (time.Time, error) {
+	value, err := a.getState().Load(stateReminderAt)
+	if err != nil || len(value) == 0 {
+		return time.Time{}, err
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return at, nil
+}
+
+// hasBotReaction reports whether the bot already reacted with content on the PR description.
+func (a *Action) hasBotReaction(number int, content string) (bool, error) {
+	listOptions := &ghapi.ListReactionOptions{ListOptions: ghapi.ListOptions{PerPage: 100}}
+	for {
+		reactions, resp, err := a.client.Reactions.ListIssueReactions(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), number, listOptions)
+		if err != nil {
+			return false, err
+		}
+		for _, r := range reactions {
+			if r.GetContent() == content && strings.EqualFold(r.GetUser().GetLogin(), a.config.GetBotLogin()) {
+				return true, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return false, nil
+}
@@ -0,0 +1,79 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+const (
+	MessageLabelPicker = `Please select the documentation label for your PR by checking one of the boxes below:
+
+%s`
+
+	labelPickerMarker = markerPrefix + "label-picker -->"
+)
+
+// ensureLabelPicker returns the bot comment containing the watched-label checklist,
+// posting it first if the PR doesn't have one yet.
+func (a *Action) ensureLabelPicker() (*ghapi.IssueComment, error) {
+	comments, err := a.listBotComments()
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.GetBody(), labelPickerMarker) {
+			return comment, nil
+		}
+	}
+
+	body := fmt.Sprintf("%s\n%s", labelPickerMarker, fmt.Sprintf(MessageLabelPicker, a.checklist()))
+	if err := a.provider.Comment(a.globalContext, a.config.GetNumber(), body); err != nil {
+		return nil, fmt.Errorf("create issue comment: %w", err)
+	}
+	return &ghapi.IssueComment{Body: &body}, nil
+}
+
+// onLabelPickerEdited relabels the current PR after a box was ticked in the label picker comment.
+// The labels are those checked in either the PR body or the picker, and only the picker posted by the bot counts.
+func (a *Action) onLabelPickerEdited(picker *ghapi.IssueComment) error {
+	if !a.isBotComment(picker) {
+		logger.Infof("Ignore the label picker of %v\n", picker.GetUser().GetLogin())
+		return nil
+	}
+
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	labels := a.extractLabels(pr.Body)
+	mergeLabels(labels, a.extractLabels(picker.GetBody()))
+	a.config.labels = labels
+	return a.Run("edited")
+}
+
+// mergeLabels merges src into dst, a label is checked if it is checked in either.
+func mergeLabels(dst, src map[string]bool) {
+	for label, checked := range src {
+		dst[label] = dst[label] || checked
+	}
+}
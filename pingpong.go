@@ -0,0 +1,89 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+const (
+	MessagePingPong = `The labels of this PR are being added and removed repeatedly (%s), probably by another automation fighting with this bot.
+The bot stopped changing this PR. Please check the automations of this repository, then delete this comment to resume.`
+
+	pingPongMarker = markerPrefix + "ping-pong -->"
+)
+
+// checkPingPong reports whether the bot must back off from the current PR, either because
+// it detected a label ping-pong now, or because it is paused since an earlier detection.
+// A human resumes the bot by deleting the diagnostic comment, only those written by the bot pause it.
+func (a *Action) checkPingPong() (bool, error) {
+	if a.client == nil || a.config.GetPingPongThreshold() <= 0 {
+		return false, nil
+	}
+
+	comments, err := a.listBotComments()
+	if err != nil {
+		return false, fmt.Errorf("list comments: %w", err)
+	}
+	for _, comment := range comments {
+		if strings.Contains(comment.GetBody(), pingPongMarker) {
+			logger.Infoln("Paused by an earlier label ping-pong, delete the diagnostic comment to resume")
+			return true, nil
+		}
+	}
+
+	timeline, err := a.listTimeline()
+	if err != nil {
+		return false, fmt.Errorf("list timeline: %w", err)
+	}
+
+	since := time.Now().Add(-a.config.GetPingPongWindow())
+	flips := make(map[string]int)
+	for _, event := range timeline {
+		if event.GetCreatedAt().Before(since) {
+			continue
+		}
+		if event.GetEvent() == "labeled" || event.GetEvent() == "unlabeled" {
+			flips[event.GetLabel().GetName()]++
+		}
+	}
+
+	fighting := []string{}
+	for label, count := range flips {
+		if count >= a.config.GetPingPongThreshold() {
+			fighting = append(fighting, fmt.Sprintf("`%s`", label))
+		}
+	}
+	if len(fighting) == 0 {
+		return false, nil
+	}
+	sort.Strings(fighting)
+
+	logger.Infof("Label ping-pong detected on %v\n", fighting)
+	err = a.provider.Comment(a.globalContext, a.config.GetNumber(),
+		fmt.Sprintf("%s\n%s", pingPongMarker, fmt.Sprintf(MessagePingPong, strings.Join(fighting, ", "))))
+	if err != nil {
+		return true, fmt.Errorf("create issue comment: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,129 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package breaker caps the number of mutating API calls made per run.
+package breaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type contextKey struct{}
+
+// Breaker counts mutating calls and trips once more than max are attempted.
+// Once tripped, all further mutating calls are refused.
+type Breaker struct {
+	max int
+
+	mu      sync.Mutex
+	count   int
+	tripped bool
+}
+
+// New returns a Breaker allowing max mutating calls. A non-positive max disables the cap.
+func New(max int) *Breaker {
+	return &Breaker{max: max}
+}
+
+// WithBreaker returns a copy of ctx whose requests are counted by b.
+func WithBreaker(ctx context.Context, b *Breaker) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// Tripped reports whether the cap was exceeded.
+func (b *Breaker) Tripped() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tripped
+}
+
+func (b *Breaker) allow() error {
+	if b == nil || b.max <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tripped || b.count >= b.max {
+		b.tripped = true
+		return fmt.Errorf("more than %d mutating API calls in one run, aborting since this indicates a bug or a fight with another bot", b.max)
+	}
+	b.count++
+	return nil
+}
+
+// Transport wraps base so that mutating requests are checked against the Breaker of the request context.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if b, ok := req.Context().Value(contextKey{}).(*Breaker); ok {
+		mutating, err := IsMutating(req)
+		if err != nil {
+			return nil, err
+		}
+		if mutating {
+			if err := b.allow(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// IsMutating reports whether req changes state. GraphQL requests only count if they are mutations.
+func IsMutating(req *http.Request) (bool, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false, nil
+	}
+	if !strings.HasSuffix(req.URL.Path, "/graphql") || req.Body == nil {
+		return true, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return false, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	query := &struct {
+		Query string `json:"query"`
+	}{}
+	if err := json.Unmarshal(body, query); err != nil {
+		return true, nil
+	}
+	return strings.HasPrefix(strings.TrimSpace(query.Query), "mutation"), nil
+}
@@ -0,0 +1,115 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package breaker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsMutating(t *testing.T) {
+	for _, tt := range []struct {
+		method, path, body string
+		mutating           bool
+	}{
+		{http.MethodGet, "/repos/apache/pulsar/pulls/1", "", false},
+		{http.MethodHead, "/repos/apache/pulsar", "", false},
+		{http.MethodPost, "/repos/apache/pulsar/issues/1/labels", `["doc"]`, true},
+		{http.MethodDelete, "/repos/apache/pulsar/issues/1/labels/doc", "", true},
+		{http.MethodPost, "/graphql", `{"query": "query { viewer { login } }"}`, false},
+		{http.MethodPost, "/graphql", `{"query": "  mutation($id: ID!) { x }"}`, true},
+		{http.MethodPost, "/graphql", `not json`, true},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		mutating, err := IsMutating(req)
+		if err != nil || mutating != tt.mutating {
+			t.Errorf("IsMutating(%v %v %v) = %v, %v, want %v", tt.method, tt.path, tt.body, mutating, err, tt.mutating)
+		}
+		// the body is still readable by the transport
+		if body, _ := io.ReadAll(req.Body); string(body) != tt.body {
+			t.Errorf("body after IsMutating = %q, want %q", body, tt.body)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(nil)}
+
+	b := New(2)
+	ctx := WithBreaker(context.Background(), b)
+	do := func(ctx context.Context, method string) error {
+		req, _ := http.NewRequestWithContext(ctx, method, srv.URL+"/repos/apache/pulsar/issues/1/labels", nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// reads aren't counted
+	for i := 0; i < 5; i++ {
+		if err := do(ctx, http.MethodGet); err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := do(ctx, http.MethodPost); err != nil {
+			t.Fatalf("POST %d: %v", i, err)
+		}
+	}
+	if b.Tripped() {
+		t.Fatalf("tripped within the cap")
+	}
+
+	// the third mutation trips the breaker, and it stays tripped
+	if err := do(ctx, http.MethodPost); err == nil || !strings.Contains(err.Error(), "more than 2 mutating API calls") {
+		t.Fatalf("POST over the cap: err = %v", err)
+	}
+	if !b.Tripped() {
+		t.Fatalf("not tripped over the cap")
+	}
+	if requests != 7 {
+		t.Fatalf("requests = %d, want 7, the refused one not sent", requests)
+	}
+
+	// requests without a breaker in their context, or with a disabled one, aren't capped
+	for _, ctx := range []context.Context{context.Background(), WithBreaker(context.Background(), New(0))} {
+		if err := do(ctx, http.MethodPost); err != nil {
+			t.Fatalf("POST without cap: %v", err)
+		}
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	if b.Tripped() {
+		t.Errorf("nil Breaker tripped")
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("nil Breaker allow: %v", err)
+	}
+}
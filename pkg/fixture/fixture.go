@@ -0,0 +1,236 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package fixture records GitHub API exchanges of a run into a directory and replays them later.
+//
+// A fixtures directory contains:
+//
+//	context.json  the event name and repository of the run
+//	event.json    the event payload
+//	api/NNNN.json one file per API exchange, in order
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// Context describes the recorded run.
+type Context struct {
+	EventName  string `json:"event_name"`
+	Repository string `json:"repository"`
+}
+
+// Exchange is a single recorded API request and its response.
+type Exchange struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	RequestBody string            `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        string            `json:"body"`
+}
+
+// recordedHeaders are the response headers needed to replay, e.g. Link for pagination.
+var recordedHeaders = []string{"Content-Type", "Link"}
+
+// Recorder is an http.RoundTripper saving every exchange into a fixtures directory.
+// Request headers, including Authorization, are never recorded.
+type Recorder struct {
+	dir  string
+	base http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+func NewRecorder(dir string, base http.RoundTripper) (*Recorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "api"), 0755); err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, base: base}, nil
+}
+
+// RecordEvent saves the event name, repository and a copy of the payload at eventPath.
+func (r *Recorder) RecordEvent(eventName, repository, eventPath string) error {
+	if err := writeJSON(filepath.Join(r.dir, "context.json"), &Context{EventName: eventName, Repository: repository}); err != nil {
+		return err
+	}
+	if len(eventPath) == 0 {
+		return nil
+	}
+	payload, err := os.ReadFile(eventPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, "event.json"), payload, 0644)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	exchange := &Exchange{Method: req.Method, URL: req.URL.RequestURI()}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		exchange.RequestBody = string(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	exchange.Status = resp.StatusCode
+	exchange.Body = string(body)
+	exchange.Header = make(map[string]string)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); len(v) > 0 {
+			exchange.Header[h] = v
+		}
+	}
+
+	r.mu.Lock()
+	r.seq++
+	name := filepath.Join(r.dir, "api", fmt.Sprintf("%04d.json", r.seq))
+	r.mu.Unlock()
+
+	if err := writeJSON(name, exchange); err != nil {
+		return nil, fmt.Errorf("record %v: %w", name, err)
+	}
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper answering requests with recorded exchanges.
+// Identical requests are answered in the order they were recorded.
+type Replayer struct {
+	mu    sync.Mutex
+	queue map[string][]*Exchange
+}
+
+func NewReplayer(dir string) (*Replayer, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "api", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	r := &Replayer{queue: make(map[string][]*Exchange)}
+	for _, name := range names {
+		exchange := &Exchange{}
+		if err := readJSON(name, exchange); err != nil {
+			return nil, fmt.Errorf("load %v: %w", name, err)
+		}
+		key := exchange.Method + " " + exchange.URL
+		r.queue[key] = append(r.queue[key], exchange)
+	}
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := req.Method + " " + req.URL.RequestURI()
+	if req.Method != http.MethodGet {
+		logger.Infof("Replay %v\n", key)
+	}
+
+	r.mu.Lock()
+	exchanges := r.queue[key]
+	var exchange *Exchange
+	if len(exchanges) > 0 {
+		exchange = exchanges[0]
+		// Keep answering with the last exchange once the queue is drained
+		if len(exchanges) > 1 {
+			r.queue[key] = exchanges[1:]
+		}
+	}
+	r.mu.Unlock()
+
+	if exchange == nil {
+		exchange = &Exchange{
+			Status: http.StatusNotFound,
+			Header: map[string]string{"Content-Type": "application/json"},
+			Body:   fmt.Sprintf(`{"message": "no recorded response for %s"}`, key),
+		}
+	}
+
+	header := make(http.Header)
+	for k, v := range exchange.Header {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(exchange.Body)),
+		ContentLength: int64(len(exchange.Body)),
+		Request:       req,
+	}, nil
+}
+
+// LoadContext reads the recorded run context of dir.
+func LoadContext(dir string) (*Context, error) {
+	c := &Context{}
+	if err := readJSON(filepath.Join(dir, "context.json"), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func writeJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+func readJSON(name string, v interface{}) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,115 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fixture
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	labels := []string{`["doc"]`, `["doc","doc-required"]`}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Link", `<https://api.github.com/labels?page=2>; rel="next"`)
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.Write([]byte(labels[0]))
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			labels[0] = labels[1]
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	os.WriteFile(eventPath, []byte(`{"action": "opened"}`), 0644)
+	if err := recorder.RecordEvent("pull_request", "apache/pulsar", eventPath); err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+
+	client := &http.Client{Transport: recorder}
+	do := func(client *http.Client, method, body string) (int, string, http.Header) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/repos/apache/pulsar/issues/1/labels", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%v: %v", method, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), resp.Header
+	}
+	do(client, http.MethodGet, "")
+	if status, body, _ := do(client, http.MethodPost, `["doc-required"]`); status != http.StatusCreated || body != `["doc-required"]` {
+		t.Fatalf("recorded POST = %v %v, want the response passed through", status, body)
+	}
+	do(client, http.MethodGet, "")
+
+	names, _ := filepath.Glob(filepath.Join(dir, "api", "*.json"))
+	if len(names) != 3 {
+		t.Fatalf("recorded %v, want 3 exchanges", names)
+	}
+	for _, name := range names {
+		data, _ := os.ReadFile(name)
+		if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "X-RateLimit") {
+			t.Fatalf("%v = %s, want no request headers nor unneeded response headers", name, data)
+		}
+	}
+	c, err := LoadContext(dir)
+	if err != nil || c.EventName != "pull_request" || c.Repository != "apache/pulsar" {
+		t.Fatalf("LoadContext = %+v, %v", c, err)
+	}
+	if event, _ := os.ReadFile(filepath.Join(dir, "event.json")); string(event) != `{"action": "opened"}` {
+		t.Fatalf("event = %s, want the payload", event)
+	}
+
+	// identical requests are answered in order, then with the last response
+	srv.Close()
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+	for i, want := range []string{`["doc"]`, `["doc","doc-required"]`, `["doc","doc-required"]`} {
+		status, body, header := do(client, http.MethodGet, "")
+		if status != http.StatusOK || body != want || !strings.Contains(header.Get("Link"), `rel="next"`) {
+			t.Fatalf("replayed GET %d = %v %v %v, want %v", i, status, body, header, want)
+		}
+	}
+	if status, body, _ := do(client, http.MethodPost, `["doc-required"]`); status != http.StatusCreated || body != `["doc-required"]` {
+		t.Fatalf("replayed POST = %v %v", status, body)
+	}
+	if status, body, _ := do(client, http.MethodDelete, ""); status != http.StatusNotFound || !strings.Contains(body, "no recorded response for DELETE") {
+		t.Fatalf("replayed unrecorded DELETE = %v %v, want 404", status, body)
+	}
+}
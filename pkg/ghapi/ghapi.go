@@ -0,0 +1,140 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ghapi isolates the go-github version used by the action, so that
+// upgrading the library touches only this file.
+package ghapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v79/github"
+)
+
+type (
+	Client                   = github.Client
+	Response                 = github.Response
+	ListOptions              = github.ListOptions
+	PullRequest              = github.PullRequest
+	PullRequestListOptions   = github.PullRequestListOptions
+	PullRequestEvent         = github.PullRequestEvent
+	IssueCommentEvent        = github.IssueCommentEvent
+	IssuesEvent              = github.IssuesEvent
+	PushEvent                = github.PushEvent
+	PullRequestReviewEvent   = github.PullRequestReviewEvent
+	IssueComment             = github.IssueComment
+	IssueListCommentsOptions = github.IssueListCommentsOptions
+	Timeline                 = github.Timeline
+	CommitFile               = github.CommitFile
+	ReviewersRequest         = github.ReviewersRequest
+	RepositoryContent        = github.RepositoryContent
+	CreateCheckRunOptions    = github.CreateCheckRunOptions
+	CheckRunOutput           = github.CheckRunOutput
+	CheckRun                 = github.CheckRun
+	Label                    = github.Label
+	CheckRunAnnotation       = github.CheckRunAnnotation
+	ListCheckRunsOptions     = github.ListCheckRunsOptions
+	ListReactionOptions      = github.ListReactionOptions
+	Issue                    = github.Issue
+	IssueRequest             = github.IssueRequest
+	IssueListByRepoOptions   = github.IssueListByRepoOptions
+	SearchOptions            = github.SearchOptions
+	PullRequestReview        = github.PullRequestReview
+	PullRequestReviewRequest = github.PullRequestReviewRequest
+	RepositoryCommit         = github.RepositoryCommit
+	User                     = github.User
+	ErrorResponse            = github.ErrorResponse
+	RateLimitError           = github.RateLimitError
+
+	PullRequestReviewDismissalRequest = github.PullRequestReviewDismissalRequest
+	RepositoryDispatchEvent           = github.RepositoryDispatchEvent
+	TeamListTeamMembersOptions        = github.TeamListTeamMembersOptions
+	AddProjectItemOptions             = github.AddProjectItemOptions
+	UpdateProjectItemOptions          = github.UpdateProjectItemOptions
+	UpdateProjectV2Field              = github.UpdateProjectV2Field
+	CreateRef                         = github.CreateRef
+	RepositoryContentFileOptions      = github.RepositoryContentFileOptions
+	NewPullRequest                    = github.NewPullRequest
+	Installation                      = github.Installation
+	InstallationToken                 = github.InstallationToken
+	InstallationEvent                 = github.InstallationEvent
+	InstallationRepositoriesEvent     = github.InstallationRepositoriesEvent
+	Repository                        = github.Repository
+)
+
+func NewClient(httpClient *http.Client) *Client {
+	return github.NewClient(httpClient)
+}
+
+// String returns a pointer to v, as the API structs take optional fields by pointer.
+func String(v string) *string {
+	return github.Ptr(v)
+}
+
+// Int returns a pointer to v.
+func Int(v int) *int {
+	return github.Ptr(v)
+}
+
+func ValidatePayload(r *http.Request, secretToken []byte) ([]byte, error) {
+	return github.ValidatePayload(r, secretToken)
+}
+
+func ParseWebHook(messageType string, payload []byte) (interface{}, error) {
+	return github.ParseWebHook(messageType, payload)
+}
+
+func WebHookType(r *http.Request) string {
+	return github.WebHookType(r)
+}
+
+func DeliveryID(r *http.Request) string {
+	return github.DeliveryID(r)
+}
+
+// GraphQL sends a GraphQL query to GitHub and decodes the data field into out if not nil.
+func GraphQL(ctx context.Context, client *Client, query string, variables map[string]interface{}, out interface{}) error {
+	url := os.Getenv("GITHUB_GRAPHQL_URL")
+	if len(url) == 0 {
+		url = "https://api.github.com/graphql"
+	}
+
+	req, err := client.NewRequest("POST", url, map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := &struct {
+		Data   interface{} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{Data: out}
+	if _, err := client.Do(ctx, req, result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("graphql: %v", result.Errors[0].Message)
+	}
+	return nil
+}
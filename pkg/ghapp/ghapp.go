@@ -0,0 +1,201 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ghapp authenticates as a GitHub App and as its installations, caching the installation
+// tokens until shortly before they expire, so that one deployment can serve all the installations.
+package ghapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+)
+
+// tokenRefreshMargin is how long before its expiry an installation token is renewed.
+const tokenRefreshMargin = 5 * time.Minute
+
+// App is a GitHub App. It is safe for concurrent use.
+type App struct {
+	id  int64
+	key *rsa.PrivateKey
+	// newClient creates a client authenticated with token, a JWT or an installation token
+	newClient func(token string) *ghapi.Client
+
+	mu     sync.Mutex
+	tokens map[int64]*ghapi.InstallationToken
+}
+
+// New creates the App id signing its JWTs with the PEM encoded private key, PKCS #1 as generated by GitHub, or PKCS #8.
+func New(id int64, privateKey []byte, newClient func(token string) *ghapi.Client) (*App, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, perr := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if perr != nil {
+			return nil, fmt.Errorf("parse private key: %w", err)
+		}
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("private key is not an RSA key")
+		}
+	}
+
+	return &App{
+		id:        id,
+		key:       key,
+		newClient: newClient,
+		tokens:    make(map[int64]*ghapi.InstallationToken),
+	}, nil
+}
+
+// JWT returns a token authenticating as the App, valid for 9 minutes. It is issued a minute in the past
+// to allow for clock drift, as GitHub recommends.
+func (a *App) JWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.id, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appClient returns a client authenticated as the App.
+func (a *App) appClient() (*ghapi.Client, error) {
+	jwt, err := a.JWT(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign JWT: %w", err)
+	}
+	return a.newClient(jwt), nil
+}
+
+// Installations lists the installations of the App.
+func (a *App) Installations(ctx context.Context) ([]*ghapi.Installation, error) {
+	client, err := a.appClient()
+	if err != nil {
+		return nil, err
+	}
+
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	installations := make([]*ghapi.Installation, 0)
+	for {
+		i, resp, err := client.Apps.ListInstallations(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, i...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return installations, nil
+}
+
+// BotLogin returns the login of the bot user the installations of the App act as, like "docbot[bot]".
+func (a *App) BotLogin(ctx context.Context) (string, error) {
+	client, err := a.appClient()
+	if err != nil {
+		return "", err
+	}
+	app, _, err := client.Apps.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return app.GetSlug() + "[bot]", nil
+}
+
+// Token returns a token of the installation id, creating one if the cached one is about to expire.
+func (a *App) Token(ctx context.Context, id int64) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if token, exist := a.tokens[id]; exist && time.Until(token.GetExpiresAt().Time) > tokenRefreshMargin {
+		return token.GetToken(), nil
+	}
+
+	client, err := a.appClient()
+	if err != nil {
+		return "", err
+	}
+	token, _, err := client.Apps.CreateInstallationToken(ctx, id, nil)
+	if err != nil {
+		return "", fmt.Errorf("create token of installation %d: %w", id, err)
+	}
+	a.tokens[id] = token
+	return token.GetToken(), nil
+}
+
+// Client returns a client authenticated as the installation id.
+func (a *App) Client(ctx context.Context, id int64) (*ghapi.Client, error) {
+	token, err := a.Token(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return a.newClient(token), nil
+}
+
+// Repositories lists the repositories the installation id can access.
+func (a *App) Repositories(ctx context.Context, id int64) ([]*ghapi.Repository, error) {
+	client, err := a.Client(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	repos := make([]*ghapi.Repository, 0)
+	for {
+		r, resp, err := client.Apps.ListRepos(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, r.Repositories...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return repos, nil
+}
@@ -0,0 +1,196 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ghapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/ghtest"
+)
+
+// bearerTransport authenticates the requests with a static token.
+type bearerTransport string
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestApp(t *testing.T, s *ghtest.Server) (*App, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app, err := New(7, pemKey, func(token string) *ghapi.Client {
+		client := ghapi.NewClient(&http.Client{Transport: bearerTransport(token)})
+		if s != nil {
+			client.BaseURL, _ = url.Parse(s.BaseURL())
+		}
+		return client
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return app, key
+}
+
+func TestNew(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal PKCS #8: %v", err)
+	}
+	if _, err := New(7, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), nil); err != nil {
+		t.Errorf("New with a PKCS #8 key: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, key, err string
+	}{
+		{"not PEM", "not a key", "not PEM encoded"},
+		{"not a key", string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("garbage")})), "parse private key"},
+	} {
+		if _, err := New(7, []byte(tt.key), nil); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("New with %v: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestJWT(t *testing.T) {
+	app, key := newTestApp(t, nil)
+	now := time.Unix(1700000000, 0)
+	jwt, err := app.JWT(now)
+	if err != nil {
+		t.Fatalf("JWT: %v", err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT %q has %d parts, want 3", jwt, len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("verify signature: %v", err)
+	}
+
+	var header map[string]string
+	decodeSegment(t, parts[0], &header)
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		t.Errorf("header = %v, want RS256 JWT", header)
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	decodeSegment(t, parts[1], &claims)
+	if claims.Iss != "7" || claims.Iat != now.Unix()-60 || claims.Exp != now.Unix()+540 {
+		t.Errorf("claims = %+v, want iss 7 issued a minute ago expiring in 9 minutes", claims)
+	}
+}
+
+func decodeSegment(t *testing.T, segment string, v interface{}) {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		t.Fatalf("decode %q: %v", segment, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar")
+	defer s.Close()
+	s.AddInstallation(42)
+	app, _ := newTestApp(t, s)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		token, err := app.Token(ctx, 42)
+		if err != nil || token != "ghs_installation42" {
+			t.Fatalf("Token = %q, %v, want ghs_installation42", token, err)
+		}
+	}
+	requests := s.TokenRequests(42)
+	if len(requests) != 1 {
+		t.Fatalf("token requests = %v, want 1, the token is cached", requests)
+	}
+	if !strings.HasPrefix(requests[0], "Bearer ey") {
+		t.Errorf("token request authorized with %q, want the JWT", requests[0])
+	}
+
+	// a token about to expire is renewed
+	app.tokens[42].ExpiresAt.Time = time.Now().Add(tokenRefreshMargin - time.Second)
+	if _, err := app.Token(ctx, 42); err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if requests := s.TokenRequests(42); len(requests) != 2 {
+		t.Fatalf("token requests = %v, want 2, the token is renewed", requests)
+	}
+
+	if _, err := app.Token(ctx, 43); err == nil || !strings.Contains(err.Error(), "installation 43") {
+		t.Errorf("Token of an unknown installation: err = %v", err)
+	}
+}
+
+func TestInstallations(t *testing.T) {
+	s := ghtest.NewServer("apache", "pulsar")
+	defer s.Close()
+	s.AddInstallation(42)
+	app, _ := newTestApp(t, s)
+	ctx := context.Background()
+
+	installations, err := app.Installations(ctx)
+	if err != nil || len(installations) != 1 || installations[0].GetID() != 42 {
+		t.Fatalf("Installations = %v, %v, want installation 42", installations, err)
+	}
+	repos, err := app.Repositories(ctx, 42)
+	if err != nil || len(repos) != 1 || repos[0].GetFullName() != "apache/pulsar" {
+		t.Fatalf("Repositories = %v, %v, want apache/pulsar", repos, err)
+	}
+	login, err := app.BotLogin(ctx)
+	if err != nil || login != "github-actions[bot]" {
+		t.Fatalf("BotLogin = %q, %v, want github-actions[bot]", login, err)
+	}
+}
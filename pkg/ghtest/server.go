@@ -0,0 +1,1452 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ghtest provides an in-memory emulation of the subset of the GitHub REST API
+// used by the labeler, for exercising end-to-end flows without network access or tokens.
+package ghtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Label struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+type User struct {
+	Login string `json:"login"`
+	Type  string `json:"type,omitempty"`
+}
+
+type Comment struct {
+	ID     int64  `json:"id"`
+	NodeID string `json:"node_id"`
+	Body   string `json:"body"`
+	User   User   `json:"user"`
+	// CreatedAt is when the comment was created, see Backdate
+	CreatedAt time.Time `json:"created_at"`
+	// Minimized is set by the minimizeComment mutation
+	Minimized bool `json:"-"`
+}
+
+type Reaction struct {
+	ID      int64  `json:"id"`
+	Content string `json:"content"`
+	User    User   `json:"user"`
+}
+
+type TimelineEvent struct {
+	Event     string    `json:"event"`
+	Actor     User      `json:"actor"`
+	Label     Label     `json:"label"`
+	CreatedAt time.Time `json:"created_at"`
+	// Source is the PR referencing this one, on cross-referenced events
+	Source *Source `json:"source,omitempty"`
+}
+
+// Source is the issue or PR of a cross-referenced event.
+type Source struct {
+	Issue struct {
+		Number      int               `json:"number"`
+		PullRequest map[string]string `json:"pull_request,omitempty"`
+	} `json:"issue"`
+}
+
+type Issue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+}
+
+type PullRequest struct {
+	ID                int64   `json:"id"`
+	Number            int     `json:"number"`
+	Title             string  `json:"title"`
+	Body              string  `json:"body"`
+	State             string  `json:"state"`
+	Locked            bool    `json:"locked"`
+	User              User    `json:"user"`
+	AuthorAssociation string  `json:"author_association,omitempty"`
+	Labels            []Label `json:"labels"`
+	Assignees         []User  `json:"assignees"`
+	// RequestedReviewers are the users whose review is requested and pending
+	RequestedReviewers []User `json:"requested_reviewers"`
+	Head               struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+	CreatedAt time.Time `json:"created_at"`
+	// Mergeable is null until GitHub computed the mergeability, MergeableState is "dirty" on conflicts
+	Mergeable      *bool  `json:"mergeable"`
+	MergeableState string `json:"mergeable_state,omitempty"`
+	Merged         bool   `json:"merged"`
+}
+
+type Review struct {
+	ID    int64  `json:"id"`
+	Body  string `json:"body"`
+	State string `json:"state"`
+	User  User   `json:"user"`
+}
+
+type CheckRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	HeadSHA    string `json:"head_sha"`
+	Conclusion string `json:"conclusion"`
+	ExternalID string `json:"external_id,omitempty"`
+	// App is the app that created the check run
+	App struct {
+		Slug string `json:"slug"`
+	} `json:"app"`
+	Output struct {
+		Title       string       `json:"title"`
+		Annotations []Annotation `json:"annotations"`
+	} `json:"output"`
+}
+
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Level     string `json:"annotation_level"`
+	Message   string `json:"message"`
+}
+
+// Server is a fake GitHub serving a single repository. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	Owner string
+	Repo  string
+
+	mu           sync.Mutex
+	repoLabels   []string
+	labelMeta    map[string]Label
+	pullRequests map[int]*PullRequest
+	comments     map[int][]*Comment
+	timeline     map[int][]*TimelineEvent
+	reviews      map[int][]*Review
+	teamMembers  map[string][]User
+	projects     map[string]*project
+	checkRuns    []*CheckRun
+	files        map[string]string
+	changedFiles map[int][]string
+	branches     map[string]string
+	mutations    int
+	requests     map[string]int
+	// patches are the unified diffs of the changed files of each PR by path
+	patches map[int]map[string]string
+	// permissions are the repository permissions of the users, read by default
+	permissions map[string]string
+	// reactions are the contents of the reactions on each comment
+	reactions map[int64][]string
+	// prReactions are the reactions on the description of each PR
+	prReactions map[int][]*Reaction
+	// installations maps the installation ids to the Authorization headers of their token requests
+	installations map[int64][]string
+	failures      map[string]int
+	nextID        int64
+	// issues are the issues other than PRs by repository, like owner/repo
+	issues map[string][]*Issue
+	// tokenUser is the user of the token, if a personal access token: GET /user is forbidden otherwise
+	tokenUser *User
+}
+
+// botUser is the actor of all changes made through the API with an installation token.
+var botUser = User{Login: "github-actions[bot]", Type: "Bot"}
+
+// NewServer starts a fake GitHub for owner/repo with the given repo labels.
+func NewServer(owner, repo string, repoLabels ...string) *Server {
+	s := &Server{
+		Owner:         owner,
+		Repo:          repo,
+		repoLabels:    repoLabels,
+		pullRequests:  make(map[int]*PullRequest),
+		comments:      make(map[int][]*Comment),
+		timeline:      make(map[int][]*TimelineEvent),
+		reviews:       make(map[int][]*Review),
+		teamMembers:   make(map[string][]User),
+		projects:      make(map[string]*project),
+		labelMeta:     make(map[string]Label),
+		installations: make(map[int64][]string),
+		files:         make(map[string]string),
+		changedFiles:  make(map[int][]string),
+		patches:       make(map[int]map[string]string),
+		permissions:   make(map[string]string),
+		reactions:     make(map[int64][]string),
+		prReactions:   make(map[int][]*Reaction),
+		branches:      map[string]string{"master": fmt.Sprintf("%040d", 0)},
+		requests:      make(map[string]int),
+		failures:      make(map[string]int),
+		issues:        make(map[string][]*Issue),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// BaseURL is the API root to configure the client with.
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+// AddPullRequest creates an open PR authored by author.
+func (s *Server) AddPullRequest(number int, author, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr := &PullRequest{ID: int64(1000 + number), Number: number, Body: body, State: "open", User: User{Login: author}, Labels: []Label{}}
+	pr.Head.SHA = fmt.Sprintf("%040d", number)
+	s.pullRequests[number] = pr
+}
+
+// SetCreatedAt sets when a PR was opened.
+func (s *Server) SetCreatedAt(number int, createdAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].CreatedAt = createdAt
+}
+
+// SetBody replaces the body of a PR, as if edited by the author.
+func (s *Server) SetBody(number int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].Body = body
+}
+
+// Body returns the current body of a PR, or empty if it doesn't exist.
+func (s *Server) Body(number int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pullRequests[number] == nil {
+		return ""
+	}
+	return s.pullRequests[number].Body
+}
+
+// SetTitle replaces the title of a PR, as if edited by the author.
+func (s *Server) SetTitle(number int, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].Title = title
+}
+
+// Title returns the current title of a PR.
+func (s *Server) Title(number int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pullRequests[number].Title
+}
+
+// AddReview submits a review of a PR by user, with state like APPROVED.
+func (s *Server) AddReview(number int, user, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.reviews[number] = append(s.reviews[number], &Review{ID: s.nextID, State: state, User: User{Login: user}})
+}
+
+// AddReviewBody submits a review of a PR by user with a body, e.g. one embedding a marker of the bot.
+func (s *Server) AddReviewBody(number int, user, state, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.reviews[number] = append(s.reviews[number], &Review{ID: s.nextID, Body: body, State: state, User: User{Login: user}})
+}
+
+// Reviews returns the reviews of a PR.
+func (s *Server) Reviews(number int) []Review {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reviews := []Review{}
+	for _, review := range s.reviews[number] {
+		reviews = append(reviews, *review)
+	}
+	return reviews
+}
+
+// AddTeamMember adds user to the team of the org, as "org/slug".
+func (s *Server) AddTeamMember(team, user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.teamMembers[team] = append(s.teamMembers[team], User{Login: user})
+}
+
+// project is a Projects v2 board of an org, whose columns are the options of its Status field.
+type project struct {
+	columns []string
+	// status of the items, keyed by the id of their PR
+	status map[int64]string
+}
+
+// projectStatusFieldID is the id of the Status field of every project.
+const projectStatusFieldID = 1
+
+// AddProject creates the project of an org, as "org/number", with the given columns.
+func (s *Server) AddProject(name string, columns ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects[name] = &project{columns: columns, status: make(map[int64]string)}
+}
+
+// ProjectStatus returns the column of a PR in a project, or empty if it's not an item of the project.
+func (s *Server) ProjectStatus(name string, number int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.projects[name].status[s.pullRequests[number].ID]
+}
+
+// handleIssues serves the issues other than PRs of repo, reporting whether the request was one.
+// Any repository has issues, so that the action can track work in another one.
+func (s *Server) handleIssues(w http.ResponseWriter, r *http.Request, repo string, parts []string) bool {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		issues := []*Issue{}
+		for _, issue := range s.issues[repo] {
+			if issue.State == "open" {
+				issues = append(issues, issue)
+			}
+		}
+		writeJSON(w, http.StatusOK, issues)
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		issue := &Issue{}
+		if !readJSON(w, r, issue) {
+			return true
+		}
+		// the issues of the served repository are numbered after its PRs
+		issue.Number = len(s.issues[repo]) + 1
+		if repo == s.Owner+"/"+s.Repo {
+			issue.Number += len(s.pullRequests)
+		}
+		issue.State = "open"
+		issue.HTMLURL = fmt.Sprintf("https://github.com/%s/issues/%d", repo, issue.Number)
+		s.issues[repo] = append(s.issues[repo], issue)
+		writeJSON(w, http.StatusCreated, issue)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		for _, issue := range s.issues[repo] {
+			if fmt.Sprint(issue.Number) != parts[0] {
+				continue
+			}
+			edit := &struct {
+				Title *string `json:"title"`
+				Body  *string `json:"body"`
+				State *string `json:"state"`
+			}{}
+			if !readJSON(w, r, edit) {
+				return true
+			}
+			if edit.Title != nil {
+				issue.Title = *edit.Title
+			}
+			if edit.Body != nil {
+				issue.Body = *edit.Body
+			}
+			if edit.State != nil {
+				issue.State = *edit.State
+			}
+			writeJSON(w, http.StatusOK, issue)
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request, p *project, parts []string) {
+	switch {
+	case match(parts, "fields") && r.Method == http.MethodGet:
+		options := []map[string]interface{}{}
+		for i, column := range p.columns {
+			options = append(options, map[string]interface{}{"id": strconv.Itoa(i), "name": map[string]string{"raw": column}})
+		}
+		writeJSON(w, http.StatusOK, []map[string]interface{}{
+			{"id": 0, "name": "Title", "data_type": "title"},
+			{"id": projectStatusFieldID, "name": "Status", "data_type": "single_select", "options": options},
+		})
+	case match(parts, "items") && r.Method == http.MethodPost:
+		var req struct {
+			Type string `json:"type"`
+			ID   int64  `json:"id"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if _, exist := p.status[req.ID]; !exist {
+			p.status[req.ID] = ""
+		}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"id": req.ID, "content_type": req.Type})
+	case match(parts, "items", "*") && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		if _, exist := p.status[id]; !exist {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		var req struct {
+			Fields []struct {
+				ID    int64  `json:"id"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, field := range req.Fields {
+			i, err := strconv.Atoi(field.Value)
+			if field.ID != projectStatusFieldID || err != nil || i < 0 || i >= len(p.columns) {
+				writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+				return
+			}
+			p.status[id] = p.columns[i]
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// Branches returns the names of the branches of the repository.
+func (s *Server) Branches() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	branches := []string{}
+	for branch := range s.branches {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+// State returns the state of a PR, open or closed.
+func (s *Server) State(number int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pullRequests[number].State
+}
+
+// SetLabelMeta sets the description and color of the repo label name.
+func (s *Server) SetLabelMeta(name, description, color string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labelMeta[name] = Label{Name: name, Description: description, Color: color}
+}
+
+// LabelMeta returns the repo label name with its description and color.
+func (s *Server) LabelMeta(name string) Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repoLabel(name)
+}
+
+func (s *Server) repoLabel(name string) Label {
+	if l, exist := s.labelMeta[name]; exist {
+		return l
+	}
+	return Label{Name: name}
+}
+
+// AddInstallation installs the GitHub App on the repository as installation id.
+func (s *Server) AddInstallation(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.installations[id] = []string{}
+}
+
+// TokenRequests returns the Authorization headers of the token requests of the installation id.
+func (s *Server) TokenRequests(id int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.installations[id]...)
+}
+
+// SetState sets the state of a PR, open or closed.
+func (s *Server) SetState(number int, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].State = state
+}
+
+// AddFile adds a file at path to the default branch.
+func (s *Server) AddFile(path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = content
+}
+
+// CheckRuns returns the check runs created so far.
+func (s *Server) CheckRuns() []CheckRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkRuns := []CheckRun{}
+	for _, c := range s.checkRuns {
+		checkRuns = append(checkRuns, *c)
+	}
+	return checkRuns
+}
+
+// AddCheckRun creates a check run on a commit as the app with slug, like another app of the repo.
+func (s *Server) AddCheckRun(headSHA, name, externalID, slug string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkRun := &CheckRun{Name: name, HeadSHA: headSHA, Conclusion: "neutral", ExternalID: externalID}
+	checkRun.App.Slug = slug
+	s.checkRuns = append(s.checkRuns, checkRun)
+	checkRun.ID = int64(len(s.checkRuns))
+}
+
+// SetMerged closes a PR as merged.
+func (s *Server) SetMerged(number int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].State = "closed"
+	s.pullRequests[number].Merged = true
+}
+
+// SetLocked locks or unlocks the conversation of a PR.
+func (s *Server) SetLocked(number int, locked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].Locked = locked
+}
+
+// SetChangedFiles sets the paths of the files changed by a PR.
+func (s *Server) SetChangedFiles(number int, paths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changedFiles[number] = paths
+}
+
+// SetPatch sets the unified diff of a file changed by a PR, adding it to the changed files.
+func (s *Server) SetPatch(number int, path, patch string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.patches[number] == nil {
+		s.patches[number] = make(map[string]string)
+	}
+	if _, exist := s.patches[number][path]; !exist {
+		s.changedFiles[number] = append(s.changedFiles[number], path)
+	}
+	s.patches[number][path] = patch
+}
+
+// SetMergeable sets the mergeability of a PR, with state "dirty" on conflicts.
+func (s *Server) SetMergeable(number int, mergeable bool, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].Mergeable = &mergeable
+	s.pullRequests[number].MergeableState = state
+}
+
+// Backdate moves the timeline events and the comments of a PR d into the past, as if they happened earlier.
+func (s *Server) Backdate(number int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range s.timeline[number] {
+		event.CreatedAt = event.CreatedAt.Add(-d)
+	}
+	for _, comment := range s.comments[number] {
+		comment.CreatedAt = comment.CreatedAt.Add(-d)
+	}
+}
+
+// SetAuthorAssociation sets the author_association of a PR, e.g. MEMBER.
+func (s *Server) SetAuthorAssociation(number int, association string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pullRequests[number].AuthorAssociation = association
+}
+
+// SetLabels replaces the labels of a PR, as if changed by the maintainer.
+func (s *Server) SetLabels(number int, labels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maintainer := User{Login: "maintainer", Type: "User"}
+	pr := s.pullRequests[number]
+	for _, l := range pr.Labels {
+		s.addEvent(number, "unlabeled", maintainer, l.Name)
+	}
+	pr.Labels = []Label{}
+	for _, l := range labels {
+		pr.Labels = append(pr.Labels, Label{Name: l})
+		s.addEvent(number, "labeled", maintainer, l)
+	}
+}
+
+func (s *Server) addEvent(number int, event string, actor User, label string) {
+	s.timeline[number] = append(s.timeline[number], &TimelineEvent{
+		Event:     event,
+		Actor:     actor,
+		Label:     Label{Name: label},
+		CreatedAt: time.Now(),
+	})
+}
+
+// AddCrossReference references a PR from the PR source, as when its description mentions it.
+func (s *Server) AddCrossReference(number, source int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event := &TimelineEvent{Event: "cross-referenced", CreatedAt: time.Now()}
+	event.Source = &Source{}
+	event.Source.Issue.Number = source
+	event.Source.Issue.PullRequest = map[string]string{"url": fmt.Sprintf("%s/repos/%s/%s/pulls/%d", s.URL, s.Owner, s.Repo, source)}
+	s.timeline[number] = append(s.timeline[number], event)
+}
+
+// Issues returns the issues other than PRs of repo, like owner/repo, which may be another repository than the served one.
+func (s *Server) Issues(repo string) []Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issues := []Issue{}
+	for _, issue := range s.issues[repo] {
+		issues = append(issues, *issue)
+	}
+	return issues
+}
+
+// Assignees returns the logins assigned to a PR.
+func (s *Server) Assignees(number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logins := []string{}
+	for _, u := range s.pullRequests[number].Assignees {
+		logins = append(logins, u.Login)
+	}
+	return logins
+}
+
+// RequestedReviewers returns the logins whose review is requested on a PR.
+func (s *Server) RequestedReviewers(number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logins := []string{}
+	for _, u := range s.pullRequests[number].RequestedReviewers {
+		logins = append(logins, u.Login)
+	}
+	return logins
+}
+
+// Labels returns the label names of a PR.
+func (s *Server) Labels(number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := []string{}
+	for _, l := range s.pullRequests[number].Labels {
+		labels = append(labels, l.Name)
+	}
+	return labels
+}
+
+// AddComment adds a comment of user to a PR, and returns its ID.
+func (s *Server) AddComment(number int, user, body string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.comments[number] = append(s.comments[number], &Comment{ID: s.nextID, NodeID: fmt.Sprintf("IC_%d", s.nextID), Body: body,
+		User: User{Login: user, Type: "User"}, CreatedAt: time.Now()})
+	return s.nextID
+}
+
+// MinimizedComments returns the IDs of the minimized comments on a PR.
+func (s *Server) MinimizedComments(number int) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []int64{}
+	for _, c := range s.comments[number] {
+		if c.Minimized {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// SetPermission sets the repository permission of user, e.g. write.
+func (s *Server) SetPermission(user, permission string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.permissions[user] = permission
+}
+
+// SetTokenUser makes the requests authenticate as the user login, as with a personal access token.
+func (s *Server) SetTokenUser(login string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenUser = &User{Login: login, Type: "User"}
+}
+
+// actor is the user changes made through the API are attributed to.
+func (s *Server) actor() User {
+	if s.tokenUser != nil {
+		return *s.tokenUser
+	}
+	return botUser
+}
+
+// AddReaction reacts with content on the description of a PR as user.
+func (s *Server) AddReaction(number int, user User, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.prReactions[number] = append(s.prReactions[number], &Reaction{ID: s.nextID, Content: content, User: user})
+}
+
+// Reactions returns the reactions on the description of a PR.
+func (s *Server) Reactions(number int) []Reaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reactions := []Reaction{}
+	for _, r := range s.prReactions[number] {
+		reactions = append(reactions, *r)
+	}
+	return reactions
+}
+
+// CommentReactions returns the contents of the reactions on a comment.
+func (s *Server) CommentReactions(id int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.reactions[id]...)
+}
+
+// Comments returns the comment bodies of a PR.
+func (s *Server) Comments(number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bodies := []string{}
+	for _, c := range s.comments[number] {
+		bodies = append(bodies, c.Body)
+	}
+	return bodies
+}
+
+// Fail makes the next times requests of method to path, relative to the repository like "issues/1/labels",
+// fail with 502 Bad Gateway.
+func (s *Server) Fail(method, path string, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method+" "+path] = times
+}
+
+// Requests returns the number of requests received so far for method to path, relative to the repository like "labels".
+func (s *Server) Requests(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method+" "+path]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if parts := strings.Split(r.URL.Path, "/"); match(parts, "", "orgs", "*", "teams", "*", "members") {
+		members := s.teamMembers[parts[2]+"/"+parts[4]]
+		if members == nil {
+			members = []User{}
+		}
+		writeJSON(w, http.StatusOK, members)
+		return
+	}
+
+	if parts := strings.Split(r.URL.Path, "/"); match(parts, "", "orgs", "*", "teams", "*", "memberships", "*") {
+		for _, member := range s.teamMembers[parts[2]+"/"+parts[4]] {
+			if member.Login == parts[6] {
+				writeJSON(w, http.StatusOK, map[string]string{"state": "active", "role": "member"})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	if parts := strings.Split(r.URL.Path, "/"); len(parts) > 5 && match(parts[:5], "", "orgs", "*", "projectsV2", "*") {
+		p := s.projects[parts[2]+"/"+parts[4]]
+		if p == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.handleProject(w, r, p, parts[5:])
+		return
+	}
+
+	if r.URL.Path == "/user" && r.Method == http.MethodGet {
+		if s.tokenUser == nil {
+			writeError(w, http.StatusForbidden, "Resource not accessible by integration")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.tokenUser)
+		return
+	}
+
+	if r.URL.Path == "/app" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 1, "slug": strings.TrimSuffix(botUser.Login, "[bot]")})
+		return
+	}
+
+	if r.URL.Path == "/app/installations" && r.Method == http.MethodGet {
+		installations := []map[string]int64{}
+		for id := range s.installations {
+			installations = append(installations, map[string]int64{"id": id})
+		}
+		writeJSON(w, http.StatusOK, installations)
+		return
+	}
+
+	if parts := strings.Split(r.URL.Path, "/"); match(parts, "", "app", "installations", "*", "access_tokens") && r.Method == http.MethodPost {
+		id, _ := strconv.ParseInt(parts[3], 10, 64)
+		if _, exist := s.installations[id]; !exist {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.installations[id] = append(s.installations[id], r.Header.Get("Authorization"))
+		writeJSON(w, http.StatusCreated, map[string]string{
+			"token":      fmt.Sprintf("ghs_installation%d", id),
+			"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if r.URL.Path == "/installation/repositories" && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"total_count": 1,
+			"repositories": []map[string]interface{}{{
+				"name":      s.Repo,
+				"full_name": s.Owner + "/" + s.Repo,
+				"owner":     map[string]string{"login": s.Owner},
+			}},
+		})
+		return
+	}
+
+	if r.URL.Path == "/search/issues" && r.Method == http.MethodGet {
+		// Only the is:open and is:closed qualifiers are supported, other qualifiers match all PRs
+		qualifiers := strings.Fields(r.URL.Query().Get("q"))
+		items := []map[string]interface{}{}
+		for i := 1; i <= len(s.pullRequests); i++ {
+			pr, exist := s.pullRequests[i]
+			if !exist {
+				continue
+			}
+			matched := true
+			for _, q := range qualifiers {
+				if state := strings.TrimPrefix(q, "is:"); (state == "open" || state == "closed") && state != pr.State {
+					matched = false
+				}
+			}
+			if matched {
+				items = append(items, map[string]interface{}{
+					"number":       pr.Number,
+					"title":        pr.Title,
+					"state":        pr.State,
+					"user":         pr.User,
+					"created_at":   pr.CreatedAt,
+					"labels":       pr.Labels,
+					"pull_request": map[string]string{"url": fmt.Sprintf("%s/repos/%s/%s/pulls/%d", s.URL, s.Owner, s.Repo, pr.Number)},
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"total_count": len(items), "items": items})
+		return
+	}
+
+	if parts := strings.Split(r.URL.Path, "/"); len(parts) >= 5 && match(parts[:5], "", "repos", "*", "*", "issues") && s.handleIssues(w, r, parts[2]+"/"+parts[3], parts[5:]) {
+		return
+	}
+
+	if r.URL.Path == "/graphql" && r.Method == http.MethodPost {
+		s.handleGraphQL(w, r)
+		return
+	}
+
+	if r.URL.Path == fmt.Sprintf("/repos/%s/%s", s.Owner, s.Repo) && r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]string{"name": s.Repo, "full_name": s.Owner + "/" + s.Repo, "default_branch": "master"})
+		return
+	}
+
+	prefix := fmt.Sprintf("/repos/%s/%s/", s.Owner, s.Repo)
+	if !strings.HasPrefix(r.URL.EscapedPath(), prefix) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	key := r.Method + " " + strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+	s.requests[key]++
+	if s.failures[key] > 0 {
+		s.failures[key]--
+		writeError(w, http.StatusBadGateway, "Bad Gateway")
+		return
+	}
+	// Split the escaped path, so that label names may contain "/"
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+	for i := range parts {
+		parts[i], _ = url.PathUnescape(parts[i])
+	}
+
+	switch {
+	case match(parts, "labels") && r.Method == http.MethodGet:
+		labels := []Label{}
+		for _, l := range s.repoLabels {
+			labels = append(labels, s.repoLabel(l))
+		}
+		writeJSON(w, http.StatusOK, labels)
+	case match(parts, "labels", "*") && r.Method == http.MethodPatch:
+		exist := false
+		for _, l := range s.repoLabels {
+			exist = exist || l == parts[1]
+		}
+		if !exist {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		label := s.repoLabel(parts[1])
+		if !readJSON(w, r, &label) {
+			return
+		}
+		s.labelMeta[parts[1]] = label
+		writeJSON(w, http.StatusOK, label)
+	case match(parts, "pulls") && r.Method == http.MethodGet:
+		prs := []*PullRequest{}
+		for i := 1; i <= len(s.pullRequests); i++ {
+			if pr, exist := s.pullRequests[i]; exist && pr.State == "open" {
+				prs = append(prs, pr)
+			}
+		}
+		writeJSON(w, http.StatusOK, prs)
+	case match(parts, "pulls") && r.Method == http.MethodPost:
+		req := &struct {
+			Title string `json:"title"`
+			Head  string `json:"head"`
+			Base  string `json:"base"`
+			Body  string `json:"body"`
+		}{}
+		if !readJSON(w, r, req) {
+			return
+		}
+		if _, exist := s.branches[req.Head]; !exist {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		number := len(s.pullRequests) + 1
+		pr := &PullRequest{ID: int64(1000 + number), Number: number, Title: req.Title, Body: req.Body, State: "open",
+			User: s.actor(), Labels: []Label{}}
+		pr.Head.SHA = s.branches[req.Head]
+		s.pullRequests[number] = pr
+		writeJSON(w, http.StatusCreated, pr)
+	case len(parts) > 3 && match(parts[:3], "git", "ref", "heads") && r.Method == http.MethodGet:
+		branch := strings.Join(parts[3:], "/")
+		sha, exist := s.branches[branch]
+		if !exist {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ref": "refs/heads/" + branch, "object": map[string]string{"sha": sha, "type": "commit"}})
+	case match(parts, "git", "refs") && r.Method == http.MethodPost:
+		req := &struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		}{}
+		if !readJSON(w, r, req) {
+			return
+		}
+		s.branches[strings.TrimPrefix(req.Ref, "refs/heads/")] = req.SHA
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"ref": req.Ref, "object": map[string]string{"sha": req.SHA, "type": "commit"}})
+	case len(parts) > 3 && match(parts[:3], "git", "refs", "heads") && r.Method == http.MethodDelete:
+		branch := strings.Join(parts[3:], "/")
+		if _, exist := s.branches[branch]; !exist {
+			writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+			return
+		}
+		delete(s.branches, branch)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) > 1 && parts[0] == "contents" && r.Method == http.MethodPut:
+		req := &struct {
+			Message string `json:"message"`
+			Content []byte `json:"content"`
+			Branch  string `json:"branch"`
+		}{}
+		if !readJSON(w, r, req) {
+			return
+		}
+		if _, exist := s.branches[req.Branch]; !exist {
+			writeError(w, http.StatusNotFound, "Branch not found")
+			return
+		}
+		s.nextID++
+		s.branches[req.Branch] = fmt.Sprintf("%040d", s.nextID)
+		path := strings.Join(parts[1:], "/")
+		s.files[path] = string(req.Content)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"content": map[string]string{"path": path},
+			"commit":  map[string]string{"sha": s.branches[req.Branch], "message": req.Message},
+		})
+	case match(parts, "pulls", "*"):
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, pr)
+		case http.MethodPatch:
+			edit := &struct {
+				Title *string `json:"title"`
+				Body  *string `json:"body"`
+				State *string `json:"state"`
+			}{}
+			if !readJSON(w, r, edit) {
+				return
+			}
+			if edit.Title != nil {
+				pr.Title = *edit.Title
+			}
+			if edit.Body != nil {
+				pr.Body = *edit.Body
+			}
+			if edit.State != nil {
+				pr.State = *edit.State
+			}
+			writeJSON(w, http.StatusOK, pr)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+	case match(parts, "issues", "*", "labels"):
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, pr.Labels)
+		case http.MethodPost:
+			names := []string{}
+			if !readJSON(w, r, &names) {
+				return
+			}
+			for _, name := range names {
+				if !hasLabel(pr.Labels, name) {
+					pr.Labels = append(pr.Labels, Label{Name: name})
+					s.addEvent(pr.Number, "labeled", s.actor(), name)
+				}
+			}
+			writeJSON(w, http.StatusOK, pr.Labels)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+	case match(parts, "issues", "*") && r.Method == http.MethodGet:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"number":       pr.Number,
+			"title":        pr.Title,
+			"state":        pr.State,
+			"labels":       pr.Labels,
+			"pull_request": map[string]string{"url": fmt.Sprintf("%s/repos/%s/%s/pulls/%d", s.URL, s.Owner, s.Repo, pr.Number)},
+		})
+	case match(parts, "issues", "*", "assignees") && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		var req struct {
+			Assignees []string `json:"assignees"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, login := range req.Assignees {
+			assignees := []User{}
+			for _, u := range pr.Assignees {
+				if u.Login != login {
+					assignees = append(assignees, u)
+				}
+			}
+			if r.Method == http.MethodPost {
+				assignees = append(assignees, User{Login: login})
+			}
+			pr.Assignees = assignees
+		}
+		writeJSON(w, http.StatusCreated, pr)
+	case match(parts, "issues", "*", "labels", "*") && r.Method == http.MethodDelete:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		name, _ := url.PathUnescape(parts[3])
+		if !hasLabel(pr.Labels, name) {
+			writeError(w, http.StatusNotFound, "Label does not exist")
+			return
+		}
+		labels := []Label{}
+		for _, l := range pr.Labels {
+			if l.Name != name {
+				labels = append(labels, l)
+			}
+		}
+		pr.Labels = labels
+		s.addEvent(pr.Number, "unlabeled", s.actor(), name)
+		writeJSON(w, http.StatusOK, pr.Labels)
+	case match(parts, "issues", "comments", "*") && r.Method == http.MethodDelete:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for number, comments := range s.comments {
+			for i, c := range comments {
+				if c.ID == id {
+					s.comments[number] = append(comments[:i:i], comments[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+	case match(parts, "issues", "comments", "*") && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		edit := &Comment{}
+		if !readJSON(w, r, edit) {
+			return
+		}
+		for _, comments := range s.comments {
+			for _, c := range comments {
+				if c.ID == id {
+					c.Body = edit.Body
+					writeJSON(w, http.StatusOK, c)
+					return
+				}
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+	case match(parts, "issues", "comments", "*", "reactions") && r.Method == http.MethodPost:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		reaction := &struct {
+			Content string `json:"content"`
+		}{}
+		if !readJSON(w, r, reaction) {
+			return
+		}
+		s.reactions[id] = append(s.reactions[id], reaction.Content)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"id": id, "content": reaction.Content, "user": s.actor()})
+	case match(parts, "issues", "*", "reactions"):
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, append([]*Reaction{}, s.prReactions[pr.Number]...))
+		case http.MethodPost:
+			reaction := &Reaction{}
+			if !readJSON(w, r, reaction) {
+				return
+			}
+			s.nextID++
+			reaction.ID = s.nextID
+			reaction.User = s.actor()
+			s.prReactions[pr.Number] = append(s.prReactions[pr.Number], reaction)
+			writeJSON(w, http.StatusCreated, reaction)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+	case match(parts, "collaborators", "*", "permission") && r.Method == http.MethodGet:
+		permission, ok := s.permissions[parts[1]]
+		if !ok {
+			permission = "read"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"permission": permission, "user": User{Login: parts[1]}})
+	case match(parts, "issues", "*", "comments"):
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			comments := s.comments[pr.Number]
+			if comments == nil {
+				comments = []*Comment{}
+			}
+			writeJSON(w, http.StatusOK, comments)
+		case http.MethodPost:
+			comment := &Comment{}
+			if !readJSON(w, r, comment) {
+				return
+			}
+			s.nextID++
+			comment.ID = s.nextID
+			comment.NodeID = fmt.Sprintf("IC_%d", s.nextID)
+			comment.User = s.actor()
+			comment.CreatedAt = time.Now()
+			s.comments[pr.Number] = append(s.comments[pr.Number], comment)
+			writeJSON(w, http.StatusCreated, comment)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+	case match(parts, "issues", "*", "events") && r.Method == http.MethodGet:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		// the issue events are the timeline events without the comments and reviews
+		writeJSON(w, http.StatusOK, append([]*TimelineEvent{}, s.timeline[pr.Number]...))
+	case match(parts, "issues", "*", "timeline") && r.Method == http.MethodGet:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		timeline := s.timeline[pr.Number]
+		if timeline == nil {
+			timeline = []*TimelineEvent{}
+		}
+		writeJSON(w, http.StatusOK, timeline)
+	case match(parts, "pulls", "*", "files") && r.Method == http.MethodGet:
+		if pr := s.pullRequest(w, parts[1]); pr != nil {
+			files := []map[string]string{}
+			for _, path := range s.changedFiles[pr.Number] {
+				files = append(files, map[string]string{"filename": path, "patch": s.patches[pr.Number][path]})
+			}
+			writeJSON(w, http.StatusOK, files)
+		}
+	case match(parts, "pulls", "*", "requested_reviewers") && r.Method == http.MethodPost:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		var req struct {
+			Reviewers []string `json:"reviewers"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, login := range req.Reviewers {
+			pr.RequestedReviewers = append(pr.RequestedReviewers, User{Login: login})
+		}
+		writeJSON(w, http.StatusCreated, pr)
+	case match(parts, "pulls", "*", "reviews") && r.Method == http.MethodGet:
+		if pr := s.pullRequest(w, parts[1]); pr != nil {
+			reviews := s.reviews[pr.Number]
+			if reviews == nil {
+				reviews = []*Review{}
+			}
+			writeJSON(w, http.StatusOK, reviews)
+		}
+	case match(parts, "pulls", "*", "reviews") && r.Method == http.MethodPost:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		var req struct {
+			Body  string `json:"body"`
+			Event string `json:"event"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		states := map[string]string{"APPROVE": "APPROVED", "REQUEST_CHANGES": "CHANGES_REQUESTED", "COMMENT": "COMMENTED"}
+		s.nextID++
+		review := &Review{ID: s.nextID, Body: req.Body, State: states[req.Event], User: s.actor()}
+		s.reviews[pr.Number] = append(s.reviews[pr.Number], review)
+		writeJSON(w, http.StatusOK, review)
+	case match(parts, "pulls", "*", "reviews", "*", "dismissals") && r.Method == http.MethodPut:
+		pr := s.pullRequest(w, parts[1])
+		if pr == nil {
+			return
+		}
+		for _, review := range s.reviews[pr.Number] {
+			if fmt.Sprint(review.ID) == parts[3] {
+				review.State = "DISMISSED"
+				writeJSON(w, http.StatusOK, review)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+	case match(parts, "check-runs") && r.Method == http.MethodPost:
+		checkRun := &CheckRun{}
+		if !readJSON(w, r, checkRun) {
+			return
+		}
+		checkRun.App.Slug = strings.TrimSuffix(botUser.Login, "[bot]")
+		s.checkRuns = append(s.checkRuns, checkRun)
+		checkRun.ID = int64(len(s.checkRuns))
+		writeJSON(w, http.StatusCreated, checkRun)
+	case match(parts, "commits", "*", "check-runs") && r.Method == http.MethodGet:
+		checkRuns := []*CheckRun{}
+		for _, c := range s.checkRuns {
+			name := r.URL.Query().Get("check_name")
+			if c.HeadSHA == parts[1] && (len(name) == 0 || c.Name == name) {
+				checkRuns = append(checkRuns, c)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"total_count": len(checkRuns), "check_runs": checkRuns})
+	case len(parts) > 1 && parts[0] == "contents" && r.Method == http.MethodGet:
+		path := strings.Join(parts[1:], "/")
+		content, exist := s.files[path]
+		if !exist {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"type":     "file",
+			"path":     path,
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// Mutations returns the number of GraphQL mutations received so far.
+func (s *Server) Mutations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mutations
+}
+
+// handleGraphQL serves the label batching operations: a query resolving the node IDs of a PR
+// and of labels aliased by their variable names, and a mutation adding and removing labels by ID.
+// Node IDs are the PR number prefixed by "PR_" and the label name prefixed by "LA_".
+// It also serves the minimizeComment mutation, the node IDs of comments being their ID prefixed by "IC_".
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	request := &struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}{}
+	if !readJSON(w, r, request) {
+		return
+	}
+
+	if strings.Contains(request.Query, "minimizeComment") {
+		for _, comments := range s.comments {
+			for _, c := range comments {
+				if c.NodeID == request.Variables["id"] {
+					c.Minimized = true
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{}})
+		return
+	}
+
+	if strings.HasPrefix(request.Query, "mutation") {
+		s.mutations++
+		number, _ := strconv.Atoi(strings.TrimPrefix(request.Variables["id"].(string), "PR_"))
+		pr := s.pullRequests[number]
+		if pr == nil {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"errors": []map[string]string{{"message": "Could not resolve to a node"}}})
+			return
+		}
+		add, _ := request.Variables["add"].([]interface{})
+		for _, id := range add {
+			name := strings.TrimPrefix(id.(string), "LA_")
+			if !hasLabel(pr.Labels, name) {
+				pr.Labels = append(pr.Labels, Label{Name: name})
+				s.addEvent(pr.Number, "labeled", s.actor(), name)
+			}
+		}
+		remove, _ := request.Variables["remove"].([]interface{})
+		for _, id := range remove {
+			name := strings.TrimPrefix(id.(string), "LA_")
+			labels := []Label{}
+			for _, l := range pr.Labels {
+				if l.Name != name {
+					labels = append(labels, l)
+				}
+			}
+			if len(labels) < len(pr.Labels) {
+				s.addEvent(pr.Number, "unlabeled", s.actor(), name)
+			}
+			pr.Labels = labels
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{}})
+		return
+	}
+
+	repository := map[string]interface{}{}
+	if number, ok := request.Variables["number"].(float64); ok && s.pullRequests[int(number)] != nil {
+		repository["pullRequest"] = map[string]string{"id": fmt.Sprintf("PR_%d", int(number))}
+	}
+	for key, value := range request.Variables {
+		name, ok := value.(string)
+		if !ok || !strings.HasPrefix(key, "l") {
+			continue
+		}
+		repository[key] = nil
+		for _, l := range s.repoLabels {
+			if l == name {
+				repository[key] = map[string]string{"id": "LA_" + name}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"repository": repository}})
+}
+
+func (s *Server) pullRequest(w http.ResponseWriter, number string) *PullRequest {
+	n, err := strconv.Atoi(number)
+	if err != nil || s.pullRequests[n] == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	return s.pullRequests[n]
+}
+
+// match reports whether path parts match pattern, where `*` matches any single part.
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(labels []Label, name string) bool {
+	for _, l := range labels {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,114 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package hook lets external Go plugins inspect a pull request and change the labels
+// the action plans to apply, without forking the action.
+//
+// A plugin is built with `go build -buildmode=plugin` against the same version of this module,
+// and exports a variable named Hook implementing PrePlanHook, PostPlanHook or both:
+//
+//	var Hook myHook
+package hook
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Plan is the set of label changes about to be made on a pull request.
+type Plan struct {
+	LabelsToAdd    []string
+	LabelsToRemove []string
+}
+
+// PrePlanHook is called before the plan is made, and may change the expected labels,
+// which map label names to whether they are checked.
+type PrePlanHook interface {
+	PrePlan(pr *scm.PullRequest, labels map[string]bool) error
+}
+
+// PostPlanHook is called after the plan is made and before it is applied, and may change the plan.
+type PostPlanHook interface {
+	PostPlan(pr *scm.PullRequest, plan *Plan) error
+}
+
+// Hooks are the hooks loaded from plugins, called in loading order.
+type Hooks struct {
+	pre  []PrePlanHook
+	post []PostPlanHook
+}
+
+// Load loads the Hook symbol of each plugin in paths.
+func Load(paths []string) (*Hooks, error) {
+	h := &Hooks{}
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %v: %w", path, err)
+		}
+		sym, err := p.Lookup("Hook")
+		if err != nil {
+			return nil, fmt.Errorf("lookup Hook in %v: %w", path, err)
+		}
+		if err := h.Add(sym); err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+	}
+	return h, nil
+}
+
+// Add registers hook, which must implement PrePlanHook, PostPlanHook or both.
+func (h *Hooks) Add(hook interface{}) error {
+	pre, isPre := hook.(PrePlanHook)
+	post, isPost := hook.(PostPlanHook)
+	if !isPre && !isPost {
+		return fmt.Errorf("%T implements neither PrePlanHook nor PostPlanHook", hook)
+	}
+	if isPre {
+		h.pre = append(h.pre, pre)
+	}
+	if isPost {
+		h.post = append(h.post, post)
+	}
+	return nil
+}
+
+func (h *Hooks) PrePlan(pr *scm.PullRequest, labels map[string]bool) error {
+	if h == nil {
+		return nil
+	}
+	for _, hook := range h.pre {
+		if err := hook.PrePlan(pr, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) PostPlan(pr *scm.PullRequest, plan *Plan) error {
+	if h == nil {
+		return nil
+	}
+	for _, hook := range h.post {
+		if err := hook.PostPlan(pr, plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package httplog logs the HTTP requests sent to the APIs, to diagnose failures from workflow logs.
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// Transport wraps base to log the method, URL, status, latency and rate limit of every request.
+// Headers other than the rate limit ones, including Authorization, are never logged.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	u := redactURL(req.URL)
+	if err != nil {
+		logger.Infof("HTTP %s %s: %v (%v)\n", req.Method, u, err, latency)
+		return resp, err
+	}
+
+	logger.Infof("HTTP %s %s: %d (%v) rate limit %s/%s reset %s request %s\n", req.Method, u, resp.StatusCode, latency,
+		header(resp, "X-RateLimit-Remaining"), header(resp, "X-RateLimit-Limit"), header(resp, "X-RateLimit-Reset"),
+		header(resp, "X-GitHub-Request-Id"))
+	return resp, nil
+}
+
+// redactURL returns u without user info and with the values of credential query parameters masked.
+func redactURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	q := r.Query()
+	for _, key := range []string{"access_token", "private_token", "token", "client_secret"} {
+		if q.Has(key) {
+			q.Set(key, "***")
+		}
+	}
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+func header(resp *http.Response, key string) string {
+	if v := resp.Header.Get(key); len(v) > 0 {
+		return v
+	}
+	return "-"
+}
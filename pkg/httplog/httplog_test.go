@@ -0,0 +1,85 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httplog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStderr returns what fn logged.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	out := captureStderr(t, func() {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/repos/apache/pulsar?access_token=secret-query&page=2", nil)
+		req.Header.Set("Authorization", "token secret-header")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	for _, want := range []string{"HTTP GET ", "/repos/apache/pulsar?access_token=%2A%2A%2A&page=2: 403", "rate limit 4999/5000 reset - request ABCD:1234"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log = %q, want %q", out, want)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("log = %q, want the credentials redacted", out)
+	}
+
+	// a failed request is logged with its error
+	srv.Close()
+	out = captureStderr(t, func() {
+		if _, err := client.Get(srv.URL); err == nil {
+			t.Fatalf("Get: err = nil, want connection refused")
+		}
+	})
+	if !strings.Contains(out, "HTTP GET "+srv.URL+": ") {
+		t.Errorf("log = %q, want the failed request", out)
+	}
+}
@@ -0,0 +1,56 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// discordDescriptionLimit is the maximum length of an embed description.
+const discordDescriptionLimit = 4096
+
+// Discord posts a Message as an embed to a Discord webhook.
+type Discord struct {
+	URL    string
+	Client *http.Client
+}
+
+func (d *Discord) Notify(ctx context.Context, msg *Message) error {
+	description := &strings.Builder{}
+	for _, item := range msg.Items {
+		line := "- " + itemLine(item) + "\n"
+		if description.Len()+len(line) >= discordDescriptionLimit {
+			description.WriteString("…")
+			break
+		}
+		description.WriteString(line)
+	}
+
+	return postJSON(ctx, d.Client, d.URL, map[string]interface{}{
+		"embeds": []interface{}{
+			map[string]interface{}{
+				"title":       msg.Title,
+				"description": description.String(),
+				"footer":      map[string]interface{}{"text": msg.Repo},
+			},
+		},
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	})
+}
@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	DefaultEmailSubject = `[{{.Repo}}] {{.Title}}`
+	DefaultEmailBody    = `{{.Title}}:
+{{range .Items}}
+- #{{.Number}} {{.Title}}{{if .Author}} (@{{.Author}}){{end}}{{if .Action}}: {{.Action}}{{end}}
+  {{.URL}}
+{{end}}`
+)
+
+// sendMail is replaced in tests.
+var sendMail = smtp.SendMail
+
+// Email sends a Message through an SMTP server, with the subject and body rendered from
+// Go templates of the Message.
+type Email struct {
+	// Server is the host:port of the SMTP server.
+	Server string
+	// Username and Password authenticate with PLAIN auth if Username is set.
+	Username string
+	Password string
+	From     string
+	To       []string
+	Subject  *template.Template
+	Body     *template.Template
+}
+
+// NewEmail parses the subject and body templates, which default to DefaultEmailSubject and DefaultEmailBody if empty.
+func NewEmail(server, username, password, from string, to []string, subject, body string) (*Email, error) {
+	if len(subject) == 0 {
+		subject = DefaultEmailSubject
+	}
+	if len(body) == 0 {
+		body = DefaultEmailBody
+	}
+	subjectTemplate, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject: %w", err)
+	}
+	bodyTemplate, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse body: %w", err)
+	}
+	if len(from) == 0 {
+		from = username
+	}
+	return &Email{
+		Server:   server,
+		Username: username,
+		Password: password,
+		From:     from,
+		To:       to,
+		Subject:  subjectTemplate,
+		Body:     bodyTemplate,
+	}, nil
+}
+
+func (e *Email) Notify(ctx context.Context, msg *Message) error {
+	subject := &strings.Builder{}
+	if err := e.Subject.Execute(subject, msg); err != nil {
+		return fmt.Errorf("render subject: %w", err)
+	}
+	body := &strings.Builder{}
+	if err := e.Body.Execute(body, msg); err != nil {
+		return fmt.Errorf("render body: %w", err)
+	}
+
+	data := &bytes.Buffer{}
+	fmt.Fprintf(data, "From: %s\r\n", e.From)
+	fmt.Fprintf(data, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	data.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if len(e.Username) > 0 {
+		host, _, err := net.SplitHostPort(e.Server)
+		if err != nil {
+			return fmt.Errorf("invalid server %v: %w", e.Server, err)
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	if err := sendMail(e.Server, auth, e.From, e.To, data.Bytes()); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package notify sends the results of the scheduled modes, like the PRs closed as stale or
+// waiting for a documentation follow-up, to the channels teams watch: email, Microsoft Teams or Discord.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Message is the result of a run to notify about.
+type Message struct {
+	// Event is the mode the message is sent from: stale or digest.
+	Event string
+	Repo  string
+	Title string
+	Items []Item
+}
+
+// Item is a pull request listed in a Message.
+type Item struct {
+	Number int
+	Title  string
+	Author string
+	Action string
+	URL    string
+}
+
+// Notifier delivers a Message to one target.
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
+
+// Webhooks are the webhook formats NewWebhook supports.
+var Webhooks = map[string]func(url string, client *http.Client) Notifier{
+	"teams":   func(url string, client *http.Client) Notifier { return &Teams{URL: url, Client: client} },
+	"discord": func(url string, client *http.Client) Notifier { return &Discord{URL: url, Client: client} },
+}
+
+// NewWebhook returns the notifier posting to url in format, one of Webhooks, through client if not nil.
+func NewWebhook(format, url string, client *http.Client) (Notifier, error) {
+	newNotifier, ok := Webhooks[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown webhook format %v", format)
+	}
+	return newNotifier(url, client), nil
+}
+
+// postJSON posts payload to url as JSON.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post webhook: %v", resp.Status)
+	}
+	return nil
+}
+
+// itemLine formats item as one line of markdown, which both Teams and Discord render.
+func itemLine(item Item) string {
+	line := fmt.Sprintf("[#%d](%s) %s", item.Number, item.URL, item.Title)
+	if len(item.Author) > 0 {
+		line += fmt.Sprintf(" (@%s)", item.Author)
+	}
+	if len(item.Action) > 0 {
+		line += ": " + item.Action
+	}
+	return line
+}
@@ -0,0 +1,113 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testMessage = &Message{Event: "stale", Repo: "apache/pulsar", Title: "Stale PRs", Items: []Item{
+	{Number: 1, Title: "Add docs", Author: "alice", Action: "closed", URL: "https://github.com/apache/pulsar/pull/1"},
+}}
+
+func TestEmail(t *testing.T) {
+	var from string
+	var to []string
+	var msg string
+	sendMail = func(addr string, a smtp.Auth, f string, t []string, m []byte) error {
+		from, to, msg = f, t, string(m)
+		return nil
+	}
+	t.Cleanup(func() { sendMail = smtp.SendMail })
+
+	email, err := NewEmail("smtp.example.com:587", "docbot@example.com", "secret", "",
+		[]string{"docs@example.com", "pmc@example.com"}, "", "")
+	if err != nil {
+		t.Fatalf("NewEmail: %v", err)
+	}
+	if err := email.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if from != "docbot@example.com" || !reflect.DeepEqual(to, []string{"docs@example.com", "pmc@example.com"}) {
+		t.Errorf("from = %v, to = %v", from, to)
+	}
+	for _, want := range []string{
+		"Subject: [apache/pulsar] Stale PRs\r\n",
+		"\r\n- #1 Add docs (@alice): closed\r\n  https://github.com/apache/pulsar/pull/1\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q doesn't contain %q", msg, want)
+		}
+	}
+
+	if _, err := NewEmail("smtp.example.com:25", "", "", "", nil, "", "{{.Missing"); err == nil {
+		t.Errorf("NewEmail with invalid body: want error")
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		payload = nil
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Errorf("unmarshal payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	line := "[#1](https://github.com/apache/pulsar/pull/1) Add docs (@alice): closed"
+	tests := []struct {
+		format string
+		want   func(payload map[string]interface{}) string
+	}{
+		{"teams", func(payload map[string]interface{}) string {
+			card := payload["attachments"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})
+			body := card["body"].([]interface{})
+			return body[len(body)-1].(map[string]interface{})["text"].(string)
+		}},
+		{"discord", func(payload map[string]interface{}) string {
+			return payload["embeds"].([]interface{})[0].(map[string]interface{})["description"].(string)
+		}},
+	}
+	for _, tt := range tests {
+		n, err := NewWebhook(tt.format, server.URL, server.Client())
+		if err != nil {
+			t.Fatalf("NewWebhook(%v): %v", tt.format, err)
+		}
+		if err := n.Notify(context.Background(), testMessage); err != nil {
+			t.Fatalf("%v: Notify: %v", tt.format, err)
+		}
+		if got := tt.want(payload); !strings.Contains(got, line) {
+			t.Errorf("%v: item = %q, want %q", tt.format, got, line)
+		}
+	}
+
+	if _, err := NewWebhook("slack", server.URL, nil); err == nil {
+		t.Errorf("NewWebhook(slack): want error")
+	}
+}
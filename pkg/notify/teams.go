@@ -0,0 +1,69 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Teams posts a Message as an Adaptive Card to a Microsoft Teams incoming webhook or workflow.
+type Teams struct {
+	URL    string
+	Client *http.Client
+}
+
+func (t *Teams) Notify(ctx context.Context, msg *Message) error {
+	body := []interface{}{
+		map[string]interface{}{
+			"type":   "TextBlock",
+			"text":   msg.Title,
+			"size":   "Medium",
+			"weight": "Bolder",
+			"wrap":   true,
+		},
+		map[string]interface{}{
+			"type":     "TextBlock",
+			"text":     msg.Repo,
+			"isSubtle": true,
+			"spacing":  "None",
+		},
+	}
+	for _, item := range msg.Items {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": "- " + itemLine(item),
+			"wrap": true,
+		})
+	}
+
+	return postJSON(ctx, t.Client, t.URL, map[string]interface{}{
+		"type": "message",
+		"attachments": []interface{}{
+			map[string]interface{}{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,60 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"value": "oidc-token-for-%s"}`, r.URL.Query().Get("audience"))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", srv.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	token, err := Token(ctx, srv.Client(), "https://example.com")
+	if err != nil || token != "oidc-token-for-https://example.com" {
+		t.Errorf("Token = %q, %v, want the token for the audience", token, err)
+	}
+	// the default client doesn't trust the test server
+	if _, err := Token(ctx, nil, "https://example.com"); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("Token with the default client: err = %v, want an unknown certificate", err)
+	}
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "wrong-token")
+	if _, err := Token(ctx, srv.Client(), "https://example.com"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Token with a wrong request token: err = %v, want 401", err)
+	}
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+	if _, err := Token(ctx, srv.Client(), "https://example.com"); err == nil || !strings.Contains(err.Error(), "id-token: write") {
+		t.Errorf("Token without the permission: err = %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package schema generates a JSON Schema from the YAML tags of Go structs, and validates decoded YAML
+// against it, so that misspelled keys and mistyped values are reported instead of silently ignored.
+//
+// Only the subset of JSON Schema the generated schemas use is supported: objects with properties,
+// required and additionalProperties, arrays with items, strings, integers, numbers and booleans.
+// A struct field is required if it has the tag `schema:"required"`, and described by the tag `description`.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema as decoded from JSON.
+type Schema = map[string]interface{}
+
+// Generate returns the schema of values of type t.
+func Generate(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return Generate(t.Elem())
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": Generate(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": Generate(t.Elem())}
+	case reflect.Struct:
+		properties := Schema{}
+		required := []interface{}{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if len(name) == 0 {
+				name = strings.ToLower(f.Name)
+			}
+			property := Generate(f.Type)
+			if description := f.Tag.Get("description"); len(description) > 0 {
+				property["description"] = description
+			}
+			properties[name] = property
+			if f.Tag.Get("schema") == "required" {
+				required = append(required, name)
+			}
+		}
+		s := Schema{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return Schema{}
+}
+
+// ValidationError reports the first value not matching the schema.
+type ValidationError struct {
+	// Path is the location of the value, e.g. content_rules[0].paths
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate validates v, as decoded from YAML into an interface{}, against s.
+func Validate(s Schema, v interface{}) error {
+	return validate(s, v, "")
+}
+
+func validate(s Schema, v interface{}, path string) error {
+	if v == nil {
+		return nil
+	}
+	want, _ := s["type"].(string)
+	got := typeOf(v)
+	if len(want) > 0 && want != got && !(want == "number" && got == "integer") {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", want, got)}
+	}
+
+	switch v := v.(type) {
+	case []interface{}:
+		items, _ := s["items"].(Schema)
+		for i, item := range v {
+			if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}, map[interface{}]interface{}:
+		return validateObject(s, toStringMap(v), path)
+	}
+	return nil
+}
+
+func validateObject(s Schema, m map[string]interface{}, path string) error {
+	properties, _ := s["properties"].(Schema)
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, exist := m[name.(string)]; !exist {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required key %q", name)}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		p := key
+		if len(path) > 0 {
+			p = path + "." + key
+		}
+		property, ok := properties[key].(Schema)
+		if !ok {
+			switch additional := s["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return &ValidationError{Path: p, Message: fmt.Sprintf("unknown key, expected one of %s", strings.Join(sortedKeys(properties), ", "))}
+				}
+				continue
+			case Schema:
+				property = additional
+			default:
+				continue
+			}
+		}
+		if err := validate(property, m[key], p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toStringMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	m := make(map[string]interface{})
+	for key, value := range v.(map[interface{}]interface{}) {
+		m[fmt.Sprint(key)] = value
+	}
+	return m
+}
+
+func sortedKeys(m Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,262 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package scm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+)
+
+// labelBatchThreshold is the number of label changes from which EditLabels batches them in one GraphQL mutation.
+const labelBatchThreshold = 5
+
+// GitHub implements Provider on top of the GitHub REST API.
+type GitHub struct {
+	client *ghapi.Client
+	owner  string
+	repo   string
+}
+
+func NewGitHub(client *ghapi.Client, owner, repo string) *GitHub {
+	return &GitHub{client: client, owner: owner, repo: repo}
+}
+
+func (g *GitHub) GetPR(ctx context.Context, number int) (*PullRequest, error) {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{
+		Number:  pr.GetNumber(),
+		Author:  pr.GetUser().GetLogin(),
+		Title:   pr.GetTitle(),
+		Body:    pr.GetBody(),
+		HeadSHA: pr.GetHead().GetSHA(),
+		Locked:  pr.GetLocked(),
+
+		CreatedAt:         pr.GetCreatedAt().Time,
+		AuthorAssociation: pr.GetAuthorAssociation(),
+	}, nil
+}
+
+func (g *GitHub) ListRepoLabels(ctx context.Context) ([]string, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	repoLabels := make([]string, 0)
+	for {
+		rLabels, resp, err := g.client.Issues.ListLabels(ctx, g.owner, g.repo, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range rLabels {
+			repoLabels = append(repoLabels, label.GetName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return repoLabels, nil
+}
+
+func (g *GitHub) ListLabels(ctx context.Context, number int) ([]string, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	issueLabels := make([]string, 0)
+	for {
+		iLabels, resp, err := g.client.Issues.ListLabelsByIssue(ctx, g.owner, g.repo, number, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range iLabels {
+			issueLabels = append(issueLabels, label.GetName())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return issueLabels, nil
+}
+
+// AddLabels adds the labels not already on the pull request, so that re-delivered events are idempotent
+// and don't spend mutations.
+func (g *GitHub) AddLabels(ctx context.Context, number int, labels []string) error {
+	add, _, err := g.pendingChanges(ctx, number, labels, nil)
+	if err != nil || len(add) == 0 {
+		return err
+	}
+	return g.addLabels(ctx, number, add)
+}
+
+// RemoveLabel removes label if it is still on the pull request, rather than failing with 404
+// when it already disappeared, e.g. on a re-delivered event.
+func (g *GitHub) RemoveLabel(ctx context.Context, number int, label string) error {
+	_, remove, err := g.pendingChanges(ctx, number, nil, []string{label})
+	if err != nil || len(remove) == 0 {
+		return err
+	}
+	return g.removeLabel(ctx, number, label)
+}
+
+// EditLabels applies the changes not already made to the current labels of the pull request. It removes then adds
+// labels through REST, or with a single GraphQL mutation if there are at least labelBatchThreshold changes
+// and all the labels exist, as GraphQL doesn't create labels on the fly like REST.
+func (g *GitHub) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	if len(add)+len(remove) == 0 {
+		return nil
+	}
+	add, remove, err := g.pendingChanges(ctx, number, add, remove)
+	if err != nil {
+		return err
+	}
+
+	if len(add)+len(remove) >= labelBatchThreshold {
+		batched, err := g.editLabelsBatch(ctx, number, add, remove)
+		if err != nil || batched {
+			return err
+		}
+	}
+
+	for _, label := range remove {
+		if err := g.removeLabel(ctx, number, label); err != nil {
+			return fmt.Errorf("remove label %v: %w", label, err)
+		}
+	}
+	if len(add) > 0 {
+		if err := g.addLabels(ctx, number, add); err != nil {
+			return fmt.Errorf("add labels %v: %w", add, err)
+		}
+	}
+	return nil
+}
+
+// pendingChanges re-reads the labels of the pull request, and returns the labels of add not on it yet,
+// and those of remove still on it.
+func (g *GitHub) pendingChanges(ctx context.Context, number int, add, remove []string) ([]string, []string, error) {
+	labels, err := g.ListLabels(ctx, number)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list labels: %w", err)
+	}
+	current := make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		current[label] = struct{}{}
+	}
+
+	pendingAdd, pendingRemove := []string{}, []string{}
+	for _, label := range add {
+		if _, exist := current[label]; !exist {
+			pendingAdd = append(pendingAdd, label)
+		}
+	}
+	for _, label := range remove {
+		if _, exist := current[label]; exist {
+			pendingRemove = append(pendingRemove, label)
+		}
+	}
+	return pendingAdd, pendingRemove, nil
+}
+
+func (g *GitHub) addLabels(ctx context.Context, number int, labels []string) error {
+	_, _, err := g.client.Issues.AddLabelsToIssue(ctx, g.owner, g.repo, number, labels)
+	return err
+}
+
+func (g *GitHub) removeLabel(ctx context.Context, number int, label string) error {
+	// Escape labels like `area/broker`, as the client doesn't
+	_, err := g.client.Issues.RemoveLabelForIssue(ctx, g.owner, g.repo, number, url.PathEscape(label))
+	return err
+}
+
+// editLabelsBatch resolves the node IDs of the pull request and labels in one query, and applies
+// the changes in one mutation. It reports false without changes if any label doesn't exist.
+func (g *GitHub) editLabelsBatch(ctx context.Context, number int, add, remove []string) (bool, error) {
+	labels := append(append([]string{}, add...), remove...)
+	declarations := []string{"$owner: String!", "$repo: String!", "$number: Int!"}
+	fields := []string{"pullRequest(number: $number) { id }"}
+	variables := map[string]interface{}{"owner": g.owner, "repo": g.repo, "number": number}
+	for i, label := range labels {
+		declarations = append(declarations, fmt.Sprintf("$l%d: String!", i))
+		fields = append(fields, fmt.Sprintf("l%d: label(name: $l%d) { id }", i, i))
+		variables[fmt.Sprintf("l%d", i)] = label
+	}
+	query := fmt.Sprintf("query(%s) { repository(owner: $owner, name: $repo) { %s } }",
+		strings.Join(declarations, ", "), strings.Join(fields, " "))
+
+	nodes := &struct {
+		Repository map[string]*struct {
+			ID string `json:"id"`
+		} `json:"repository"`
+	}{}
+	if err := ghapi.GraphQL(ctx, g.client, query, variables, nodes); err != nil {
+		return false, fmt.Errorf("resolve label IDs: %w", err)
+	}
+	pr := nodes.Repository["pullRequest"]
+	if pr == nil {
+		return false, fmt.Errorf("resolve label IDs: pull request %d not found", number)
+	}
+	ids := make([]string, len(labels))
+	for i := range labels {
+		node := nodes.Repository[fmt.Sprintf("l%d", i)]
+		if node == nil {
+			return false, nil
+		}
+		ids[i] = node.ID
+	}
+
+	declarations = []string{"$id: ID!"}
+	fields = []string{}
+	variables = map[string]interface{}{"id": pr.ID}
+	if len(add) > 0 {
+		declarations = append(declarations, "$add: [ID!]!")
+		fields = append(fields, "add: addLabelsToLabelable(input: {labelableId: $id, labelIds: $add}) { clientMutationId }")
+		variables["add"] = ids[:len(add)]
+	}
+	if len(remove) > 0 {
+		declarations = append(declarations, "$remove: [ID!]!")
+		fields = append(fields, "remove: removeLabelsFromLabelable(input: {labelableId: $id, labelIds: $remove}) { clientMutationId }")
+		variables["remove"] = ids[len(add):]
+	}
+	mutation := fmt.Sprintf("mutation(%s) { %s }", strings.Join(declarations, ", "), strings.Join(fields, " "))
+	if err := ghapi.GraphQL(ctx, g.client, mutation, variables, nil); err != nil {
+		return false, fmt.Errorf("edit labels: %w", err)
+	}
+	return true, nil
+}
+
+func (g *GitHub) Comment(ctx context.Context, number int, body string) error {
+	_, _, err := g.client.Issues.CreateComment(ctx, g.owner, g.repo, number, &ghapi.IssueComment{Body: &body})
+	return err
+}
+
+func (g *GitHub) EditComment(ctx context.Context, number int, id int64, body string) error {
+	_, _, err := g.client.Issues.EditComment(ctx, g.owner, g.repo, id, &ghapi.IssueComment{Body: &body})
+	return err
+}
+
+func (g *GitHub) EditBody(ctx context.Context, number int, body string) error {
+	_, _, err := g.client.PullRequests.Edit(ctx, g.owner, g.repo, number, &ghapi.PullRequest{Body: &body})
+	return err
+}
+
+func (g *GitHub) EditTitle(ctx context.Context, number int, title string) error {
+	_, _, err := g.client.PullRequests.Edit(ctx, g.owner, g.repo, number, &ghapi.PullRequest{Title: &title})
+	return err
+}
@@ -0,0 +1,219 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLab implements Provider on top of the GitLab REST API v4, treating merge requests as pull requests.
+type GitLab struct {
+	client  *http.Client
+	baseURL string
+	project string
+	token   string
+}
+
+// NewGitLab creates a GitLab provider for project, which is either a numeric id or a full path.
+// baseURL is the API root such as https://gitlab.com/api/v4.
+func NewGitLab(client *http.Client, baseURL, project, token string) *GitLab {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &GitLab{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		project: url.PathEscape(project),
+		token:   token,
+	}
+}
+
+type gitlabMergeRequest struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	SHA         string   `json:"sha"`
+	// DiscussionLocked means only members can comment
+	DiscussionLocked bool      `json:"discussion_locked"`
+	CreatedAt        time.Time `json:"created_at"`
+	Author           struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+type gitlabLabel struct {
+	Name string `json:"name"`
+}
+
+func (g *GitLab) GetPR(ctx context.Context, number int) (*PullRequest, error) {
+	mr, err := g.getMergeRequest(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{
+		Number:  mr.IID,
+		Author:  mr.Author.Username,
+		Title:   mr.Title,
+		Body:    mr.Description,
+		HeadSHA: mr.SHA,
+		Locked:  mr.DiscussionLocked,
+
+		CreatedAt: mr.CreatedAt,
+	}, nil
+}
+
+func (g *GitLab) ListRepoLabels(ctx context.Context) ([]string, error) {
+	repoLabels := make([]string, 0)
+	page := "1"
+	for len(page) > 0 {
+		var labels []gitlabLabel
+		resp, err := g.do(ctx, http.MethodGet,
+			fmt.Sprintf("/projects/%s/labels?per_page=100&page=%s", g.project, page), nil, &labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range labels {
+			repoLabels = append(repoLabels, label.Name)
+		}
+		page = resp.Header.Get("X-Next-Page")
+	}
+	return repoLabels, nil
+}
+
+func (g *GitLab) ListLabels(ctx context.Context, number int) ([]string, error) {
+	mr, err := g.getMergeRequest(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return mr.Labels, nil
+}
+
+func (g *GitLab) AddLabels(ctx context.Context, number int, labels []string) error {
+	return g.updateMergeRequest(ctx, number, url.Values{"add_labels": {strings.Join(labels, ",")}})
+}
+
+func (g *GitLab) RemoveLabel(ctx context.Context, number int, label string) error {
+	return g.updateMergeRequest(ctx, number, url.Values{"remove_labels": {label}})
+}
+
+func (g *GitLab) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	form := url.Values{}
+	if len(add) > 0 {
+		form.Set("add_labels", strings.Join(add, ","))
+	}
+	if len(remove) > 0 {
+		form.Set("remove_labels", strings.Join(remove, ","))
+	}
+	if len(form) == 0 {
+		return nil
+	}
+	return g.updateMergeRequest(ctx, number, form)
+}
+
+func (g *GitLab) Comment(ctx context.Context, number int, body string) error {
+	_, err := g.do(ctx, http.MethodPost,
+		fmt.Sprintf("/projects/%s/merge_requests/%d/notes", g.project, number),
+		url.Values{"body": {body}}, nil)
+	return err
+}
+
+func (g *GitLab) EditComment(ctx context.Context, number int, id int64, body string) error {
+	_, err := g.do(ctx, http.MethodPut,
+		fmt.Sprintf("/projects/%s/merge_requests/%d/notes/%d", g.project, number, id),
+		url.Values{"body": {body}}, nil)
+	return err
+}
+
+func (g *GitLab) EditBody(ctx context.Context, number int, body string) error {
+	return g.updateMergeRequest(ctx, number, url.Values{"description": {body}})
+}
+
+func (g *GitLab) EditTitle(ctx context.Context, number int, title string) error {
+	return g.updateMergeRequest(ctx, number, url.Values{"title": {title}})
+}
+
+func (g *GitLab) getMergeRequest(ctx context.Context, number int) (*gitlabMergeRequest, error) {
+	mr := &gitlabMergeRequest{}
+	_, err := g.do(ctx, http.MethodGet,
+		fmt.Sprintf("/projects/%s/merge_requests/%d", g.project, number), nil, mr)
+	if err != nil {
+		return nil, err
+	}
+	return mr, nil
+}
+
+func (g *GitLab) updateMergeRequest(ctx context.Context, number int, form url.Values) error {
+	_, err := g.do(ctx, http.MethodPut,
+		fmt.Sprintf("/projects/%s/merge_requests/%d", g.project, number), form, nil)
+	return err
+}
+
+// StatusError is returned for a GitLab API request answered with an error status.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// do sends form as the request body if not nil, and decodes the JSON response into v if not nil.
+func (g *GitLab) do(ctx context.Context, method, path string, form url.Values, v interface{}) (*http.Response, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp, &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,181 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package scm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitLab serves merge request 1 of the project apache/pulsar.
+type fakeGitLab struct {
+	mu          sync.Mutex
+	repoLabels  []string
+	title, body string
+	labels      []string
+	notes       []string
+	puts        int
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "glpat-test" {
+		http.Error(w, `{"message": "401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	const project = "/api/v4/projects/apache%2Fpulsar"
+	switch path := r.URL.EscapedPath(); {
+	case path == project+"/labels" && r.Method == http.MethodGet:
+		// one label per page
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < len(f.repoLabels) {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		json.NewEncoder(w).Encode([]map[string]string{{"name": f.repoLabels[page-1]}})
+	case path == project+"/merge_requests/1" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"iid": 1, "title": f.title, "description": f.body, "labels": f.labels, "sha": "abc123",
+			"discussion_locked": true, "created_at": "2024-01-02T03:04:05Z",
+			"author": map[string]string{"username": "alice"},
+		})
+	case path == project+"/merge_requests/1" && r.Method == http.MethodPut:
+		f.puts++
+		r.ParseForm()
+		if add := r.PostForm.Get("add_labels"); len(add) > 0 {
+			f.labels = append(f.labels, strings.Split(add, ",")...)
+		}
+		for _, remove := range strings.Split(r.PostForm.Get("remove_labels"), ",") {
+			for i, label := range f.labels {
+				if label == remove {
+					f.labels = append(f.labels[:i], f.labels[i+1:]...)
+					break
+				}
+			}
+		}
+		if _, exist := r.PostForm["title"]; exist {
+			f.title = r.PostForm.Get("title")
+		}
+		if _, exist := r.PostForm["description"]; exist {
+			f.body = r.PostForm.Get("description")
+		}
+		w.Write([]byte("{}"))
+	case path == project+"/merge_requests/1/notes" && r.Method == http.MethodPost:
+		r.ParseForm()
+		f.notes = append(f.notes, r.PostForm.Get("body"))
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, project+"/merge_requests/1/notes/") && r.Method == http.MethodPut:
+		// the notes are numbered from 1 in the order they are posted
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, project+"/merge_requests/1/notes/"))
+		if id < 1 || id > len(f.notes) {
+			http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		r.ParseForm()
+		f.notes[id-1] = r.PostForm.Get("body")
+		w.Write([]byte("{}"))
+	default:
+		http.Error(w, `{"message": "404 Not Found"}`, http.StatusNotFound)
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	fake := &fakeGitLab{repoLabels: []string{"doc", "doc-required", "doc-not-needed"}, title: "Fix", body: "- [x] `doc`", labels: []string{"bug"}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	g := NewGitLab(srv.Client(), srv.URL+"/api/v4/", "apache/pulsar", "glpat-test")
+	ctx := context.Background()
+
+	pr, err := g.GetPR(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetPR: %v", err)
+	}
+	if pr.Number != 1 || pr.Author != "alice" || pr.Title != "Fix" || pr.Body != "- [x] `doc`" ||
+		pr.HeadSHA != "abc123" || !pr.Locked || pr.CreatedAt.Year() != 2024 {
+		t.Errorf("GetPR = %+v", pr)
+	}
+
+	repoLabels, err := g.ListRepoLabels(ctx)
+	if err != nil || !reflect.DeepEqual(repoLabels, fake.repoLabels) {
+		t.Errorf("ListRepoLabels = %v, %v, want all pages %v", repoLabels, err, fake.repoLabels)
+	}
+
+	if err := g.AddLabels(ctx, 1, []string{"doc", "doc-required"}); err != nil {
+		t.Fatalf("AddLabels: %v", err)
+	}
+	if err := g.RemoveLabel(ctx, 1, "bug"); err != nil {
+		t.Fatalf("RemoveLabel: %v", err)
+	}
+	if err := g.EditLabels(ctx, 1, []string{"doc-not-needed"}, []string{"doc", "doc-required"}); err != nil {
+		t.Fatalf("EditLabels: %v", err)
+	}
+	labels, err := g.ListLabels(ctx, 1)
+	if err != nil || !reflect.DeepEqual(labels, []string{"doc-not-needed"}) {
+		t.Errorf("ListLabels = %v, %v, want [doc-not-needed]", labels, err)
+	}
+
+	// nothing to edit sends no request
+	puts := fake.puts
+	if err := g.EditLabels(ctx, 1, nil, nil); err != nil || fake.puts != puts {
+		t.Errorf("empty EditLabels = %v, sent %d requests", err, fake.puts-puts)
+	}
+
+	if err := g.EditTitle(ctx, 1, "[doc] Fix"); err != nil {
+		t.Fatalf("EditTitle: %v", err)
+	}
+	if err := g.EditBody(ctx, 1, "- [ ] `doc`"); err != nil {
+		t.Fatalf("EditBody: %v", err)
+	}
+	if err := g.Comment(ctx, 1, "Please add the docs & examples"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if err := g.Comment(ctx, 1, "Thanks"); err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if err := g.EditComment(ctx, 1, 2, "Thanks for the docs"); err != nil {
+		t.Fatalf("EditComment: %v", err)
+	}
+	if fake.title != "[doc] Fix" || fake.body != "- [ ] `doc`" ||
+		!reflect.DeepEqual(fake.notes, []string{"Please add the docs & examples", "Thanks for the docs"}) {
+		t.Errorf("title %q, body %q, notes %q after the edits", fake.title, fake.body, fake.notes)
+	}
+}
+
+func TestGitLabErrors(t *testing.T) {
+	srv := httptest.NewServer(&fakeGitLab{})
+	defer srv.Close()
+	ctx := context.Background()
+
+	g := NewGitLab(srv.Client(), srv.URL+"/api/v4", "apache/pulsar", "wrong-token")
+	if _, err := g.GetPR(ctx, 1); err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("GetPR with a wrong token: err = %v, want 401", err)
+	}
+
+	g = NewGitLab(srv.Client(), srv.URL+"/api/v4", "apache/pulsar", "glpat-test")
+	if err := g.Comment(ctx, 2, "hello"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Comment on a missing merge request: err = %v, want 404", err)
+	}
+}
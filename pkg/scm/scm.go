@@ -0,0 +1,62 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package scm abstracts the source code management platform hosting the pull requests.
+package scm
+
+import (
+	"context"
+	"time"
+)
+
+// PullRequest is the platform independent view of a pull request or merge request.
+type PullRequest struct {
+	Number int
+	Author string
+	Title  string
+	Body   string
+	// HeadSHA is the commit the pull request head points to.
+	HeadSHA string
+	// Locked means the conversation is locked to collaborators.
+	Locked bool
+	// CreatedAt is when the pull request was opened.
+	CreatedAt time.Time
+
+	// AuthorAssociation is the GitHub author_association, e.g. FIRST_TIME_CONTRIBUTOR.
+	// It is empty if the platform doesn't provide one.
+	AuthorAssociation string
+}
+
+// Provider is the set of operations needed to reconcile labels of a pull request.
+// A Provider is bound to a single repository.
+type Provider interface {
+	GetPR(ctx context.Context, number int) (*PullRequest, error)
+	// ListRepoLabels lists label names defined in the repository.
+	ListRepoLabels(ctx context.Context) ([]string, error)
+	// ListLabels lists label names applied on the pull request.
+	ListLabels(ctx context.Context, number int) ([]string, error)
+	AddLabels(ctx context.Context, number int, labels []string) error
+	RemoveLabel(ctx context.Context, number int, label string) error
+	// EditLabels adds and removes labels of the pull request, in a single request where the platform allows,
+	// so that a failure doesn't leave the changes partially applied.
+	EditLabels(ctx context.Context, number int, add, remove []string) error
+	Comment(ctx context.Context, number int, body string) error
+	// EditComment replaces the body of the comment id on the pull request.
+	EditComment(ctx context.Context, number int, id int64, body string) error
+	EditBody(ctx context.Context, number int, body string) error
+	EditTitle(ctx context.Context, number int, title string) error
+}
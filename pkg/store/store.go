@@ -0,0 +1,186 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package store
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketDeliveries   = []byte("deliveries")
+	bucketPullRequests = []byte("pull_requests")
+	bucketState        = []byte("state")
+)
+
+// Store persists which webhook deliveries and PR revisions have been processed,
+// so that redelivered or out-of-order events can be skipped, and the bookkeeping of PRs.
+type Store struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bucketDeliveries, bucketPullRequests, bucketState} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// HasDelivery reports whether the delivery id was marked as handled. A nil Store has seen no delivery.
+func (s *Store) HasDelivery(id string) (bool, error) {
+	if s == nil || len(id) == 0 {
+		return false, nil
+	}
+
+	seen := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		seen = tx.Bucket(bucketDeliveries).Get([]byte(id)) != nil
+		return nil
+	})
+	return seen, err
+}
+
+// MarkDelivery records the delivery id as handled, so that its redeliveries are skipped.
+// It must be called once the delivery was handled successfully, for a failed one to be retried by a redelivery.
+// A nil Store discards it.
+func (s *Store) MarkDelivery(id string) error {
+	if s == nil || len(id) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDeliveries).Put([]byte(id), encodeTime(time.Now()))
+	})
+}
+
+// Prune deletes the deliveries handled and the PR revisions updated before before, so that the db doesn't grow
+// without limit, and returns how many entries were deleted. A nil Store has nothing to prune.
+func (s *Store) Prune(before time.Time) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	pruned := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bucketDeliveries, bucketPullRequests} {
+			b := tx.Bucket(bucket)
+			expired := [][]byte{}
+			err := b.ForEach(func(k, v []byte) error {
+				if decodeTime(v).Before(before) {
+					expired = append(expired, append([]byte{}, k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// keys can't be deleted while iterating
+			for _, k := range expired {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			pruned += len(expired)
+		}
+		return nil
+	})
+	return pruned, err
+}
+
+// AdvancePullRequest records updatedAt for the PR identified by key and reports whether
+// it is newer than the last recorded one. Stale events must not be applied.
+// A nil Store treats every revision as new.
+func (s *Store) AdvancePullRequest(key string, updatedAt time.Time) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+
+	newer := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPullRequests)
+		if v := b.Get([]byte(key)); v != nil && updatedAt.Before(decodeTime(v)) {
+			return nil
+		}
+		newer = true
+		return b.Put([]byte(key), encodeTime(updatedAt))
+	})
+	return newer, err
+}
+
+// Get returns the value recorded under key, or "" if there's none.
+// A nil Store has no values.
+func (s *Store) Get(key string) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+
+	value := ""
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value = string(tx.Bucket(bucketState).Get([]byte(key)))
+		return nil
+	})
+	return value, err
+}
+
+// Put records value under key. A nil Store discards it.
+func (s *Store) Put(key, value string) error {
+	if s == nil {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketState).Put([]byte(key), []byte(value))
+	})
+}
+
+func encodeTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func decodeTime(b []byte) time.Time {
+	if len(b) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
@@ -0,0 +1,145 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	s, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestDeliveries(t *testing.T) {
+	s := openTestStore(t)
+
+	if seen, err := s.HasDelivery("1"); err != nil || seen {
+		t.Fatalf("HasDelivery before MarkDelivery = %v, %v, want false", seen, err)
+	}
+	if err := s.MarkDelivery("1"); err != nil {
+		t.Fatalf("MarkDelivery: %v", err)
+	}
+	if seen, err := s.HasDelivery("1"); err != nil || !seen {
+		t.Fatalf("HasDelivery after MarkDelivery = %v, %v, want true", seen, err)
+	}
+	if seen, _ := s.HasDelivery("2"); seen {
+		t.Fatalf("HasDelivery of another delivery = true")
+	}
+}
+
+func TestAdvancePullRequest(t *testing.T) {
+	s := openTestStore(t)
+	updatedAt := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		updatedAt time.Time
+		newer     bool
+	}{
+		{updatedAt, true},
+		// a redelivery of the same revision
+		{updatedAt, true},
+		{updatedAt.Add(-time.Minute), false},
+		{updatedAt.Add(time.Minute), true},
+		{updatedAt, false},
+	} {
+		if newer, err := s.AdvancePullRequest("apache/pulsar#1", tt.updatedAt); err != nil || newer != tt.newer {
+			t.Fatalf("AdvancePullRequest(%v) = %v, %v, want %v", tt.updatedAt, newer, err, tt.newer)
+		}
+	}
+	// PRs are independent
+	if newer, _ := s.AdvancePullRequest("apache/pulsar#2", updatedAt.Add(-time.Hour)); !newer {
+		t.Fatalf("AdvancePullRequest of another PR = false")
+	}
+}
+
+func TestState(t *testing.T) {
+	s := openTestStore(t)
+
+	if value, err := s.Get("apache/pulsar#1/key"); err != nil || value != "" {
+		t.Fatalf("Get before Put = %q, %v", value, err)
+	}
+	if err := s.Put("apache/pulsar#1/key", "value"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if value, err := s.Get("apache/pulsar#1/key"); err != nil || value != "value" {
+		t.Fatalf("Get = %q, %v, want value", value, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := openTestStore(t)
+	if err := s.MarkDelivery("old"); err != nil {
+		t.Fatalf("MarkDelivery: %v", err)
+	}
+	if _, err := s.AdvancePullRequest("apache/pulsar#1", time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("AdvancePullRequest: %v", err)
+	}
+	if _, err := s.AdvancePullRequest("apache/pulsar#2", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("AdvancePullRequest: %v", err)
+	}
+	if err := s.Put("apache/pulsar#1/key", "value"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	if err := s.MarkDelivery("new"); err != nil {
+		t.Fatalf("MarkDelivery: %v", err)
+	}
+
+	// the deliveries and revisions before the cutoff are deleted, the state is kept
+	if pruned, err := s.Prune(cutoff); err != nil || pruned != 2 {
+		t.Fatalf("Prune = %d, %v, want 2", pruned, err)
+	}
+	for id, want := range map[string]bool{"old": false, "new": true} {
+		if seen, _ := s.HasDelivery(id); seen != want {
+			t.Errorf("HasDelivery(%v) = %v, want %v", id, seen, want)
+		}
+	}
+	if newer, _ := s.AdvancePullRequest("apache/pulsar#2", time.Now()); newer {
+		t.Errorf("revision after the cutoff was pruned")
+	}
+	if value, _ := s.Get("apache/pulsar#1/key"); value != "value" {
+		t.Errorf("state was pruned")
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	if seen, err := s.HasDelivery("1"); err != nil || seen {
+		t.Errorf("HasDelivery = %v, %v, want false", seen, err)
+	}
+	if err := s.MarkDelivery("1"); err != nil {
+		t.Errorf("MarkDelivery: %v", err)
+	}
+	if newer, err := s.AdvancePullRequest("apache/pulsar#1", time.Now()); err != nil || !newer {
+		t.Errorf("AdvancePullRequest = %v, %v, want true", newer, err)
+	}
+	if pruned, err := s.Prune(time.Now()); err != nil || pruned != 0 {
+		t.Errorf("Prune = %d, %v, want 0", pruned, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
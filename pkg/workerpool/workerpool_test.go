@@ -0,0 +1,177 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunOrder(t *testing.T) {
+	tasks := []Task{}
+	for i := 0; i < 20; i++ {
+		// later tasks finish first
+		delay := time.Duration(20-i) * time.Millisecond
+		var err error
+		if i%2 == 1 {
+			err = fmt.Errorf("task %d", i)
+		}
+		tasks = append(tasks, Task{Key: fmt.Sprint(i), Fn: func(ctx context.Context) error {
+			time.Sleep(delay)
+			return err
+		}})
+	}
+
+	results := New(4).Run(context.Background(), tasks)
+	if len(results) != len(tasks) {
+		t.Fatalf("results = %d, want %d", len(results), len(tasks))
+	}
+	for i, result := range results {
+		if result.Key != fmt.Sprint(i) {
+			t.Fatalf("results[%d].Key = %v, want the order of the tasks", i, result.Key)
+		}
+		if (result.Err != nil) != (i%2 == 1) {
+			t.Errorf("results[%d].Err = %v", i, result.Err)
+		}
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	tasks := []Task{}
+	for i := 0; i < 12; i++ {
+		tasks = append(tasks, Task{Key: fmt.Sprint(i), Fn: func(ctx context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		}})
+	}
+
+	New(3).Run(context.Background(), tasks)
+	if p := peak.Load(); p > 3 {
+		t.Fatalf("peak concurrency = %d, want at most 3 workers busy together", p)
+	}
+	// a non-positive number of workers runs the tasks one by one
+	peak.Store(0)
+	New(0).Run(context.Background(), tasks)
+	if p := peak.Load(); p != 1 {
+		t.Fatalf("peak concurrency of New(0) = %d, want 1", p)
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	ran := atomic.Int32{}
+	tasks := []Task{
+		{Key: "ok", Fn: func(ctx context.Context) error { ran.Add(1); return nil }},
+		{Key: "panic", Fn: func(ctx context.Context) error { panic("boom") }},
+		{Key: "after", Fn: func(ctx context.Context) error { ran.Add(1); return nil }},
+	}
+
+	results := New(1).Run(context.Background(), tasks)
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "panic: boom") {
+		t.Fatalf("panicking task err = %v, want panic: boom", results[1].Err)
+	}
+	if results[0].Err != nil || results[2].Err != nil || ran.Load() != 2 {
+		t.Fatalf("results = %v, want the other tasks run", results)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := New(2).Run(ctx, []Task{{Key: "1", Fn: func(ctx context.Context) error {
+		t.Errorf("task run after cancelation")
+		return nil
+	}}})
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", results[0].Err)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(100)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	// the first event is immediate, the next ones are 10ms apart
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("6 events took %v, want at least 50ms at 100/s", elapsed)
+	}
+
+	// a non-positive rate and a nil Limiter don't limit
+	for _, l := range []*Limiter{NewLimiter(0), nil} {
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			if err := l.Wait(context.Background()); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Fatalf("unlimited events took %v", elapsed)
+		}
+	}
+}
+
+func TestLimiterCanceled(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLimiterTransport(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewLimiter(50).Transport(nil)}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("requests = %d, want 3", requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("3 requests took %v, want at least 40ms at 50/s", elapsed)
+	}
+}
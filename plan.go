@@ -0,0 +1,178 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/breaker"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Plan is the decision made for a PR: the label changes, the edits of the body and the verdict.
+// It is logged as a single JSON line and written to the plan output, so that the behavior can be reviewed,
+// and downstream steps can apply it.
+type Plan struct {
+	Number int      `json:"number"`
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+	// BodyEdits are the checkboxes checked or unchecked in the body
+	BodyEdits []Edit `json:"body_edits"`
+	// Body and Title are the edited body and title, if changed
+	Body     *string  `json:"body,omitempty"`
+	Title    *string  `json:"title,omitempty"`
+	Comments []string `json:"comments"`
+	Verdict  Verdict  `json:"verdict"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// Edit checks or unchecks the checkbox of Label in the PR body.
+type Edit struct {
+	Label   string `json:"label"`
+	Checked bool   `json:"checked"`
+}
+
+// Verdict is the outcome of a run, success or the failure class.
+type Verdict string
+
+const verdictSuccess Verdict = "success"
+
+func newPlan() *Plan {
+	return &Plan{Add: []string{}, Remove: []string{}, BodyEdits: []Edit{}, Comments: []string{}}
+}
+
+// addBodyEdits records the checkbox changes of changeList, sorted by label.
+func (p *Plan) addBodyEdits(changeList map[string]bool) {
+	if p == nil {
+		return
+	}
+	labels := make([]string, 0, len(changeList))
+	for label := range changeList {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		p.BodyEdits = append(p.BodyEdits, Edit{Label: label, Checked: changeList[label]})
+	}
+}
+
+// planProvider records the changes made through a Provider into a plan.
+type planProvider struct {
+	scm.Provider
+	plan *Plan
+}
+
+func newPlanProvider(p scm.Provider) *planProvider {
+	return &planProvider{Provider: p, plan: newPlan()}
+}
+
+func (p *planProvider) AddLabels(ctx context.Context, number int, labels []string) error {
+	p.plan.Add = append(p.plan.Add, labels...)
+	return p.Provider.AddLabels(ctx, number, labels)
+}
+
+func (p *planProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	p.plan.Remove = append(p.plan.Remove, label)
+	return p.Provider.RemoveLabel(ctx, number, label)
+}
+
+func (p *planProvider) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	p.plan.Add = append(p.plan.Add, add...)
+	p.plan.Remove = append(p.plan.Remove, remove...)
+	return p.Provider.EditLabels(ctx, number, add, remove)
+}
+
+func (p *planProvider) Comment(ctx context.Context, number int, body string) error {
+	p.plan.Comments = append(p.plan.Comments, body)
+	return p.Provider.Comment(ctx, number, body)
+}
+
+func (p *planProvider) EditComment(ctx context.Context, number int, id int64, body string) error {
+	p.plan.Comments = append(p.plan.Comments, body)
+	return p.Provider.EditComment(ctx, number, id, body)
+}
+
+func (p *planProvider) EditBody(ctx context.Context, number int, body string) error {
+	p.plan.Body = &body
+	return p.Provider.EditBody(ctx, number, body)
+}
+
+func (p *planProvider) EditTitle(ctx context.Context, number int, title string) error {
+	p.plan.Title = &title
+	return p.Provider.EditTitle(ctx, number, title)
+}
+
+// setPlanOutput logs the plan of PR number, with the verdict of err, and writes it to the plan output.
+func (p *planProvider) setPlanOutput(number int, err error) {
+	p.plan.Number = number
+	p.plan.Verdict = verdictSuccess
+	if err != nil {
+		p.plan.Verdict = Verdict(failureClass(err))
+		p.plan.Message = err.Error()
+	}
+	data, err := json.Marshal(p.plan)
+	if err != nil {
+		logger.Errorf("Marshal plan: %v\n", err)
+		return
+	}
+	logger.Infof("Plan: %s\n", data)
+	githubactions.SetOutput("plan", string(data))
+}
+
+// planOnlyTransport wraps base so that mutating requests are logged and answered with no content instead of sent.
+func planOnlyTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &planTransport{base: base}
+}
+
+type planTransport struct {
+	base http.RoundTripper
+}
+
+func (t *planTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mutating, err := breaker.IsMutating(req)
+	if err != nil {
+		return nil, err
+	}
+	if !mutating {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	logger.Infof("Plan %v %v\n", req.Method, req.URL.Path)
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,103 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// policyInput is the input document of the Rego policy.
+type policyInput struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Author string   `json:"author"`
+	Labels []string `json:"labels"`
+	Files  []string `json:"files"`
+}
+
+// applyPolicy evaluates the Rego policy at POLICY_PATH against the current PR with the opa CLI,
+// recording each message of the data.docbot.deny set as a violation of the repository rules.
+func (a *Action) applyPolicy() error {
+	if len(a.config.GetPolicyPath()) == 0 || a.client == nil {
+		return nil
+	}
+
+	vars, err := a.expressionVars()
+	if err != nil {
+		return err
+	}
+	pr := vars["pr"].(map[string]interface{})
+	input, err := json.Marshal(&policyInput{
+		Number: a.config.GetNumber(),
+		Title:  pr["title"].(string),
+		Body:   pr["body"].(string),
+		Author: pr["author"].(string),
+		Labels: vars["labels"].([]string),
+		Files:  vars["files"].([]string),
+	})
+	if err != nil {
+		return err
+	}
+
+	denied, err := evalPolicy(a.config.GetOPAPath(), a.config.GetPolicyPath(), input)
+	if err != nil {
+		return fmt.Errorf("evaluate %v: %w", a.config.GetPolicyPath(), err)
+	}
+	logger.Infof("Policy violations: %v\n", denied)
+	a.ruleFailures = append(a.ruleFailures, denied...)
+	return nil
+}
+
+// evalPolicy returns the sorted messages of data.docbot.deny for input.
+func evalPolicy(opa, policy string, input []byte) ([]string, error) {
+	cmd := exec.Command(opa, "eval", "--format", "json", "--data", policy, "--stdin-input", "data.docbot.deny")
+	cmd.Stdin = bytes.NewReader(input)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, stderr.String())
+	}
+
+	result := &struct {
+		Result []struct {
+			Expressions []struct {
+				Value []string `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}{}
+	if err := json.Unmarshal(out, result); err != nil {
+		return nil, fmt.Errorf("decode result: %w", err)
+	}
+
+	denied := []string{}
+	for _, r := range result.Result {
+		for _, e := range r.Expressions {
+			denied = append(denied, e.Value...)
+		}
+	}
+	sort.Strings(denied)
+	return denied, nil
+}
@@ -0,0 +1,216 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxLabelPatternLength limits the size of a custom LABEL_PATTERN
+	maxLabelPatternLength = 1024
+	// maxLabelPatternGroups limits the capture groups of a custom LABEL_PATTERN
+	maxLabelPatternGroups = 16
+	// maxBodyLength limits how much of a PR body is parsed, GitHub allows 65536 characters
+	maxBodyLength = 1 << 18
+	// maxEditableBodyLength is the length of a PR body GitHub allows
+	maxEditableBodyLength = 65536
+	// maxLabelMatches limits the checkboxes parsed per preset
+	maxLabelMatches = 1000
+)
+
+// labelPreset extracts labels from one style of PR template. The pattern must capture
+// the checkbox state and the label name, as the first two groups unless the indexes are set.
+type labelPreset struct {
+	re      *regexp.Regexp
+	checked func(state string) bool
+
+	// indexes of the groups named checked and label in a custom pattern, if any
+	checkedIndex int
+	labelIndex   int
+}
+
+// submatches returns the checkbox state and the label name captured in the submatches m of re.
+func (p labelPreset) submatches(m []string) (string, string) {
+	if p.checkedIndex > 0 && p.labelIndex > 0 {
+		return m[p.checkedIndex], m[p.labelIndex]
+	}
+	return m[1], m[2]
+}
+
+// labelPresets are the built-in template styles selectable by LABEL_PATTERN_PRESET.
+var labelPresets = map[string]labelPreset{
+	// - [x] `doc-required`
+	"markdown": {
+		re:      regexp.MustCompile("- \\[(.*?)\\] ?`(.+?)`"),
+		checked: isMarkdownChecked,
+	},
+	// <input type="checkbox" checked> `doc-required`
+	"html": {
+		re:      regexp.MustCompile("(?i)<input\\s([^>]*\\btype=[\"']?checkbox\\b[^>]*)>\\s*(?:<[^>]*>\\s*)*`?([^`<\\s]+)`?"),
+		checked: isHTMLChecked,
+	},
+	// | [x] | `doc-required` | description |
+	"table": {
+		re:      regexp.MustCompile("(?m)^\\|\\s*\\[(.*?)\\]\\s*\\|\\s*`?([^`|]+?)`?\\s*\\|"),
+		checked: isMarkdownChecked,
+	},
+}
+
+// lenientCheckboxPattern matches a markdown checkbox line with any bullet, and the label
+// with or without backticks, like "* [X] doc-required: why". Without backticks, the label ends at a space
+// and drops a trailing ":", "," or ".". LABEL_TOLERANCE lenient adds it to the markdown preset.
+const lenientCheckboxPattern = "^[ \\t]*[-*+][ \\t]+\\[([ xX]?)\\][ \\t]*(`[^`\\r\\n]+`|[^\\s`]*[^\\s`:,.])"
+
+var (
+	lenientCheckboxRegexp = regexp.MustCompile(lenientCheckboxPattern)
+	lenientMarkdownPreset = labelPreset{
+		re:      regexp.MustCompile("(?m)" + lenientCheckboxPattern),
+		checked: isMarkdownChecked,
+	}
+)
+
+func isMarkdownChecked(state string) bool {
+	return strings.ToLower(strings.TrimSpace(state)) == "x"
+}
+
+var htmlCheckedRegexp = regexp.MustCompile(`(?i)\bchecked\b`)
+
+func isHTMLChecked(attributes string) bool {
+	return htmlCheckedRegexp.MatchString(attributes)
+}
+
+// compileLabelPresets returns the presets to extract labels with. A custom pattern takes precedence over presets.
+// If lenient, the markdown preset also tolerates other bullets and labels without backticks.
+func compileLabelPresets(pattern string, names []string, lenient bool) ([]labelPreset, error) {
+	if len(pattern) == 0 {
+		presets := []labelPreset{}
+		for _, name := range names {
+			presets = append(presets, labelPresets[name])
+			if lenient && name == "markdown" {
+				presets = append(presets, lenientMarkdownPreset)
+			}
+		}
+		return presets, nil
+	}
+
+	if len(pattern) > maxLabelPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d", maxLabelPatternLength)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newLabelPatternError(pattern, err)
+	}
+	if re.NumSubexp() > maxLabelPatternGroups {
+		return nil, fmt.Errorf("pattern has %d groups, more than %d", re.NumSubexp(), maxLabelPatternGroups)
+	}
+
+	// Named groups take precedence over positions, so that other groups don't shift them
+	checkedIndex, labelIndex := re.SubexpIndex("checked"), re.SubexpIndex("label")
+	switch {
+	case checkedIndex > 0 && labelIndex > 0:
+	case checkedIndex > 0:
+		return nil, fmt.Errorf("pattern names the (?P<checked>...) group but not the (?P<label>...) group")
+	case labelIndex > 0:
+		return nil, fmt.Errorf("pattern names the (?P<label>...) group but not the (?P<checked>...) group")
+	case re.NumSubexp() < 2:
+		return nil, fmt.Errorf("pattern must capture the checkbox state and the label name, "+
+			"as (?P<checked>...) and (?P<label>...) or the first two groups, got %d groups", re.NumSubexp())
+	}
+	return []labelPreset{{re: re, checked: isMarkdownChecked, checkedIndex: checkedIndex, labelIndex: labelIndex}}, nil
+}
+
+// labelPatternError is a syntax error of a custom LABEL_PATTERN, at the 1-based Column of the pattern.
+type labelPatternError struct {
+	Column int
+	Err    *syntax.Error
+}
+
+func (e *labelPatternError) Error() string {
+	return fmt.Sprintf("column %d: %v", e.Column, e.Err.Code)
+}
+
+// newLabelPatternError locates the syntax error err in pattern. The parser reports unbalanced parentheses
+// along with the whole pattern, so the offending one is searched for.
+func newLabelPatternError(pattern string, err error) error {
+	var syntaxErr *syntax.Error
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	offset := 0
+	switch {
+	case syntaxErr.Code == syntax.ErrMissingParen || syntaxErr.Code == syntax.ErrUnexpectedParen:
+		offset = unbalancedParen(pattern)
+	case syntaxErr.Expr != pattern:
+		offset = max(strings.Index(pattern, syntaxErr.Expr), 0)
+	}
+	return &labelPatternError{Column: utf8.RuneCountInString(pattern[:offset]) + 1, Err: syntaxErr}
+}
+
+// unbalancedParen returns the offset of the first unmatched ")" of pattern, or else of the last unmatched "(",
+// skipping escaped characters and character classes.
+func unbalancedParen(pattern string) int {
+	open := []int{}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			// a "]" right after "[" or "[^" is a literal
+			j := i + 1
+			if j < len(pattern) && pattern[j] == '^' {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				j++
+			}
+			for ; j < len(pattern) && pattern[j] != ']'; j++ {
+				if pattern[j] == '\\' {
+					j++
+				}
+			}
+			i = j
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				return i
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return open[len(open)-1]
+	}
+	return 0
+}
+
+// describeLabelPatternError names the invalid pattern in an annotation, pointing at the position of a syntax error.
+func describeLabelPatternError(pattern string, err error) string {
+	message := fmt.Sprintf("LABEL_PATTERN %q is invalid: %v", pattern, err)
+	var patternErr *labelPatternError
+	if errors.As(err, &patternErr) {
+		message += fmt.Sprintf("\n%s\n%s^", pattern, strings.Repeat(" ", patternErr.Column-1))
+	}
+	return message
+}
@@ -0,0 +1,119 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// projectColumn is the column of the project board a PR with label belongs to.
+type projectColumn struct {
+	label  string
+	column string
+}
+
+// parseProjectColumns parses PROJECT_COLUMNS, labels mapped to columns separated by ",",
+// e.g. `doc-required=Needs docs,doc-complete=Done`. The order is that of the workflow stages.
+func parseProjectColumns(slug string) ([]projectColumn, error) {
+	columns := []projectColumn{}
+	for _, entry := range strings.Split(slug, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		label, column, ok := strings.Cut(entry, "=")
+		label, column = strings.TrimSpace(label), strings.TrimSpace(column)
+		if !ok || len(label) == 0 || len(column) == 0 {
+			return nil, fmt.Errorf("%q is not label=column", entry)
+		}
+		columns = append(columns, projectColumn{label: label, column: column})
+	}
+	return columns, nil
+}
+
+// syncProjectStatus moves the current PR to the column of PROJECT following its labels, adding it to
+// the project if needed. The column is the status field option named after the latest stage whose label
+// is applied; the PR isn't moved if none is.
+func (a *Action) syncProjectStatus() error {
+	if len(a.config.GetProject()) == 0 || len(a.config.projectColumns) == 0 || a.client == nil {
+		return nil
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	applied := make(map[string]struct{})
+	for _, label := range labels {
+		applied[label] = struct{}{}
+	}
+	column := ""
+	for _, c := range a.config.projectColumns {
+		if _, exist := applied[c.label]; exist {
+			column = c.column
+		}
+	}
+	if len(column) == 0 {
+		return nil
+	}
+
+	org, slug, _ := strings.Cut(a.config.GetProject(), "/")
+	number, _ := strconv.Atoi(slug)
+	fields, _, err := a.client.Projects.ListOrganizationProjectFields(a.globalContext, org, number, nil)
+	if err != nil {
+		return fmt.Errorf("list fields of project %v: %w", a.config.GetProject(), err)
+	}
+	var fieldID int64
+	optionID := ""
+	for _, field := range fields {
+		if field.GetName() != a.config.GetProjectStatusField() {
+			continue
+		}
+		fieldID = field.GetID()
+		for _, option := range field.Options {
+			if option.GetName().GetRaw() == column {
+				optionID = option.GetID()
+			}
+		}
+	}
+	if len(optionID) == 0 {
+		return fmt.Errorf("project %v has no %v column %q", a.config.GetProject(), a.config.GetProjectStatusField(), column)
+	}
+
+	pr, err := a.getPullRequest()
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+
+	logger.Infof("@Move PR to %v of project %v\n", column, a.config.GetProject())
+	item, _, err := a.client.Projects.AddOrganizationProjectItem(a.globalContext, org, number,
+		&ghapi.AddProjectItemOptions{Type: "PullRequest", ID: pr.GetID()})
+	if err != nil {
+		return fmt.Errorf("add PR to project %v: %w", a.config.GetProject(), err)
+	}
+	_, _, err = a.client.Projects.UpdateOrganizationProjectItem(a.globalContext, org, number, item.GetID(),
+		&ghapi.UpdateProjectItemOptions{Fields: []*ghapi.UpdateProjectV2Field{{ID: fieldID, Value: optionID}}})
+	if err != nil {
+		return fmt.Errorf("update item of project %v: %w", a.config.GetProject(), err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxLoggedStringLength is the length strings of the event payload are truncated to in logs.
+const maxLoggedStringLength = 256
+
+// tokenRegexp matches GitHub and GitLab access tokens.
+var tokenRegexp = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,})`)
+
+// maskTokens replaces the access tokens in s.
+func maskTokens(s string) string {
+	return tokenRegexp.ReplaceAllString(s, "***")
+}
+
+// redactPayload returns a copy of the event payload v safe to log: tokens are masked, the fields
+// at the dotted paths of redacted are obfuscated, and other strings are truncated.
+func redactPayload(v interface{}, redacted map[string]struct{}) interface{} {
+	return redactValue(v, "", redacted)
+}
+
+func redactValue(v interface{}, path string, redacted map[string]struct{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			p := key
+			if len(path) > 0 {
+				p = path + "." + key
+			}
+			m[key] = redactValue(value, p, redacted)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = redactValue(value, path, redacted)
+		}
+		return s
+	case string:
+		if _, exist := redacted[path]; exist {
+			return fmt.Sprintf("[redacted %d bytes]", len(v))
+		}
+		v = maskTokens(v)
+		if len(v) > maxLoggedStringLength {
+			v = fmt.Sprintf("%s...[truncated %d bytes]", strings.ToValidUTF8(v[:maxLoggedStringLength], ""), len(v)-maxLoggedStringLength)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,56 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactPayload(t *testing.T) {
+	token := "ghp_" + strings.Repeat("a", 36)
+	event := map[string]interface{}{
+		"pull_request": map[string]interface{}{
+			"body":  "secret body",
+			"title": "leaked " + token,
+			"labels": []interface{}{
+				map[string]interface{}{"name": strings.Repeat("x", maxLoggedStringLength+10)},
+			},
+		},
+		"number": float64(1),
+	}
+
+	got := redactPayload(event, map[string]struct{}{"pull_request.body": {}}).(map[string]interface{})
+	pr := got["pull_request"].(map[string]interface{})
+	if pr["body"] != "[redacted 11 bytes]" {
+		t.Errorf("body = %q, want redacted", pr["body"])
+	}
+	if pr["title"] != "leaked ***" {
+		t.Errorf("title = %q, want token masked", pr["title"])
+	}
+	name := pr["labels"].([]interface{})[0].(map[string]interface{})["name"].(string)
+	if !strings.HasSuffix(name, "...[truncated 10 bytes]") {
+		t.Errorf("name = %q, want truncated", name)
+	}
+	if got["number"] != float64(1) {
+		t.Errorf("number = %v, want 1", got["number"])
+	}
+	if event["pull_request"].(map[string]interface{})["body"] != "secret body" {
+		t.Errorf("event was modified")
+	}
+}
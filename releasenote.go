@@ -0,0 +1,138 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const (
+	MessageReleaseNoteMissing = `Please describe the user-facing change of your PR under a "%s" heading in the PR description,
+or write NONE there if it doesn't need a release note.`
+
+	releaseNoteMissingMarker = markerPrefix + "release-note-missing -->"
+
+	labelReleaseNote     = "release-note"
+	labelReleaseNoteNone = "release-note-none"
+)
+
+var (
+	markdownHeadingRegexp = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	htmlCommentRegexp     = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// releaseNote returns the text under heading in body, with HTML comments removed.
+// The second result is false if body has no such heading.
+func releaseNote(body, heading string) (string, bool) {
+	found := false
+	note := &strings.Builder{}
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if m := markdownHeadingRegexp.FindStringSubmatch(line); m != nil {
+			if found {
+				break
+			}
+			found = strings.EqualFold(m[1], heading)
+			continue
+		}
+		if found {
+			note.WriteString(line)
+			note.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(htmlCommentRegexp.ReplaceAllString(note.String(), "")), found
+}
+
+// checkReleaseNote applies release-note or release-note-none depending on the release note
+// section of the PR body, and reminds the author once if the section is missing or empty,
+// applying the missing label of the release-note category if configured.
+func (a *Action) checkReleaseNote() error {
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+
+	note, _ := releaseNote(pr.Body, a.config.GetReleaseNoteHeading())
+	logger.Infof("Release note: %q\n", note)
+
+	want := ""
+	switch {
+	case len(note) == 0:
+	case strings.EqualFold(note, "none"):
+		want = labelReleaseNoteNone
+	default:
+		want = labelReleaseNote
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	has := make(map[string]struct{})
+	for _, label := range labels {
+		has[label] = struct{}{}
+	}
+
+	managed := []string{labelReleaseNote, labelReleaseNoteNone}
+	if missing, exist := a.config.labelMissingMap[missingCategoryReleaseNote]; exist {
+		managed = append(managed, missing)
+		if len(note) == 0 {
+			want = missing
+		}
+	}
+
+	for _, label := range managed {
+		if _, exist := has[label]; exist && label != want {
+			if err := a.provider.RemoveLabel(a.globalContext, a.config.GetNumber(), label); err != nil {
+				return fmt.Errorf("remove label %v: %w", label, err)
+			}
+		}
+	}
+	if _, exist := has[want]; len(want) > 0 && !exist {
+		if err := a.provider.AddLabels(a.globalContext, a.config.GetNumber(), []string{want}); err != nil {
+			return fmt.Errorf("add label %v: %w", want, err)
+		}
+	}
+
+	if len(note) == 0 {
+		return a.remindReleaseNote(pr)
+	}
+	return nil
+}
+
+// remindReleaseNote asks the author for a release note, unless already asked.
+func (a *Action) remindReleaseNote(pr *scm.PullRequest) error {
+	if a.client != nil {
+		comments, err := a.listBotComments()
+		if err != nil {
+			return fmt.Errorf("list comments: %w", err)
+		}
+		for _, c := range comments {
+			if strings.Contains(c.GetBody(), releaseNoteMissingMarker) {
+				return nil
+			}
+		}
+	}
+
+	return a.provider.Comment(a.globalContext, pr.Number, fmt.Sprintf("%s\n@%s %s", releaseNoteMissingMarker, pr.Author,
+		a.withGuide(fmt.Sprintf(MessageReleaseNoteMissing, a.config.GetReleaseNoteHeading()))))
+}
@@ -0,0 +1,115 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// MessageRemovalPending is posted when the checkboxes of labels are unchecked within REMOVAL_GRACE_PERIOD.
+const MessageRemovalPending = `The labels %s were unchecked and will be removed in %v, unless they are checked again.`
+
+// statePendingRemovals is the state key of the labels whose removal is deferred, with when they were unchecked.
+const statePendingRemovals = "pending removals"
+
+// now returns the current time, replaced in tests.
+var now = time.Now
+
+// deferRemovals drops from labelsToRemove the labels unchecked for less than REMOVAL_GRACE_PERIOD, recording when
+// they were first seen unchecked, so that an accidental edit of the PR template doesn't drop labels right away.
+// Labels no longer to remove, e.g. checked again, leave the pending removals.
+func (a *Action) deferRemovals(labelsToRemove map[string]struct{}) error {
+	grace := a.config.GetRemovalGracePeriod()
+	if grace <= 0 {
+		return nil
+	}
+
+	pending, err := a.loadPendingRemovals()
+	if err != nil {
+		return err
+	}
+
+	current := now().UTC()
+	next := make(map[string]time.Time)
+	unchecked := []string{}
+	for label := range labelsToRemove {
+		if a.config.isMissingLabel(label) {
+			continue
+		}
+		since, ok := pending[label]
+		if !ok {
+			since = current
+			unchecked = append(unchecked, label)
+		}
+		if current.Sub(since) >= grace {
+			logger.Infof("Label %v unchecked since %v, removing it\n", label, since)
+			continue
+		}
+		logger.Infof("Label %v unchecked since %v, deferring its removal\n", label, since)
+		next[label] = since
+		delete(labelsToRemove, label)
+	}
+
+	if samePendingRemovals(pending, next) {
+		return nil
+	}
+	message := ""
+	if len(unchecked) > 0 {
+		sort.Strings(unchecked)
+		message = fmt.Sprintf(MessageRemovalPending, formatLabels(unchecked), grace)
+	}
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	return a.getState().Save(statePendingRemovals, string(data), message)
+}
+
+// loadPendingRemovals returns the labels whose removal is deferred, with when they were unchecked.
+func (a *Action) loadPendingRemovals() (map[string]time.Time, error) {
+	value, err := a.getState().Load(statePendingRemovals)
+	if err != nil {
+		return nil, fmt.Errorf("load state: %w", err)
+	}
+	pending := make(map[string]time.Time)
+	if len(value) == 0 {
+		return pending, nil
+	}
+	if err := json.Unmarshal([]byte(value), &pending); err != nil {
+		logger.Infof("Ignore invalid pending removals %q: %v\n", value, err)
+		return make(map[string]time.Time), nil
+	}
+	return pending, nil
+}
+
+func samePendingRemovals(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for label, since := range a {
+		if other, ok := b[label]; !ok || !other.Equal(since) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// repoLabelCache shares the labels of each repository among the PRs handled in one process,
+// so that a batch fetches them once per repository rather than once per PR.
+type repoLabelCache struct {
+	mu     sync.Mutex
+	labels map[string][]string
+}
+
+func newRepoLabelCache() *repoLabelCache {
+	return &repoLabelCache{labels: make(map[string][]string)}
+}
+
+// shareRepoLabels makes a look up the repo labels in cache, keyed by the repository of its PR.
+func (a *Action) shareRepoLabels(cache *repoLabelCache) *Action {
+	a.provider = &cachedRepoLabelsProvider{
+		Provider: a.provider,
+		cache:    cache,
+		key:      a.config.GetOwner() + "/" + a.config.GetRepo(),
+	}
+	return a
+}
+
+type cachedRepoLabelsProvider struct {
+	scm.Provider
+	cache *repoLabelCache
+	key   string
+}
+
+// ListRepoLabels returns the cached labels of the repository, fetching them on first use.
+// Concurrent PRs of the same batch wait for the first fetch rather than sending their own.
+func (p *cachedRepoLabelsProvider) ListRepoLabels(ctx context.Context) ([]string, error) {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	labels, ok := p.cache.labels[p.key]
+	if !ok {
+		var err error
+		if labels, err = p.Provider.ListRepoLabels(ctx); err != nil {
+			return nil, err
+		}
+		p.cache.labels[p.key] = labels
+	}
+	return append([]string(nil), labels...), nil
+}
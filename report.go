@@ -0,0 +1,252 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/oidc"
+)
+
+// labelReport is the label statistics of the PRs created in a date range.
+type labelReport struct {
+	Since        string         `json:"since"`
+	Until        string         `json:"until"`
+	PullRequests int            `json:"pull_requests"`
+	LabelCounts  map[string]int `json:"label_counts"`
+	// AvgTimeToLabel is the average time from PR creation until the first watched label, in hours.
+	AvgTimeToLabel float64 `json:"avg_time_to_label_hours"`
+	// MedianTimeToLabel and P90TimeToLabel are percentiles of the time to label, in hours.
+	MedianTimeToLabel float64 `json:"median_time_to_label_hours"`
+	P90TimeToLabel    float64 `json:"p90_time_to_label_hours"`
+	// Unlabeled is the number of PRs which never got a watched label.
+	Unlabeled int `json:"unlabeled"`
+	// MissingPercent is the percentage of PRs that were labeled with the missing label.
+	MissingPercent float64 `json:"missing_percent"`
+}
+
+// runReport computes label statistics over the PRs created between REPORT_SINCE and REPORT_UNTIL,
+// and writes them to REPORT_OUTPUT, or to the step summary if not set, and posts them to REPORT_WEBHOOK_URL if set.
+func runReport(ctx context.Context, ac *ActionConfig) error {
+	report, err := labelStatistics(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+	if err != nil {
+		return err
+	}
+
+	output := reportOutput(ac, "label-report")
+	if err := writeReport(report, ac.GetReportFormat(), output); err != nil {
+		return err
+	}
+	if len(ac.GetReportUploadURL()) > 0 {
+		if err := uploadReport(output, ac.GetReportUploadURL()); err != nil {
+			return err
+		}
+	}
+	if len(ac.GetReportWebhookURL()) > 0 {
+		return postReport(ctx, ac.httpClient, report, ac.GetReportWebhookURL(), ac.GetReportWebhookAudience())
+	}
+	return nil
+}
+
+// labelStatistics computes the label statistics of the PRs created between REPORT_SINCE and REPORT_UNTIL with client.
+func labelStatistics(ctx context.Context, ac *ActionConfig, client *ghapi.Client) (*labelReport, error) {
+	query := fmt.Sprintf("repo:%s/%s is:pr created:%s..%s", ac.GetOwner(), ac.GetRepo(), ac.GetReportSince(), ac.GetReportUntil())
+	logger.Infof("@Search PRs: %v\n", query)
+	prs, err := searchIssues(ctx, client, query)
+	if err != nil {
+		return nil, fmt.Errorf("search PRs: %w", err)
+	}
+
+	report := &labelReport{
+		Since:        ac.GetReportSince(),
+		Until:        ac.GetReportUntil(),
+		PullRequests: len(prs),
+		LabelCounts:  make(map[string]int),
+	}
+	missing := 0
+	timesToLabel := []time.Duration{}
+	for _, pr := range prs {
+		for _, label := range pr.Labels {
+			if ac.isWatchedLabel(label.GetName()) {
+				report.LabelCounts[label.GetName()]++
+			}
+		}
+
+		timeline, err := listIssueTimeline(ctx, client, ac.GetOwner(), ac.GetRepo(), pr.GetNumber())
+		if err != nil {
+			logger.Errorf("#%d: %v\n", pr.GetNumber(), err)
+			continue
+		}
+		flagged, firstLabeledAt := false, time.Time{}
+		for _, event := range timeline {
+			if event.GetEvent() != "labeled" {
+				continue
+			}
+			name := event.GetLabel().GetName()
+			if name == ac.GetLabelMissing() {
+				flagged = true
+				continue
+			}
+			if ac.isWatchedLabel(name) && firstLabeledAt.IsZero() {
+				firstLabeledAt = event.GetCreatedAt().Time
+			}
+		}
+		if flagged {
+			missing++
+		}
+		if firstLabeledAt.IsZero() {
+			report.Unlabeled++
+			continue
+		}
+		timesToLabel = append(timesToLabel, firstLabeledAt.Sub(pr.GetCreatedAt().Time))
+	}
+	if len(timesToLabel) > 0 {
+		var total time.Duration
+		for _, d := range timesToLabel {
+			total += d
+		}
+		report.AvgTimeToLabel = (total / time.Duration(len(timesToLabel))).Hours()
+		report.MedianTimeToLabel = percentile(timesToLabel, 50).Hours()
+		report.P90TimeToLabel = percentile(timesToLabel, 90).Hours()
+	}
+	if len(prs) > 0 {
+		report.MissingPercent = float64(missing) * 100 / float64(len(prs))
+	}
+	return report, nil
+}
+
+// percentile returns the p-th percentile of durations by the nearest-rank method, sorting durations.
+func percentile(durations []time.Duration, p int) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := (p*len(durations) + 99) / 100
+	return durations[max(rank, 1)-1]
+}
+
+// postReport posts report as JSON to webhookURL through client, authenticated by the OIDC token of the run for audience,
+// which the receiving side verifies against the GitHub issuer instead of sharing a secret.
+func postReport(ctx context.Context, client *http.Client, report *labelReport, webhookURL, audience string) error {
+	token, err := oidc.Token(ctx, client, audience)
+	if err != nil {
+		return fmt.Errorf("get OIDC token: %w", err)
+	}
+	githubactions.AddMask(token)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	logger.Infoln("@Post report")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post report: %v", resp.Status)
+	}
+	return nil
+}
+
+// reportOutput returns the file the report is written to: report-output, or a file named after name
+// in the temporary directory when the report is only uploaded.
+func reportOutput(ac *ActionConfig, name string) string {
+	if len(ac.GetReportOutput()) > 0 || len(ac.GetReportUploadURL()) == 0 {
+		return ac.GetReportOutput()
+	}
+	return filepath.Join(os.TempDir(), name+"."+ac.GetReportFormat())
+}
+
+func writeReport(report *labelReport, format, output string) error {
+	labels := make([]string, 0, len(report.LabelCounts))
+	for label := range report.LabelCounts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	if len(output) == 0 {
+		summary := &strings.Builder{}
+		fmt.Fprintf(summary, "## Label report %s..%s\n\n", report.Since, report.Until)
+		fmt.Fprintf(summary, "- PRs: %d\n- Average time to label: %.1fh\n- Median time to label: %.1fh\n- 90th percentile time to label: %.1fh\n"+
+			"- Never labeled: %d\n- Flagged with missing label: %.1f%%\n\n",
+			report.PullRequests, report.AvgTimeToLabel, report.MedianTimeToLabel, report.P90TimeToLabel, report.Unlabeled, report.MissingPercent)
+		summary.WriteString("| Label | PRs |\n| --- | --- |\n")
+		for _, label := range labels {
+			fmt.Fprintf(summary, "| `%s` | %d |\n", label, report.LabelCounts[label])
+		}
+		githubactions.AddStepSummary(summary.String())
+		return nil
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	defer f.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		return nil
+	}
+
+	w := csv.NewWriter(f)
+	records := [][]string{
+		{"metric", "value"},
+		{"pull_requests", strconv.Itoa(report.PullRequests)},
+		{"avg_time_to_label_hours", strconv.FormatFloat(report.AvgTimeToLabel, 'f', 1, 64)},
+		{"median_time_to_label_hours", strconv.FormatFloat(report.MedianTimeToLabel, 'f', 1, 64)},
+		{"p90_time_to_label_hours", strconv.FormatFloat(report.P90TimeToLabel, 'f', 1, 64)},
+		{"unlabeled", strconv.Itoa(report.Unlabeled)},
+		{"missing_percent", strconv.FormatFloat(report.MissingPercent, 'f', 1, 64)},
+	}
+	for _, label := range labels {
+		records = append(records, []string{"label:" + label, strconv.Itoa(report.LabelCounts[label])})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
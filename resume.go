@@ -0,0 +1,116 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// statePendingLabels is the state key of the label changes which failed, with when they were recorded.
+const statePendingLabels = "pending labels"
+
+type pendingLabels struct {
+	Add    []string  `json:"add"`
+	Remove []string  `json:"remove"`
+	At     time.Time `json:"at"`
+}
+
+// editLabels applies the label changes to the current PR. If they fail, possibly halfway, they are recorded
+// in the state, so that the next event completes them instead of leaving the PR half-reconciled.
+func (a *Action) editLabels(add, remove []string) error {
+	err := a.provider.EditLabels(a.globalContext, a.config.GetNumber(), add, remove)
+	if err == nil || a.client == nil {
+		return err
+	}
+
+	data, merr := json.Marshal(&pendingLabels{Add: add, Remove: remove, At: now()})
+	if merr != nil {
+		return err
+	}
+	logger.Infoln("@Record pending label changes")
+	if serr := a.getState().Save(statePendingLabels, string(data), ""); serr != nil {
+		a.warn("Record pending label changes", serr)
+	}
+	return err
+}
+
+// resumeLabelChanges completes the label changes recorded by editLabels in an earlier run. The changes are applied
+// against the current labels, so that those already made before the failure are skipped, and the labels added or
+// removed since the record are left as they are, as whoever changed them decided.
+func (a *Action) resumeLabelChanges() error {
+	if a.client == nil {
+		return nil
+	}
+	value, err := a.getState().Load(statePendingLabels)
+	if err != nil {
+		return fmt.Errorf("load pending label changes: %w", err)
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	pending := &pendingLabels{}
+	if err := json.Unmarshal([]byte(value), pending); err != nil {
+		logger.Infof("Ignore invalid pending label changes: %v\n", err)
+		return a.getState().Save(statePendingLabels, "", "")
+	}
+
+	timeline, err := a.listTimeline()
+	if err != nil {
+		return fmt.Errorf("list timeline: %w", err)
+	}
+	changed := make(map[string]struct{})
+	for _, event := range timeline {
+		if (event.GetEvent() == "labeled" || event.GetEvent() == "unlabeled") && event.GetCreatedAt().After(pending.At) {
+			changed[event.GetLabel().GetName()] = struct{}{}
+		}
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	current := make(map[string]struct{})
+	for _, label := range labels {
+		current[label] = struct{}{}
+	}
+	add, remove := []string{}, []string{}
+	for _, label := range pending.Add {
+		_, exist := current[label]
+		if _, edited := changed[label]; !exist && !edited {
+			add = append(add, label)
+		}
+	}
+	for _, label := range pending.Remove {
+		_, exist := current[label]
+		if _, edited := changed[label]; exist && !edited {
+			remove = append(remove, label)
+		}
+	}
+
+	logger.Infof("@Resume label changes recorded at %v: add %v, remove %v\n", pending.At, add, remove)
+	if err := a.provider.EditLabels(a.globalContext, a.config.GetNumber(), add, remove); err != nil {
+		return fmt.Errorf("resume label changes: %w", err)
+	}
+	// the timeline is fetched again with the changes
+	a.timeline = nil
+	return a.getState().Save(statePendingLabels, "", "")
+}
@@ -0,0 +1,124 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+var reviewerMarkerRegexp = regexp.MustCompile(regexp.QuoteMeta(markerPrefix) + `reviewer label="(.*?)" reviewer="(.*?)" -->`)
+
+// assignReviewers requests one reviewer for each label in labels that has reviewers configured,
+// unless a reviewer was already requested for that label before.
+func (a *Action) assignReviewers(pr *scm.PullRequest, labels map[string]struct{}) error {
+	if len(a.config.reviewerMatrix) == 0 || a.client == nil {
+		return nil
+	}
+
+	pending := []string{}
+	for label := range labels {
+		if _, exist := a.config.reviewerMatrix[label]; exist {
+			pending = append(pending, label)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.Strings(pending)
+
+	logger.Infoln("@Assign reviewers")
+	comments, err := a.listBotComments()
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	assigned := make(map[string]struct{})
+	for _, comment := range comments {
+		for _, m := range reviewerMarkerRegexp.FindAllStringSubmatch(comment.GetBody(), -1) {
+			assigned[m[1]] = struct{}{}
+		}
+	}
+
+	for _, label := range pending {
+		if _, exist := assigned[label]; exist {
+			logger.Infof("Reviewer already assigned for %v\n", label)
+			continue
+		}
+
+		candidates := []string{}
+		for _, r := range a.config.reviewerMatrix[label] {
+			if r != pr.Author {
+				candidates = append(candidates, r)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		reviewer, err := a.pickReviewer(candidates, pr.Number)
+		if err != nil {
+			return fmt.Errorf("pick reviewer for %v: %w", label, err)
+		}
+
+		logger.Infof("Request review from %v for %v\n", reviewer, label)
+		_, _, err = a.client.PullRequests.RequestReviewers(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.Number,
+			ghapi.ReviewersRequest{Reviewers: []string{reviewer}})
+		if err != nil {
+			return fmt.Errorf("request reviewer %v: %w", reviewer, err)
+		}
+
+		err = a.provider.Comment(a.globalContext, pr.Number,
+			fmt.Sprintf("%sreviewer label=%q reviewer=%q -->\nRequested review from @%s for `%s`.", markerPrefix, label, reviewer, reviewer, label))
+		if err != nil {
+			return fmt.Errorf("create issue comment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pickReviewer selects one of candidates according to the configured assignment strategy.
+func (a *Action) pickReviewer(candidates []string, number int) (string, error) {
+	if a.config.GetReviewerAssignment() != "least-assigned" {
+		return candidates[number%len(candidates)], nil
+	}
+
+	prs, err := listOpenPullRequests(a.globalContext, a.client, a.config.GetOwner(), a.config.GetRepo())
+	if err != nil {
+		return "", err
+	}
+	load := make(map[string]int)
+	for _, pr := range prs {
+		for _, user := range pr.RequestedReviewers {
+			load[user.GetLogin()]++
+		}
+	}
+
+	reviewer := candidates[0]
+	for _, c := range candidates[1:] {
+		if load[c] < load[reviewer] {
+			reviewer = c
+		}
+	}
+	return reviewer, nil
+}
@@ -0,0 +1,121 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/glob"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// ContentRule applies Label when a line added in a changed file matching Paths matches Pattern.
+type ContentRule struct {
+	Label string `yaml:"label" schema:"required"`
+	// Paths are globs of the files to check, empty means all files
+	Paths   []string `yaml:"paths"`
+	Pattern string   `yaml:"pattern" schema:"required"`
+}
+
+// contentRuleLabels returns the labels deduced from the diff of the current PR by the content rules.
+func (a *Action) contentRuleLabels() (map[string]bool, error) {
+	labels := make(map[string]bool)
+
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(fc.ContentRules) == 0 {
+		return labels, nil
+	}
+
+	patterns := make([]*regexp.Regexp, len(fc.ContentRules))
+	for i, rule := range fc.ContentRules {
+		if patterns[i], err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("content rule for %v: %w", rule.Label, err)
+		}
+	}
+
+	files, err := a.listFiles()
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+
+	for _, file := range files {
+		added := addedLines(file.GetPatch())
+		for i, rule := range fc.ContentRules {
+			if labels[rule.Label] || !matchAny(rule.Paths, file.GetFilename()) {
+				continue
+			}
+			for _, line := range added {
+				if patterns[i].MatchString(line) {
+					logger.Infof("Content rule matched %v in %v: %v\n", rule.Label, file.GetFilename(), line)
+					labels[rule.Label] = true
+					break
+				}
+			}
+		}
+	}
+
+	return labels, nil
+}
+
+// listFiles lists the changed files of the current PR, including their patches.
+func (a *Action) listFiles() ([]*ghapi.CommitFile, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	files := make([]*ghapi.CommitFile, 0)
+	for {
+		f, resp, err := a.client.PullRequests.ListFiles(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return files, nil
+}
+
+// addedLines returns the lines added by a unified diff patch, without the leading `+`.
+func addedLines(patch string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			lines = append(lines, strings.TrimSuffix(line[1:], "\r"))
+		}
+	}
+	return lines
+}
+
+// matchAny reports whether name matches one of globs, or globs is empty.
+func matchAny(globs []string, name string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, g := range globs {
+		if glob.Match(g, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// runCache holds the resources fetched during a run, so that the features needing the same resource,
+// e.g. the checklist, path rules and size labels all needing the PR, share a single request.
+// Changes made through the provider invalidate the resources they affect.
+type runCache struct {
+	mu         sync.Mutex
+	repoLabels []string
+	prs        map[int]*scm.PullRequest
+	labels     map[int][]string
+	// pullRequest is the PR of the run as returned by the GitHub API, for the features needing more than scm.PullRequest
+	pullRequest *ghapi.PullRequest
+}
+
+// newCachedProvider wraps p so that its reads go through a new runCache.
+func newCachedProvider(p scm.Provider) *cachedProvider {
+	return &cachedProvider{
+		Provider: p,
+		cache: &runCache{
+			prs:    make(map[int]*scm.PullRequest),
+			labels: make(map[int][]string),
+		},
+	}
+}
+
+type cachedProvider struct {
+	scm.Provider
+	cache *runCache
+}
+
+func (p *cachedProvider) GetPR(ctx context.Context, number int) (*scm.PullRequest, error) {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	pr, ok := p.cache.prs[number]
+	if !ok {
+		var err error
+		if pr, err = p.Provider.GetPR(ctx, number); err != nil {
+			return nil, err
+		}
+		p.cache.prs[number] = pr
+	}
+	// callers may change the copy, e.g. its body before editing it
+	cp := *pr
+	return &cp, nil
+}
+
+func (p *cachedProvider) ListRepoLabels(ctx context.Context) ([]string, error) {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	if p.cache.repoLabels == nil {
+		labels, err := p.Provider.ListRepoLabels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.cache.repoLabels = labels
+	}
+	return append([]string(nil), p.cache.repoLabels...), nil
+}
+
+func (p *cachedProvider) ListLabels(ctx context.Context, number int) ([]string, error) {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	labels, ok := p.cache.labels[number]
+	if !ok {
+		var err error
+		if labels, err = p.Provider.ListLabels(ctx, number); err != nil {
+			return nil, err
+		}
+		p.cache.labels[number] = labels
+	}
+	return append([]string(nil), labels...), nil
+}
+
+func (p *cachedProvider) AddLabels(ctx context.Context, number int, labels []string) error {
+	defer p.invalidate(number, true)
+	return p.Provider.AddLabels(ctx, number, labels)
+}
+
+func (p *cachedProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	defer p.invalidate(number, true)
+	return p.Provider.RemoveLabel(ctx, number, label)
+}
+
+func (p *cachedProvider) EditLabels(ctx context.Context, number int, add, remove []string) error {
+	defer p.invalidate(number, true)
+	return p.Provider.EditLabels(ctx, number, add, remove)
+}
+
+func (p *cachedProvider) EditBody(ctx context.Context, number int, body string) error {
+	defer p.invalidate(number, false)
+	return p.Provider.EditBody(ctx, number, body)
+}
+
+func (p *cachedProvider) EditTitle(ctx context.Context, number int, title string) error {
+	defer p.invalidate(number, false)
+	return p.Provider.EditTitle(ctx, number, title)
+}
+
+// invalidate drops the cached PR number, and its labels if labels changed, once a change is sent.
+// Failed changes invalidate too, as they may have been partially applied.
+func (p *cachedProvider) invalidate(number int, labels bool) {
+	p.cache.invalidate(number, labels)
+}
+
+// invalidate drops the cached PR number, and its labels if labels may have changed.
+func (c *runCache) invalidate(number int, labels bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.prs, number)
+	if labels {
+		delete(c.labels, number)
+	}
+	c.pullRequest = nil
+}
+
+// getPullRequest gets the PR of the run from the GitHub API, once per run unless the PR changed since.
+func (a *Action) getPullRequest() (*ghapi.PullRequest, error) {
+	if a.cache != nil {
+		a.cache.mu.Lock()
+		defer a.cache.mu.Unlock()
+		if a.cache.pullRequest != nil {
+			return a.cache.pullRequest, nil
+		}
+	}
+	pr, _, err := a.client.PullRequests.Get(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+	if a.cache != nil {
+		a.cache.pullRequest = pr
+	}
+	return pr, nil
+}
@@ -0,0 +1,336 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// selftestBranchPrefix names the branches of the throwaway PRs opened by selftest.
+const selftestBranchPrefix = "docbot-selftest-"
+
+// runSelftestCommand runs `docbot selftest --repo owner/sandbox [--pr number]` with the flags in args.
+func runSelftestCommand(args []string) error {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	repo := flags.String("repo", "", "sandbox repository to test against, as owner/repo")
+	number := flags.Int("pr", 0, "designated test PR to use instead of opening a throwaway one")
+	if err := flags.Parse(args); err != nil {
+		return newComplianceError(failureConfig, err.Error())
+	}
+	if len(strings.Split(*repo, "/")) != 2 {
+		return newComplianceError(failureConfig, "selftest needs --repo owner/repo")
+	}
+
+	os.Setenv("GITHUB_REPOSITORY", *repo)
+	ac, err := NewActionConfig()
+	if err != nil {
+		return newComplianceError(failureConfig, fmt.Sprintf("get action config: %v", err))
+	}
+	if err := configureTransport(ac); err != nil {
+		return newComplianceError(failureConfig, fmt.Sprintf("configure transport: %v", err))
+	}
+	ctx := context.Background()
+	return runSelftest(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()), *number)
+}
+
+// selftestStep is a flow run against the test PR, with the assertion of its outcome.
+type selftestStep struct {
+	name string
+	// prepare changes the PR as its author or a maintainer would before the event
+	prepare func() error
+	event   string
+	check   func(labels []string, runErr error) error
+}
+
+// runSelftest runs the opened, edited and labeled flows against PR number of the sandbox repository,
+// or a throwaway PR if number is 0, asserting the resulting labels and comments.
+// The throwaway PR is closed and its branch deleted, a designated PR gets its body and labels back.
+func runSelftest(ctx context.Context, ac *ActionConfig, client *ghapi.Client, number int) error {
+	owner, repo := ac.GetOwner(), ac.GetRepo()
+	provider := scm.NewGitHub(client, owner, repo)
+	watched := (&Action{config: ac}).watchedLabels()
+	if len(watched) < 2 {
+		return newComplianceError(failureConfig, "selftest needs at least 2 labels in LABEL_WATCH_LIST")
+	}
+
+	required := append([]string{}, watched[:2]...)
+	if ac.GetEnableLabelMissing() {
+		required = append(required, ac.GetLabelMissing())
+	}
+	repoLabels, err := provider.ListRepoLabels(ctx)
+	if err != nil {
+		return fmt.Errorf("list repo labels: %w", err)
+	}
+	if lacking := subtractLabels(required, repoLabels); len(lacking) > 0 {
+		return newComplianceError(failureConfig, fmt.Sprintf("%v/%v lacks the labels %v", owner, repo, lacking))
+	}
+
+	checklist := renderChecklist(watched)
+	if number == 0 {
+		pr, cleanup, err := openSelftestPR(ctx, client, owner, repo, checklist)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		number = pr.GetNumber()
+	} else {
+		restore, err := resetSelftestPR(ctx, ac, provider, number, checklist)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	logger.Infof("@Selftest on %v/%v#%d\n", owner, repo, number)
+
+	steps := []selftestStep{
+		{
+			name:  "opened with no label checked",
+			event: "opened",
+			check: func(labels []string, runErr error) error {
+				if !ac.GetEnableLabelMissing() {
+					return runErr
+				}
+				if runErr == nil || failureClass(runErr) != failureLabelMissing {
+					return fmt.Errorf("got %w, want the missing label failure", runErr)
+				}
+				if !hasLabel(labels, ac.GetLabelMissing()) {
+					return fmt.Errorf("labels are %v, want %v", labels, ac.GetLabelMissing())
+				}
+				return checkSelftestReminder(ctx, ac, client, number)
+			},
+		},
+		{
+			name: fmt.Sprintf("edited to check %v", watched[0]),
+			prepare: func() error {
+				body, err := setCheckboxes(checklist, map[string]bool{watched[0]: true}, false)
+				if err != nil {
+					return err
+				}
+				return provider.EditBody(ctx, number, body)
+			},
+			event: "edited",
+			check: func(labels []string, runErr error) error {
+				if runErr != nil {
+					return runErr
+				}
+				if !hasLabel(labels, watched[0]) || hasLabel(labels, ac.GetLabelMissing()) {
+					return fmt.Errorf("labels are %v, want %v without %v", labels, watched[0], ac.GetLabelMissing())
+				}
+				return nil
+			},
+		},
+		{
+			name: fmt.Sprintf("labeled %v by a maintainer", watched[1]),
+			prepare: func() error {
+				return provider.EditLabels(ctx, number, []string{watched[1]}, []string{watched[0]})
+			},
+			event: "labeled",
+			check: func(labels []string, runErr error) error {
+				if runErr != nil {
+					return runErr
+				}
+				pr, err := provider.GetPR(ctx, number)
+				if err != nil {
+					return err
+				}
+				if !(&Action{config: ac}).extractLabels(pr.Body)[watched[1]] {
+					return fmt.Errorf("%v is not checked in the body", watched[1])
+				}
+				return nil
+			},
+		},
+	}
+
+	failed := 0
+	for _, step := range steps {
+		if err := runSelftestStep(ctx, ac, client, provider, number, step); err != nil {
+			failed++
+			logger.Errorf("FAIL %v: %v\n", step.name, err)
+			continue
+		}
+		logger.Infof("PASS %v\n", step.name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("selftest failed %v of %v steps", failed, len(steps))
+	}
+	return nil
+}
+
+func runSelftestStep(ctx context.Context, ac *ActionConfig, client *ghapi.Client, provider scm.Provider, number int, step selftestStep) error {
+	if step.prepare != nil {
+		if err := step.prepare(); err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+	}
+	pr, _, err := client.PullRequests.Get(ctx, ac.GetOwner(), ac.GetRepo(), number)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	runErr := newPullRequestAction(ctx, ac, client, ac.GetOwner(), ac.GetRepo(), pr).Run(step.event)
+	labels, err := provider.ListLabels(ctx, number)
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	return step.check(labels, runErr)
+}
+
+// checkSelftestReminder checks that the author of PR number was reminded in a comment, if reminders are comments.
+func checkSelftestReminder(ctx context.Context, ac *ActionConfig, client *ghapi.Client, number int) error {
+	if ac.GetNotifyMode() != "comment" || ac.GetCommentChannel() != "comment" {
+		return nil
+	}
+	action := &Action{config: ac.withNumber(number), globalContext: ctx, client: client}
+	comments, err := action.listIssueComments()
+	if err != nil {
+		return fmt.Errorf("list comments: %w", err)
+	}
+	for _, c := range comments {
+		if strings.Contains(c.GetBody(), markerPrefix+"reminder") {
+			return nil
+		}
+	}
+	return fmt.Errorf("no reminder comment")
+}
+
+// openSelftestPR opens a PR with body from a new branch of the default branch, changing a file.
+// The returned cleanup closes the PR and deletes the branch.
+func openSelftestPR(ctx context.Context, client *ghapi.Client, owner, repo, body string) (*ghapi.PullRequest, func(), error) {
+	repository, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get repo: %w", err)
+	}
+	base := repository.GetDefaultBranch()
+	ref, _, err := client.Git.GetRef(ctx, owner, repo, "heads/"+base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get %v: %w", base, err)
+	}
+
+	branch := fmt.Sprintf("%s%d", selftestBranchPrefix, time.Now().Unix())
+	logger.Infof("@Create branch %v\n", branch)
+	if _, _, err := client.Git.CreateRef(ctx, owner, repo, ghapi.CreateRef{Ref: "refs/heads/" + branch, SHA: ref.GetObject().GetSHA()}); err != nil {
+		return nil, nil, fmt.Errorf("create branch %v: %w", branch, err)
+	}
+	deleteBranch := func() {
+		if _, err := client.Git.DeleteRef(ctx, owner, repo, "heads/"+branch); err != nil {
+			logger.Errorf("Delete branch %v: %v\n", branch, err)
+		}
+	}
+
+	_, _, err = client.Repositories.CreateFile(ctx, owner, repo, branch+".md", &ghapi.RepositoryContentFileOptions{
+		Message: ghapi.String("docbot selftest"),
+		Content: []byte("Throwaway file of the docbot selftest.\n"),
+		Branch:  ghapi.String(branch),
+	})
+	if err != nil {
+		deleteBranch()
+		return nil, nil, fmt.Errorf("commit to %v: %w", branch, err)
+	}
+	pr, _, err := client.PullRequests.Create(ctx, owner, repo, &ghapi.NewPullRequest{
+		Title: ghapi.String("docbot selftest"),
+		Head:  ghapi.String(branch),
+		Base:  ghapi.String(base),
+		Body:  ghapi.String(body),
+	})
+	if err != nil {
+		deleteBranch()
+		return nil, nil, fmt.Errorf("open PR: %w", err)
+	}
+	logger.Infof("Opened %v\n", pr.GetHTMLURL())
+
+	return pr, func() {
+		logger.Infof("@Close #%d\n", pr.GetNumber())
+		_, _, err := client.PullRequests.Edit(ctx, owner, repo, pr.GetNumber(), &ghapi.PullRequest{State: ghapi.String("closed")})
+		if err != nil {
+			logger.Errorf("Close #%d: %v\n", pr.GetNumber(), err)
+		}
+		deleteBranch()
+	}, nil
+}
+
+// resetSelftestPR sets the body of PR number to the unchecked checklist and removes its watched and missing labels.
+// The returned restore sets back the body and labels it had.
+func resetSelftestPR(ctx context.Context, ac *ActionConfig, provider scm.Provider, number int, checklist string) (func(), error) {
+	pr, err := provider.GetPR(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("get PR: %w", err)
+	}
+	labels, err := provider.ListLabels(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("list labels: %w", err)
+	}
+	managed := []string{}
+	for _, label := range labels {
+		if ac.isWatchedLabel(label) || ac.isMissingLabel(label) {
+			managed = append(managed, label)
+		}
+	}
+	if err := provider.EditLabels(ctx, number, nil, managed); err != nil {
+		return nil, fmt.Errorf("remove labels: %w", err)
+	}
+	if err := provider.EditBody(ctx, number, checklist); err != nil {
+		return nil, fmt.Errorf("edit body: %w", err)
+	}
+
+	return func() {
+		logger.Infof("@Restore #%d\n", number)
+		current, err := provider.ListLabels(ctx, number)
+		if err == nil {
+			err = provider.EditLabels(ctx, number, subtractLabels(managed, current), subtractLabels(current, labels))
+		}
+		if err != nil {
+			logger.Errorf("Restore labels of #%d: %v\n", number, err)
+		}
+		if err := provider.EditBody(ctx, number, pr.Body); err != nil {
+			logger.Errorf("Restore body of #%d: %v\n", number, err)
+		}
+	}, nil
+}
+
+// hasLabel reports whether labels contains label.
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// subtractLabels returns the labels of a not in b.
+func subtractLabels(a, b []string) []string {
+	set := make(map[string]struct{})
+	for _, label := range b {
+		set[label] = struct{}{}
+	}
+	diff := []string{}
+	for _, label := range a {
+		if _, exist := set[label]; !exist {
+			diff = append(diff, label)
+		}
+	}
+	return diff
+}
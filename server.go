@@ -0,0 +1,326 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/ghapp"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/store"
+)
+
+const (
+	// repoConfigTTL is how long the config file of a repo served as a GitHub App is cached.
+	repoConfigTTL = 10 * time.Minute
+	// deliveryTTL is how long the handled deliveries are kept in the state db, GitHub redelivers those of the past 3 days
+	deliveryTTL = 7 * 24 * time.Hour
+	// pruneInterval is how often the expired deliveries are deleted from the state db
+	pruneInterval = time.Hour
+)
+
+type server struct {
+	config *ActionConfig
+	client *ghapi.Client
+	store  *store.Store
+
+	// app authenticates as the installation of each webhook instead of client, if APP_ID is set
+	app *ghapp.App
+	// repoConfigs caches the config files of the repos of the installations by full name
+	repoConfigs map[string]*repoConfig
+
+	// debouncer delays the edited events of a PR, if DEBOUNCE_WINDOW is set
+	debouncer *debouncer
+
+	// serializes handling so concurrent deliveries for one PR don't race
+	mu sync.Mutex
+}
+
+type repoConfig struct {
+	fileConfig *FileConfig
+	loadedAt   time.Time
+}
+
+// runServer serves GitHub webhooks as a long-lived service.
+func runServer(ctx context.Context, ac *ActionConfig) error {
+	var st *store.Store
+	if len(ac.GetStateDB()) > 0 {
+		var err error
+		st, err = store.Open(ac.GetStateDB())
+		if err != nil {
+			return fmt.Errorf("open state db: %w", err)
+		}
+		defer st.Close()
+		go pruneStore(st)
+	}
+
+	s := &server{
+		config:      ac,
+		client:      newGitHubClient(ctx, ac.GetToken(), ac.transport()),
+		store:       st,
+		repoConfigs: make(map[string]*repoConfig),
+	}
+	if ac.GetDebounceWindow() > 0 {
+		s.debouncer = newDebouncer(ac.GetDebounceWindow())
+	}
+	if ac.GetAppID() > 0 {
+		app, err := ghapp.New(ac.GetAppID(), []byte(ac.GetAppPrivateKey()), func(token string) *ghapi.Client {
+			return newGitHubClient(ctx, token, ac.transport())
+		})
+		if err != nil {
+			return fmt.Errorf("create app: %w", err)
+		}
+		s.app = app
+		// the installations comment as the bot user of the app, whose markers are the only trusted ones
+		botLogin, err := app.BotLogin(ctx)
+		if err != nil {
+			return fmt.Errorf("get app: %w", err)
+		}
+		ac.botLogin = &botLogin
+		if err := s.discover(ctx); err != nil {
+			return fmt.Errorf("discover installations: %w", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", s.handleWebhook)
+	mux.HandleFunc("/metrics", handleMetrics)
+
+	logger.Infof("@Listen on %v\n", ac.GetServerAddr())
+	return http.ListenAndServe(ac.GetServerAddr(), mux)
+}
+
+func (s *server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := ghapi.ValidatePayload(r, []byte(s.config.GetWebhookSecret()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	event, err := ghapi.ParseWebHook(ghapi.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.app != nil && s.handleInstallationEvent(r.Context(), event) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	prEvent, ok := event.(*ghapi.PullRequestEvent)
+	if !ok || !s.config.isEventEnabled(ghapi.WebHookType(r), event) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	deliveryID := ghapi.DeliveryID(r)
+	seen, err := s.store.HasDelivery(deliveryID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if seen {
+		logger.Infof("Skip duplicate delivery %v\n", deliveryID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	pr := prEvent.GetPullRequest()
+	key := fmt.Sprintf("%s#%d", prEvent.GetRepo().GetFullName(), pr.GetNumber())
+	newer, err := s.store.AdvancePullRequest(key, pr.GetUpdatedAt().Time)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !newer {
+		logger.Infof("Skip stale delivery %v for %v\n", deliveryID, key)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Handle only the latest of a burst of edits, e.g. while the author ticks the checkboxes one by one
+	if s.debouncer != nil && prEvent.GetAction() == "edited" {
+		logger.Infof("Debounce %v edited for %v\n", key, s.config.GetDebounceWindow())
+		s.debouncer.debounce(key, func() {
+			if err := s.handlePullRequest(context.Background(), prEvent, key); err != nil {
+				logger.Errorf("%v: %v\n", key, err)
+				return
+			}
+			s.markDelivery(deliveryID)
+		})
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := s.handlePullRequest(r.Context(), prEvent, key); err != nil {
+		logger.Errorf("%v: %v\n", key, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Only a handled delivery is marked, so that GitHub can redeliver a failed one
+	s.markDelivery(deliveryID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// markDelivery records the delivery id as handled. A failure is only logged, since the delivery was handled
+// and at worst a redelivery is handled again.
+func (s *server) markDelivery(id string) {
+	if err := s.store.MarkDelivery(id); err != nil {
+		logger.Errorf("Mark delivery %v: %v\n", id, err)
+	}
+}
+
+// pruneStore deletes the deliveries older than deliveryTTL from st every pruneInterval.
+func pruneStore(st *store.Store) {
+	for ; ; time.Sleep(pruneInterval) {
+		pruned, err := st.Prune(time.Now().Add(-deliveryTTL))
+		if err != nil {
+			logger.Errorf("Prune state db: %v\n", err)
+			continue
+		}
+		if pruned > 0 {
+			logger.Infof("Pruned %d expired entries of the state db\n", pruned)
+		}
+	}
+}
+
+// handlePullRequest runs the action for the pull_request event of the PR key. Failures of the action
+// are only logged, and the returned error is about authenticating or loading the config of the repo.
+func (s *server) handlePullRequest(ctx context.Context, prEvent *ghapi.PullRequestEvent, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logger.Infof("@Handle %v %v\n", key, prEvent.GetAction())
+	owner, repo := prEvent.GetRepo().GetOwner().GetLogin(), prEvent.GetRepo().GetName()
+	client := s.client
+	var fileConfig *FileConfig
+	if s.app != nil {
+		var err error
+		if client, err = s.app.Client(ctx, prEvent.GetInstallation().GetID()); err != nil {
+			return err
+		}
+		if fileConfig, err = s.repoFileConfig(ctx, client, owner, repo); err != nil {
+			return err
+		}
+	}
+	action := newPullRequestAction(ctx, s.config, client, owner, repo, prEvent.GetPullRequest())
+	action.fileConfig = fileConfig
+	if s.config.GetStateBackend() == "db" {
+		action.state = &dbState{action: action, store: s.store, prefix: key}
+	}
+	if err := action.Run(prEvent.GetAction()); err != nil {
+		logger.Errorf("%v: %v\n", key, err)
+	}
+	return nil
+}
+
+// discover lists the repos of all the installations of the app, and loads their config files.
+func (s *server) discover(ctx context.Context) error {
+	installations, err := s.app.Installations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, installation := range installations {
+		if err := s.discoverInstallation(ctx, installation.GetID()); err != nil {
+			logger.Errorf("Discover installation %d: %v\n", installation.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// discoverInstallation loads the config files of the repos of the installation id.
+// Repos whose config file is invalid are logged and skipped, so that they don't break the others.
+func (s *server) discoverInstallation(ctx context.Context, id int64) error {
+	client, err := s.app.Client(ctx, id)
+	if err != nil {
+		return err
+	}
+	repos, err := s.app.Repositories(ctx, id)
+	if err != nil {
+		return fmt.Errorf("list repos: %w", err)
+	}
+
+	logger.Infof("@Discover installation %d: %d repos\n", id, len(repos))
+	for _, repo := range repos {
+		delete(s.repoConfigs, repo.GetFullName())
+		if _, err := s.repoFileConfig(ctx, client, repo.GetOwner().GetLogin(), repo.GetName()); err != nil {
+			logger.Errorf("%v: %v\n", repo.GetFullName(), err)
+		}
+	}
+	return nil
+}
+
+// handleInstallationEvent keeps the repos up to date as the app is installed, uninstalled, or granted other repos,
+// reporting whether event was an installation event.
+func (s *server) handleInstallationEvent(ctx context.Context, event interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := int64(0)
+	removed := []*ghapi.Repository{}
+	switch e := event.(type) {
+	case *ghapi.InstallationEvent:
+		if e.GetAction() == "deleted" {
+			removed = e.Repositories
+		} else {
+			id = e.GetInstallation().GetID()
+		}
+	case *ghapi.InstallationRepositoriesEvent:
+		removed = e.RepositoriesRemoved
+		if len(e.RepositoriesAdded) > 0 {
+			id = e.GetInstallation().GetID()
+		}
+	default:
+		return false
+	}
+
+	for _, repo := range removed {
+		delete(s.repoConfigs, repo.GetFullName())
+	}
+	if id > 0 {
+		if err := s.discoverInstallation(ctx, id); err != nil {
+			logger.Errorf("Discover installation %d: %v\n", id, err)
+		}
+	}
+	return true
+}
+
+// repoFileConfig returns the config file of owner/repo, cached for repoConfigTTL.
+func (s *server) repoFileConfig(ctx context.Context, client *ghapi.Client, owner, repo string) (*FileConfig, error) {
+	fullName := owner + "/" + repo
+	if c, exist := s.repoConfigs[fullName]; exist && time.Since(c.loadedAt) < repoConfigTTL {
+		return c.fileConfig, nil
+	}
+
+	config := *s.config
+	config.owner, config.repo = &owner, &repo
+	action := &Action{config: &config, globalContext: ctx, client: client}
+	fc, err := action.getFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.repoConfigs[fullName] = &repoConfig{fileConfig: fc, loadedAt: time.Now()}
+	return fc, nil
+}
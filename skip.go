@@ -0,0 +1,48 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// isSkipped reports whether the current PR carries SKIP_LABEL, which opts it out of
+// the bot entirely. The result is reported in the skipped output.
+func (a *Action) isSkipped() (bool, error) {
+	if len(a.config.GetSkipLabel()) == 0 {
+		return false, nil
+	}
+
+	labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return false, fmt.Errorf("list labels: %w", err)
+	}
+	for _, label := range labels {
+		if label == a.config.GetSkipLabel() {
+			logger.Infof("PR #%d has label %v, skip it\n", a.config.GetNumber(), label)
+			githubactions.SetOutput("skipped", "true")
+			return true, nil
+		}
+	}
+	githubactions.SetOutput("skipped", "false")
+	return false, nil
+}
@@ -0,0 +1,101 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// samlErrorRegexp matches the error of a request denied because the token isn't authorized
+// for the SAML single sign-on enforced by the organization.
+var samlErrorRegexp = regexp.MustCompile(`: 403 .*SAML enforcement`)
+
+var (
+	ssoMu sync.Mutex
+	// ssoURL authorizes the token for the single sign-on of the organization, as told by the latest denied request
+	ssoURL string
+)
+
+// ssoTransport wraps base to remember the authorization URL of the requests denied by SAML enforcement,
+// which GitHub only gives in the X-GitHub-SSO header, like "required; url=https://github.com/orgs/apache/sso?...".
+type ssoTransport struct {
+	base http.RoundTripper
+}
+
+func (t *ssoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+	if required, url, found := strings.Cut(resp.Header.Get("X-GitHub-SSO"), "; url="); found && required == "required" {
+		ssoMu.Lock()
+		ssoURL = url
+		ssoMu.Unlock()
+	}
+	return resp, err
+}
+
+// samlHint returns how to fix err if it is due to SAML enforcement, or "" otherwise.
+func samlHint(err error) string {
+	if err == nil || !samlErrorRegexp.MatchString(err.Error()) {
+		return ""
+	}
+	ssoMu.Lock()
+	url := ssoURL
+	ssoMu.Unlock()
+
+	hint := "The token is not authorized for the SAML single sign-on of the organization."
+	if len(url) > 0 {
+		hint += fmt.Sprintf(" Authorize it at %s,", url)
+	} else {
+		hint += " Authorize it in the token settings under Configure SSO,"
+	}
+	return hint + " or set token-source to a token authorized for it, like the token of a GitHub App installed in the organization."
+}
+
+// githubToken returns the token read from TOKEN_SOURCE, either `env:NAME` or `file:PATH`, or GITHUB_TOKEN if not set.
+func githubToken() (string, error) {
+	source := getInput("token-source")
+	if len(source) == 0 {
+		return getInput("github-token"), nil
+	}
+
+	kind, value, _ := strings.Cut(source, ":")
+	switch kind {
+	case "env":
+		if token := os.Getenv(value); len(token) > 0 {
+			return token, nil
+		}
+		return "", fmt.Errorf("TOKEN_SOURCE %v is empty", source)
+	case "file":
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return "", fmt.Errorf("TOKEN_SOURCE %v: %w", source, err)
+		}
+		if token := strings.TrimSpace(string(data)); len(token) > 0 {
+			return token, nil
+		}
+		return "", fmt.Errorf("TOKEN_SOURCE %v is empty", source)
+	}
+	return "", fmt.Errorf("TOKEN_SOURCE is invalid, expected env:NAME or file:PATH: %v", source)
+}
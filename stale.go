@@ -0,0 +1,205 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/notify"
+)
+
+const (
+	MessageStaleWarning = `This PR has been missing a documentation label for a while and will be %s in %d days without a response.
+Please select a label, or comment if you need help.`
+
+	staleMarker = markerPrefix + "stale-warning -->"
+)
+
+type staleResult struct {
+	number int
+	title  string
+	action string
+}
+
+// runStale warns and then closes or converts to draft the open PRs which carried
+// the missing label for longer than the configured days without author response.
+func runStale(ctx context.Context, ac *ActionConfig) error {
+	return applyStale(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+}
+
+// applyStale applies the stale policy to the open PRs of the repository with client.
+func applyStale(ctx context.Context, ac *ActionConfig, client *ghapi.Client) error {
+	logger.Infoln("@List open PRs")
+	prs, err := listOpenPullRequests(ctx, client, ac.GetOwner(), ac.GetRepo())
+	if err != nil {
+		return fmt.Errorf("list open PRs: %w", err)
+	}
+
+	results := []staleResult{}
+	items := []notify.Item{}
+	for _, pr := range prs {
+		action := newPullRequestAction(ctx, ac, client, ac.GetOwner(), ac.GetRepo(), pr)
+		result, err := action.applyStalePolicy(pr)
+		if err != nil {
+			logger.Errorf("#%d: %v\n", pr.GetNumber(), err)
+			continue
+		}
+		if len(result) > 0 {
+			results = append(results, staleResult{number: pr.GetNumber(), title: pr.GetTitle(), action: result})
+			items = append(items, notify.Item{Number: pr.GetNumber(), Title: pr.GetTitle(), Author: pr.GetUser().GetLogin(),
+				Action: result, URL: pr.GetHTMLURL()})
+		}
+	}
+
+	summary := &strings.Builder{}
+	summary.WriteString("## Stale PRs missing labels\n\n")
+	if ac.GetStaleDryRun() {
+		summary.WriteString("Dry run, no changes were made.\n\n")
+	}
+	summary.WriteString("| PR | Title | Action |\n| --- | --- | --- |\n")
+	for _, r := range results {
+		fmt.Fprintf(summary, "| #%d | %s | %s |\n", r.number, strings.ReplaceAll(r.title, "|", "\\|"), r.action)
+	}
+	logger.Infof("Stale PRs: %v\n", len(results))
+	githubactions.AddStepSummary(summary.String())
+
+	if ac.GetStaleDryRun() {
+		return nil
+	}
+	return notifyAll(ctx, ac, &notify.Message{Event: "stale", Title: "Stale PRs missing labels", Items: items})
+}
+
+// applyStalePolicy returns the action taken on pr, or empty if pr is not stale.
+func (a *Action) applyStalePolicy(pr *ghapi.PullRequest) (string, error) {
+	missing := []string{}
+	for _, label := range pr.Labels {
+		if _, exempt := a.config.staleExemptLabels[label.GetName()]; exempt {
+			return "", nil
+		}
+		if label.GetName() == a.config.GetSkipLabel() {
+			return "", nil
+		}
+		if a.config.isMissingLabel(label.GetName()) {
+			missing = append(missing, label.GetName())
+		}
+	}
+	if len(missing) == 0 {
+		return "", nil
+	}
+
+	// the PR has been missing a label since the first of its missing labels was applied
+	var labeledAt time.Time
+	for _, label := range missing {
+		at, err := a.labeledAt(label)
+		if err != nil {
+			return "", fmt.Errorf("get labeled time: %w", err)
+		}
+		if !at.IsZero() && (labeledAt.IsZero() || at.Before(labeledAt)) {
+			labeledAt = at
+		}
+	}
+	if labeledAt.IsZero() || time.Since(labeledAt) < time.Duration(a.config.GetStaleDays())*24*time.Hour {
+		return "", nil
+	}
+
+	comments, err := a.listIssueComments()
+	if err != nil {
+		return "", fmt.Errorf("list comments: %w", err)
+	}
+	var warnedAt time.Time
+	for _, comment := range comments {
+		if comment.GetCreatedAt().Before(labeledAt) {
+			continue
+		}
+		if comment.GetUser().GetLogin() == pr.GetUser().GetLogin() {
+			return "", nil // author responded
+		}
+		// anyone can paste the marker, only the warning of the bot counts
+		if strings.Contains(comment.GetBody(), staleMarker) && a.isBotComment(comment) {
+			warnedAt = comment.GetCreatedAt().Time
+		}
+	}
+
+	verb := "closed"
+	if a.config.GetStaleAction() == "draft" {
+		verb = "converted to draft"
+	}
+
+	if warnedAt.IsZero() {
+		if !a.config.GetStaleDryRun() {
+			err = a.provider.Comment(a.globalContext, pr.GetNumber(),
+				fmt.Sprintf("%s\n@%s %s", staleMarker, pr.GetUser().GetLogin(),
+					fmt.Sprintf(MessageStaleWarning, verb, a.config.GetStaleWarningDays())))
+			if err != nil {
+				return "", fmt.Errorf("create issue comment: %w", err)
+			}
+		}
+		return "warned", nil
+	}
+
+	if time.Since(warnedAt) < time.Duration(a.config.GetStaleWarningDays())*24*time.Hour {
+		return "", nil
+	}
+
+	if !a.config.GetStaleDryRun() {
+		if a.config.GetStaleAction() == "draft" {
+			if pr.GetDraft() {
+				return "", nil
+			}
+			err = ghapi.GraphQL(a.globalContext, a.client,
+				`mutation($id: ID!) { convertPullRequestToDraft(input: {pullRequestId: $id}) { clientMutationId } }`,
+				map[string]interface{}{"id": pr.GetNodeID()}, nil)
+		} else {
+			_, _, err = a.client.PullRequests.Edit(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.GetNumber(),
+				&ghapi.PullRequest{State: ghapi.String("closed")})
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", verb, err)
+		}
+	}
+	return verb, nil
+}
+
+// labeledAt returns when label was last added to the current PR, or zero time if never.
+func (a *Action) labeledAt(label string) (time.Time, error) {
+	var at time.Time
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	for {
+		events, resp, err := a.client.Issues.ListIssueEvents(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return at, err
+		}
+		for _, event := range events {
+			if event.GetEvent() == "labeled" && event.GetLabel().GetName() == label {
+				at = event.GetCreatedAt().Time
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return at, nil
+}
@@ -0,0 +1,244 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/store"
+)
+
+// stateCheckRunName is the name of the check runs recording the state of a PR in their external_id.
+const stateCheckRunName = "docbot / state"
+
+// State persists the bookkeeping of the bot on the current PR, like when the author was last reminded,
+// as selected by STATE_BACKEND.
+type State interface {
+	// Load returns the value recorded under key, or "" if there's none.
+	Load(key string) (string, error)
+	// Save records value under key, posting message as a PR comment along if not empty.
+	Save(key, value, message string) error
+}
+
+// getState returns the State of the current PR, hidden comment markers unless STATE_BACKEND says otherwise.
+// The db state is set by the server, which owns the database.
+func (a *Action) getState() State {
+	if a.state == nil {
+		if a.config.GetStateBackend() == "check-run" {
+			a.state = &checkRunState{action: a}
+		} else {
+			a.state = &commentState{action: a}
+		}
+	}
+	return a.state
+}
+
+// commentState records values in hidden markers of the bot comments, like <!-- docbot:key="value" -->.
+// A value is saved with the comment it comes along with, or else updated in place in the last comment recording it.
+type commentState struct {
+	action *Action
+}
+
+func (s *commentState) Load(key string) (string, error) {
+	if s.action.client == nil {
+		return "", nil
+	}
+	comments, err := s.action.listBotComments()
+	if err != nil {
+		return "", fmt.Errorf("list comments: %w", err)
+	}
+
+	re := stateMarkerRegexp(key)
+	value := ""
+	for _, c := range comments {
+		for _, m := range re.FindAllStringSubmatch(c.GetBody(), -1) {
+			v := ""
+			if err := json.Unmarshal([]byte(m[1]), &v); err == nil {
+				value = v
+			}
+		}
+	}
+	return value, nil
+}
+
+func (s *commentState) Save(key, value, message string) error {
+	// json.Marshal escapes ">", so the value can't end the HTML comment
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	marker := fmt.Sprintf("%s%s=%s -->", markerPrefix, key, data)
+	a := s.action
+	if len(message) > 0 {
+		return a.provider.Comment(a.globalContext, a.config.GetNumber(), fmt.Sprintf("%s\n%s", marker, message))
+	}
+
+	// with nothing to tell, the marker is updated in place rather than posted as a blank-looking comment
+	comment, err := s.latest(key)
+	if err != nil {
+		return err
+	}
+	if comment == nil {
+		return a.provider.Comment(a.globalContext, a.config.GetNumber(), marker)
+	}
+	body := stateMarkerRegexp(key).ReplaceAllLiteralString(comment.GetBody(), marker)
+	// the provider appends the current version marker
+	body = versionMarkerRegexp.ReplaceAllString(body, "")
+	return a.provider.EditComment(a.globalContext, a.config.GetNumber(), comment.GetID(), body)
+}
+
+// latest returns the last bot comment recording a value under key, nil if there's none.
+func (s *commentState) latest(key string) (*ghapi.IssueComment, error) {
+	if s.action.client == nil {
+		return nil, nil
+	}
+	comments, err := s.action.listBotComments()
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	re := stateMarkerRegexp(key)
+	var comment *ghapi.IssueComment
+	for _, c := range comments {
+		if re.MatchString(c.GetBody()) {
+			comment = c
+		}
+	}
+	return comment, nil
+}
+
+// stateMarkerRegexp matches the markers recording a value under key, capturing the value as a JSON string.
+func stateMarkerRegexp(key string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(markerPrefix+key+"=") + `("(?:[^"\\]|\\.)*") -->`)
+}
+
+// checkRunState records the values as JSON in the external_id of a neutral check run on the PR head,
+// so no comment is needed, but the values are lost when new commits are pushed.
+type checkRunState struct {
+	action *Action
+}
+
+func (s *checkRunState) Load(key string) (string, error) {
+	_, values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return values[key], nil
+}
+
+func (s *checkRunState) Save(key, value, message string) error {
+	a := s.action
+	if len(message) > 0 {
+		if err := a.provider.Comment(a.globalContext, a.config.GetNumber(), message); err != nil {
+			return err
+		}
+	}
+
+	headSHA, values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if a.client == nil || len(headSHA) == 0 || !a.config.features().checkRuns {
+		return nil
+	}
+	values[key] = value
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = a.client.Checks.CreateCheckRun(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), ghapi.CreateCheckRunOptions{
+		Name:       stateCheckRunName,
+		HeadSHA:    headSHA,
+		ExternalID: ghapi.String(string(data)),
+		Status:     ghapi.String("completed"),
+		Conclusion: ghapi.String("neutral"),
+		Output: &ghapi.CheckRunOutput{
+			Title:   ghapi.String("Bot state"),
+			Summary: ghapi.String("Bookkeeping of the bot, ignore it."),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create check run: %w", err)
+	}
+	return nil
+}
+
+// load returns the PR head and the values of its latest state check run.
+func (s *checkRunState) load() (string, map[string]string, error) {
+	a := s.action
+	values := make(map[string]string)
+	if a.client == nil {
+		return "", values, nil
+	}
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return "", nil, fmt.Errorf("get PR: %w", err)
+	}
+	if len(pr.HeadSHA) == 0 {
+		return "", values, nil
+	}
+
+	result, _, err := a.client.Checks.ListCheckRunsForRef(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), pr.HeadSHA,
+		&ghapi.ListCheckRunsOptions{CheckName: ghapi.String(stateCheckRunName)})
+	if err != nil {
+		return "", nil, fmt.Errorf("list check runs: %w", err)
+	}
+	// any app of the repo can create a check run named like the state, only those of the bot are trusted
+	slug := strings.TrimSuffix(a.config.GetBotLogin(), "[bot]")
+	var latest *ghapi.CheckRun
+	for _, c := range result.CheckRuns {
+		if c.GetApp().GetSlug() != slug {
+			continue
+		}
+		if latest == nil || c.GetID() > latest.GetID() {
+			latest = c
+		}
+	}
+	if latest != nil && len(latest.GetExternalID()) > 0 {
+		if err := json.Unmarshal([]byte(latest.GetExternalID()), &values); err != nil {
+			return "", nil, fmt.Errorf("parse state of check run %d: %w", latest.GetID(), err)
+		}
+	}
+	return pr.HeadSHA, values, nil
+}
+
+// dbState records the values in the state db of the server, under the key of the PR.
+type dbState struct {
+	action *Action
+	store  *store.Store
+	// prefix is the key of the PR, like owner/repo#1
+	prefix string
+}
+
+func (s *dbState) Load(key string) (string, error) {
+	return s.store.Get(s.prefix + "/" + key)
+}
+
+func (s *dbState) Save(key, value, message string) error {
+	a := s.action
+	if len(message) > 0 {
+		if err := a.provider.Comment(a.globalContext, a.config.GetNumber(), message); err != nil {
+			return err
+		}
+	}
+	return s.store.Put(s.prefix+"/"+key, value)
+}
@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// listTimeline lists all timeline events of the current PR, oldest first.
+// The timeline is fetched once per run.
+func (a *Action) listTimeline() ([]*ghapi.Timeline, error) {
+	if a.timeline != nil {
+		return a.timeline, nil
+	}
+
+	timeline, err := listIssueTimeline(a.globalContext, a.client, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+	a.timeline = timeline
+	return timeline, nil
+}
+
+func listIssueTimeline(ctx context.Context, client *ghapi.Client, owner, repo string, number int) ([]*ghapi.Timeline, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	timeline := make([]*ghapi.Timeline, 0)
+	for {
+		t, resp, err := client.Issues.ListIssueTimeline(ctx, owner, repo, number, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, t...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return timeline, nil
+}
+
+// keepHumanLabels drops from labelsToRemove the labels last applied by someone other than the bot,
+// unless FORCE_MANAGE is set. The missing label is always managed by the bot.
+func (a *Action) keepHumanLabels(labelsToRemove map[string]struct{}) error {
+	if a.config.GetForceManage() || a.client == nil || len(labelsToRemove) == 0 {
+		return nil
+	}
+
+	timeline, err := a.listTimeline()
+	if err != nil {
+		return err
+	}
+	appliers := make(map[string]string)
+	for _, event := range timeline {
+		if event.GetEvent() == "labeled" {
+			appliers[event.GetLabel().GetName()] = event.GetActor().GetLogin()
+		}
+	}
+
+	for label := range labelsToRemove {
+		if a.config.isMissingLabel(label) {
+			continue
+		}
+		if applier := appliers[label]; applier != a.config.GetBotLogin() {
+			logger.Infof("Keep label %v applied by %v\n", label, applier)
+			delete(labelsToRemove, label)
+		}
+	}
+	return nil
+}
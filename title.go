@@ -0,0 +1,81 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+const MessageTitlePrefixMismatch = "The PR title should start with one of %s to match the selected label."
+
+// titlePrefixRegexp matches a leading [prefix] of a PR title.
+var titlePrefixRegexp = regexp.MustCompile(`^\s*\[([^\]]*)\]\s*`)
+
+// checkTitlePrefix verifies that the PR title starts with [label] for one of the checked
+// watched labels. Depending on TITLE_PREFIX, a mismatch is fixed by editing the title,
+// or reported as a failed check run.
+func (a *Action) checkTitlePrefix() error {
+	checked := make(map[string]struct{})
+	prefixes := []string{}
+	for _, label := range a.watchedLabels() {
+		if a.config.labels[label] {
+			checked[label] = struct{}{}
+			prefixes = append(prefixes, fmt.Sprintf("`[%s]`", label))
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+
+	title := pr.Title
+	if m := titlePrefixRegexp.FindStringSubmatch(title); m != nil {
+		if _, ok := checked[m[1]]; ok {
+			return nil
+		}
+		// Replace the prefix of another watched label, keep any other prefix
+		if a.config.isWatchedLabel(m[1]) {
+			title = title[len(m[0]):]
+		}
+	}
+
+	message := fmt.Sprintf(MessageTitlePrefixMismatch, strings.Join(prefixes, ", "))
+	logger.Infof("Title %q mismatches labels: %v\n", pr.Title, message)
+
+	if a.config.GetTitlePrefix() == "fix" {
+		title = strings.TrimSuffix(strings.TrimPrefix(prefixes[0], "`"), "`") + " " + strings.TrimSpace(title)
+		logger.Infof("@Edit title to %q\n", title)
+		if err := a.provider.EditTitle(a.globalContext, pr.Number, title); err != nil {
+			return fmt.Errorf("edit title: %w", err)
+		}
+		return nil
+	}
+
+	if err := a.createCheckRun(pr.HeadSHA, "failure", "PR title doesn't match the label", message); err != nil {
+		return err
+	}
+	return newComplianceError(failureNonCompliant, message)
+}
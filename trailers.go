@@ -0,0 +1,127 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// CommitTrailer applies a label from the value of a trailer in the commit messages of the PR,
+// e.g. `Docs-Impact: yes`.
+type CommitTrailer struct {
+	Key string `yaml:"key" schema:"required"`
+	// Labels maps a trailer value, case-insensitively, to the label it applies
+	Labels map[string]string `yaml:"labels" schema:"required"`
+}
+
+// trailerRegexp matches a `Key: value` trailer line.
+var trailerRegexp = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*?)\s*$`)
+
+// commitTrailers returns the trailers of a commit message, keyed by their lower-cased key.
+// Only the last paragraph of the message is considered, as git does.
+func commitTrailers(message string) map[string]string {
+	trailers := make(map[string]string)
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	paragraphs := strings.Split(message, "\n\n")
+	if len(paragraphs) < 2 {
+		return trailers
+	}
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if m := trailerRegexp.FindStringSubmatch(line); m != nil {
+			trailers[strings.ToLower(m[1])] = m[2]
+		}
+	}
+	return trailers
+}
+
+// applyCommitTrailers merges the labels deduced from the commit trailers into the expected labels.
+// The trailer of the latest commit wins.
+func (a *Action) applyCommitTrailers() error {
+	if a.client == nil {
+		return nil
+	}
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return err
+	}
+	if len(fc.CommitTrailers) == 0 {
+		return nil
+	}
+
+	commits, err := a.listCommits()
+	if err != nil {
+		return fmt.Errorf("list commits: %w", err)
+	}
+
+	values := make(map[string]string)
+	for _, commit := range commits {
+		for key, value := range commitTrailers(commit.GetCommit().GetMessage()) {
+			values[key] = value
+		}
+	}
+
+	labels := make(map[string]bool)
+	for _, trailer := range fc.CommitTrailers {
+		value, ok := values[strings.ToLower(trailer.Key)]
+		if !ok {
+			continue
+		}
+		for v, label := range trailer.Labels {
+			if strings.EqualFold(v, value) {
+				logger.Infof("Commit trailer %v: %v applies %v\n", trailer.Key, value, label)
+				labels[label] = true
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	if a.ruleLabels == nil {
+		a.ruleLabels = make(map[string]bool)
+	}
+	if a.config.labels == nil {
+		a.config.labels = make(map[string]bool)
+	}
+	mergeLabels(a.ruleLabels, labels)
+	mergeLabels(a.config.labels, labels)
+	return nil
+}
+
+// listCommits lists the commits of the current PR, oldest first.
+func (a *Action) listCommits() ([]*ghapi.RepositoryCommit, error) {
+	listOptions := &ghapi.ListOptions{PerPage: 100}
+	commits := make([]*ghapi.RepositoryCommit, 0)
+	for {
+		c, resp, err := a.client.PullRequests.ListCommits(a.globalContext, a.config.GetOwner(), a.config.GetRepo(), a.config.GetNumber(), listOptions)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c...)
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+	return commits, nil
+}
@@ -0,0 +1,43 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommitTrailers(t *testing.T) {
+	tests := []struct {
+		message string
+		want    map[string]string
+	}{
+		{"Fix typo", map[string]string{}},
+		{"Fix: typo in the subject", map[string]string{}},
+		{"Add config\n\nDocs-Impact: yes\nSigned-off-by: a <a@b.c>", map[string]string{
+			"docs-impact":   "yes",
+			"signed-off-by": "a <a@b.c>",
+		}},
+		{"Add config\r\n\r\nNote: not a trailer\r\n\r\ndocs-impact: No \r\n", map[string]string{"docs-impact": "No"}},
+	}
+	for _, tt := range tests {
+		if got := commitTrailers(tt.message); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commitTrailers(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/httplog"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// tlsVersions are the values of TLS_MIN_VERSION.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// configureTransport sets up the transport of the HTTP client of ac, shared by all API clients: requests go
+// through the proxy of HTTPS_PROXY unless excluded by NO_PROXY, and servers are also trusted
+// if their certificate is signed by a CA in the PEM bundle of CA_BUNDLE. Each request is
+// canceled after HTTP_TIMEOUT, so that a stalled connection doesn't hang the run. With
+// DEBUG_HTTP, every request is logged.
+func configureTransport(ac *ActionConfig) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConns = ac.GetMaxIdleConns()
+	transport.MaxIdleConnsPerHost = ac.GetMaxIdleConns()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.MinVersion = ac.GetTLSMinVersion()
+
+	if caBundle := ac.GetCABundle(); len(caBundle) > 0 {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA bundle %v", caBundle)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	var base http.RoundTripper = transport
+	if ac.GetDebugHTTP() {
+		base = httplog.Transport(transport)
+	}
+	if timeout := ac.GetHTTPTimeout(); timeout > 0 {
+		base = &timeoutTransport{base: base, timeout: timeout}
+	}
+	ac.httpClient.Transport = &ssoTransport{base: base}
+	return nil
+}
+
+// installAPITransport wraps the transport of the GitHub API requests of every mode, so that they are
+// recorded into RECORD_DIR or replayed from REPLAY_DIR, and with PLAN_ONLY, the mutating ones aren't sent.
+// It returns the fixture transport.
+func installAPITransport(ac *ActionConfig) (http.RoundTripper, error) {
+	transport, err := newFixtureTransport(ac)
+	if err != nil {
+		return nil, fmt.Errorf("create fixture transport: %w", err)
+	}
+	ac.apiTransport = transport
+	if ac.GetPlanOnly() {
+		logger.Infoln("@Plan only, mutating requests are not sent")
+		ac.apiTransport = planOnlyTransport(transport)
+	}
+	return transport, nil
+}
+
+// transport returns the transport of the GitHub API requests, installed by installAPITransport,
+// or else set up by configureTransport, nil for the default one.
+func (ac *ActionConfig) transport() http.RoundTripper {
+	if ac == nil {
+		return nil
+	}
+	if ac.apiTransport != nil {
+		return ac.apiTransport
+	}
+	if ac.httpClient == nil {
+		return nil
+	}
+	return ac.httpClient.Transport
+}
+
+// timeoutTransport cancels each request through base if it isn't done after timeout,
+// including reading the response body.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %v: %w", t.timeout, err)
+		}
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the context of a response once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,171 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const MessageLabelsChanged = "Labels changed: %s. Comment `" + undoCommand + "` to revert these changes."
+
+// stateLabelSnapshot is the state key of the labels of the PR around the latest run changing them.
+const stateLabelSnapshot = "label snapshot"
+
+// labelSnapshot records the labels of a PR before and after a run changing them, and the checkboxes of its body before.
+type labelSnapshot struct {
+	Before  []string        `json:"before"`
+	After   []string        `json:"after"`
+	Checked map[string]bool `json:"checked"`
+}
+
+// snapshotLabels reads the labels and checkboxes of the current PR before the run, and returns the function
+// recording them at the end of the run if the labels changed, so that the changes can be undone.
+func (a *Action) snapshotLabels() func() {
+	before, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		a.warn("Snapshot labels", err)
+		return func() {}
+	}
+	pr, err := a.provider.GetPR(a.globalContext, a.config.GetNumber())
+	if err != nil {
+		a.warn("Snapshot labels", err)
+		return func() {}
+	}
+	checked := a.extractLabels(pr.Body)
+
+	return func() {
+		after, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+		if err != nil {
+			a.warn("Snapshot labels", err)
+			return
+		}
+		added, removed := diffLabels(before, after)
+		if len(added) == 0 && len(removed) == 0 {
+			return
+		}
+
+		data, err := json.Marshal(labelSnapshot{Before: before, After: after, Checked: checked})
+		if err != nil {
+			a.warn("Snapshot labels", err)
+			return
+		}
+		changes := []string{}
+		for _, label := range added {
+			changes = append(changes, fmt.Sprintf("added `%s`", label))
+		}
+		for _, label := range removed {
+			changes = append(changes, fmt.Sprintf("removed `%s`", label))
+		}
+		message := fmt.Sprintf(MessageLabelsChanged, strings.Join(changes, ", "))
+		if err := a.getState().Save(stateLabelSnapshot, string(data), message); err != nil {
+			a.warn("Save label snapshot", err)
+		}
+	}
+}
+
+// undo reverts the label changes of the latest snapshot on pr, leaving the later changes alone,
+// and restores the checkboxes of its body before them.
+func (a *Action) undo(pr *scm.PullRequest) error {
+	value, err := a.getState().Load(stateLabelSnapshot)
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+	if len(value) == 0 {
+		logger.Infof("No label changes to undo on PR #%d\n", pr.Number)
+		return nil
+	}
+	snapshot := labelSnapshot{}
+	if err := json.Unmarshal([]byte(value), &snapshot); err != nil {
+		return fmt.Errorf("parse label snapshot: %w", err)
+	}
+
+	current, err := a.provider.ListLabels(a.globalContext, pr.Number)
+	if err != nil {
+		return fmt.Errorf("list labels: %w", err)
+	}
+	currentSet := make(map[string]struct{}, len(current))
+	for _, label := range current {
+		currentSet[label] = struct{}{}
+	}
+	added, removed := diffLabels(snapshot.Before, snapshot.After)
+	add, remove := []string{}, []string{}
+	for _, label := range removed {
+		if _, ok := currentSet[label]; !ok {
+			add = append(add, label)
+		}
+	}
+	for _, label := range added {
+		if _, ok := currentSet[label]; ok {
+			remove = append(remove, label)
+		}
+	}
+	if len(add) > 0 || len(remove) > 0 {
+		logger.Infof("@Undo label changes: add %v, remove %v\n", add, remove)
+		if err := a.provider.EditLabels(a.globalContext, pr.Number, add, remove); err != nil {
+			return fmt.Errorf("edit labels: %w", err)
+		}
+	}
+
+	changeList := make(map[string]bool)
+	checked := a.extractLabels(pr.Body)
+	for label, wasChecked := range snapshot.Checked {
+		if isChecked, ok := checked[label]; !ok || isChecked != wasChecked {
+			changeList[label] = wasChecked
+		}
+	}
+	if len(changeList) > 0 {
+		logger.Infof("@Undo checkbox changes: %v\n", changeList)
+		if err := a.editCheckboxes(pr, changeList); err != nil {
+			return fmt.Errorf("edit PR: %w", err)
+		}
+	}
+	return nil
+}
+
+// diffLabels returns the sorted labels in after but not before, and those in before but not after.
+func diffLabels(before, after []string) ([]string, []string) {
+	beforeSet := make(map[string]struct{}, len(before))
+	for _, label := range before {
+		beforeSet[label] = struct{}{}
+	}
+	afterSet := make(map[string]struct{}, len(after))
+	for _, label := range after {
+		afterSet[label] = struct{}{}
+	}
+
+	added, removed := []string{}, []string{}
+	for label := range afterSet {
+		if _, ok := beforeSet[label]; !ok {
+			added = append(added, label)
+		}
+	}
+	for label := range beforeSet {
+		if _, ok := afterSet[label]; !ok {
+			removed = append(removed, label)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,181 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+const MessageLabelsUnknown = `The checked labels %s don't exist in this repository and were ignored, please check their spelling.`
+
+// stateLabelsUnknown is the state key of the unknown labels the author was last told about.
+const stateLabelsUnknown = "labels unknown"
+
+// maxSuggestionDistance is the largest edit distance between an unknown label and the label suggested for it.
+const maxSuggestionDistance = 2
+
+// unknownLabel is a checked label of the PR body missing from the repository.
+type unknownLabel struct {
+	name string
+	// suggestion is the closest label within maxSuggestionDistance, if any
+	suggestion string
+	distance   int
+	// corrected is whether suggestion was applied instead, with AUTOCORRECT_LABELS
+	corrected bool
+}
+
+func (u unknownLabel) String() string {
+	switch {
+	case u.corrected:
+		return fmt.Sprintf("`%s` (corrected to `%s`)", u.name, u.suggestion)
+	case len(u.suggestion) > 0:
+		return fmt.Sprintf("`%s` (did you mean `%s`?)", u.name, u.suggestion)
+	}
+	return fmt.Sprintf("`%s`", u.name)
+}
+
+// findUnknownLabels returns the checked labels missing from the repository: the watched ones, and the checkboxes of
+// the body close to a watched label, which are likely misspelled. When watching every checkbox, suggestions are
+// made among the repository labels.
+func (a *Action) findUnknownLabels(pr *scm.PullRequest, repoLabels []string) []unknownLabel {
+	inRepo := make(map[string]struct{}, len(repoLabels))
+	for _, label := range repoLabels {
+		inRepo[label] = struct{}{}
+	}
+	candidates := a.watchedLabels()
+	if a.config.watchesAll() {
+		candidates = repoLabels
+	}
+
+	unknown := []unknownLabel{}
+	for label, checked := range a.config.labels {
+		if _, exist := inRepo[label]; exist || !checked {
+			continue
+		}
+		u := unknownLabel{name: label}
+		if a.config.watchesAll() {
+			u.suggestion, u.distance = suggestLabel(label, candidates)
+		}
+		unknown = append(unknown, u)
+	}
+	for label, checked := range a.extractCheckboxes(pr.Body) {
+		if !checked || a.config.isWatchedLabel(label) || a.config.isMissingLabel(label) {
+			continue
+		}
+		if suggestion, distance := suggestLabel(label, candidates); len(suggestion) > 0 {
+			unknown = append(unknown, unknownLabel{name: label, suggestion: suggestion, distance: distance})
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].name < unknown[j].name })
+	return unknown
+}
+
+// autocorrectLabels checks the suggestion of each unknown label one edit away from it instead, if it is in the repository.
+func autocorrectLabels(unknown []unknownLabel, repoLabelsSet map[string]struct{}, expectedLabelsMap map[string]bool) {
+	for i, u := range unknown {
+		if _, exist := repoLabelsSet[u.suggestion]; !exist || u.distance != 1 {
+			continue
+		}
+		logger.Infof("Correct label %v to %v\n", u.name, u.suggestion)
+		expectedLabelsMap[u.suggestion] = true
+		unknown[i].corrected = true
+	}
+}
+
+// reportUnknownLabels surfaces the unknown labels checked in the body of pr, which are otherwise ignored: as a warning
+// annotation, in the labels-unknown output, and with COMMENT_UNKNOWN_LABELS, in a comment unless the author was already
+// told about the same labels.
+func (a *Action) reportUnknownLabels(pr *scm.PullRequest, unknown []unknownLabel) {
+	names := make([]string, len(unknown))
+	descriptions := make([]string, len(unknown))
+	for i, u := range unknown {
+		names[i] = u.name
+		descriptions[i] = u.String()
+	}
+	githubactions.SetOutput("labels-unknown", strings.Join(names, ","))
+	if len(unknown) == 0 {
+		return
+	}
+	githubactions.Warningf("PR #%d: checked labels not in the repository are ignored: %v", pr.Number, strings.Join(descriptions, ", "))
+
+	if !a.config.GetCommentUnknownLabels() {
+		return
+	}
+	value := strings.Join(descriptions, ",")
+	last, err := a.getState().Load(stateLabelsUnknown)
+	if err != nil {
+		a.warn("Load unknown labels", err)
+		return
+	}
+	if last == value {
+		return
+	}
+	message := fmt.Sprintf("@%s %s", pr.Author, fmt.Sprintf(MessageLabelsUnknown, strings.Join(descriptions, ", ")))
+	if err := a.getState().Save(stateLabelsUnknown, value, message); err != nil {
+		a.warn("Comment unknown labels", err)
+	}
+}
+
+// suggestLabel returns the first of the sorted candidates closest to label within maxSuggestionDistance, and its
+// distance, or "" if there's none. Short labels need a closer candidate, so that `doc` isn't taken for `bug`.
+func suggestLabel(label string, candidates []string) (string, int) {
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+	suggestion, best := "", maxSuggestionDistance+1
+	for _, candidate := range sorted {
+		if candidate == label {
+			continue
+		}
+		if d := levenshtein(label, candidate); d < best && d*2 < len([]rune(label)) {
+			suggestion, best = candidate, d
+		}
+	}
+	if len(suggestion) == 0 {
+		return "", 0
+	}
+	return suggestion, best
+}
+
+// levenshtein returns the number of single-character insertions, deletions and substitutions turning a into b.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
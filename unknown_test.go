@@ -0,0 +1,44 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import "testing"
+
+func TestSuggestLabel(t *testing.T) {
+	candidates := []string{"bug", "doc", "doc-complete", "doc-not-needed", "doc-required"}
+	for _, tc := range []struct {
+		label      string
+		suggestion string
+		distance   int
+	}{
+		{"doc-requred", "doc-required", 1},
+		{"doc-requried", "doc-required", 2},
+		{"Doc-not-needed", "doc-not-needed", 1},
+		{"doc-completed", "doc-complete", 1},
+		{"dog", "doc", 1},
+		{"bag", "bug", 1},
+		{"docs-ok", "", 0},
+		{"feature", "", 0},
+		{"doc", "", 0},
+	} {
+		suggestion, distance := suggestLabel(tc.label, candidates)
+		if suggestion != tc.suggestion || distance != tc.distance {
+			t.Errorf("suggestLabel(%q) = %q, %d, want %q, %d", tc.label, suggestion, distance, tc.suggestion, tc.distance)
+		}
+	}
+}
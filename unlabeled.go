@@ -0,0 +1,58 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// sleep waits out the grace period, replaced in tests.
+var sleep = time.Sleep
+
+// missingLabelsOnUnlabeled returns the missing labels to add once a human removed the last label of a category,
+// according to MISSING_ON_UNLABELED: readd adds them right away, grace only if no label is selected again
+// within MISSING_GRACE_PERIOD, as when swapping labels, and none leaves the PR alone.
+func (a *Action) missingLabelsOnUnlabeled(missingLabels []string) ([]string, error) {
+	switch a.config.GetMissingOnUnlabeled() {
+	case "none":
+		logger.Infof("Last label removed, not adding missing labels %v\n", missingLabels)
+		return nil, nil
+	case "grace":
+		logger.Infof("Last label removed, waiting %v before adding missing labels\n", a.config.GetMissingGracePeriod())
+		sleep(a.config.GetMissingGracePeriod())
+		// humans may have changed the labels while waiting
+		a.cache.invalidate(a.config.GetNumber(), true)
+
+		labels, err := a.provider.ListLabels(a.globalContext, a.config.GetNumber())
+		if err != nil {
+			return nil, fmt.Errorf("list labels: %w", err)
+		}
+		watched := []string{}
+		for _, label := range labels {
+			if a.config.isWatchedLabel(label) {
+				watched = append(watched, label)
+			}
+		}
+		lacking, _ := a.checkCardinality(watched)
+		return a.config.missingLabelsOf(lacking), nil
+	}
+	return missingLabels, nil
+}
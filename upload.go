@@ -0,0 +1,55 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// uploadReport copies the report file to the bucket URL with the CLI of the cloud provider, which picks up
+// the credentials of the job, e.g. from aws-actions/configure-aws-credentials or google-github-actions/auth.
+// A URL ending with "/" is treated as a prefix and the file name is appended.
+func uploadReport(file, url string) error {
+	if strings.HasSuffix(url, "/") {
+		url += path.Base(file)
+	}
+
+	var cmd *exec.Cmd
+	switch {
+	case strings.HasPrefix(url, "s3://"):
+		cmd = exec.Command("aws", "s3", "cp", "--only-show-errors", file, url)
+	case strings.HasPrefix(url, "gs://"):
+		cmd = exec.Command("gcloud", "storage", "cp", file, url)
+	default:
+		return fmt.Errorf("unsupported upload URL %v", url)
+	}
+
+	logger.Infof("@Upload report to %v\n", url)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("upload report: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/ghapi"
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// labelUsage is the cleanup report of the labels of the configuration.
+type labelUsage struct {
+	// Unused are the watched labels on no open PR or issue, nor on one closed within LABEL_USAGE_DAYS
+	Unused []string
+	// Deleted are the labels of the configuration missing from the repository
+	Deleted []string
+	// Miscolored are the labels of the configuration off the color convention of LABEL_HUES
+	Miscolored []labelColor
+}
+
+// runLabelUsage writes the cleanup report of the configured labels to the step summary.
+func runLabelUsage(ctx context.Context, ac *ActionConfig) error {
+	usage, err := reportLabelUsage(ctx, ac, newGitHubClient(ctx, ac.GetToken(), ac.transport()))
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Unused labels: %v\n", usage.Unused)
+	logger.Infof("Deleted labels: %v\n", usage.Deleted)
+	logger.Infof("Miscolored labels: %v\n", usage.Miscolored)
+	githubactions.AddStepSummary(usage.markdown(ac.GetLabelUsageDays()))
+	return nil
+}
+
+// reportLabelUsage finds the watched labels nobody uses anymore, and the configured labels deleted from the repository.
+func reportLabelUsage(ctx context.Context, ac *ActionConfig, client *ghapi.Client) (*labelUsage, error) {
+	action := &Action{
+		config:        ac,
+		globalContext: ctx,
+		client:        client,
+	}
+
+	logger.Infoln("@List repository labels")
+	labels, err := action.listRepoLabels()
+	if err != nil {
+		return nil, fmt.Errorf("list labels: %w", err)
+	}
+	inRepo := make(map[string]string)
+	for _, l := range labels {
+		inRepo[l.GetName()] = l.GetColor()
+	}
+
+	since := time.Now().AddDate(0, 0, -ac.GetLabelUsageDays()).Format("2006-01-02")
+	used := make(map[string]struct{})
+	for _, query := range []string{
+		fmt.Sprintf("repo:%s/%s is:open", ac.GetOwner(), ac.GetRepo()),
+		fmt.Sprintf("repo:%s/%s is:closed closed:>=%s", ac.GetOwner(), ac.GetRepo(), since),
+	} {
+		logger.Infof("@Search PRs and issues: %v\n", query)
+		issues, err := searchIssues(ctx, client, query)
+		if err != nil {
+			return nil, fmt.Errorf("search PRs and issues: %w", err)
+		}
+		for _, issue := range issues {
+			for _, l := range issue.Labels {
+				used[l.GetName()] = struct{}{}
+			}
+		}
+	}
+
+	configured, err := action.configuredLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &labelUsage{Unused: []string{}, Deleted: []string{}, Miscolored: []labelColor{}}
+	for _, label := range action.watchedLabels() {
+		if _, exist := used[label]; !exist {
+			usage.Unused = append(usage.Unused, label)
+		}
+	}
+	for _, label := range configured {
+		color, exist := inRepo[label]
+		if !exist {
+			usage.Deleted = append(usage.Deleted, label)
+			continue
+		}
+		if miscolored, off := checkLabelColor(ac.labelHues, label, color); off {
+			usage.Miscolored = append(usage.Miscolored, miscolored)
+		}
+	}
+	return usage, nil
+}
+
+// configuredLabels returns the labels of the watch list, the missing labels if enabled, and the labels of the config file, sorted.
+func (a *Action) configuredLabels() ([]string, error) {
+	set := make(map[string]struct{})
+	for label := range a.config.labelWatchSet {
+		set[label] = struct{}{}
+	}
+	if a.config.GetEnableLabelMissing() {
+		for label := range a.config.groupMissingLabels() {
+			set[label] = struct{}{}
+		}
+		for _, label := range a.config.labelMissingMap {
+			set[label] = struct{}{}
+		}
+	}
+
+	fc, err := a.getFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range fc.Labels {
+		set[def.Name] = struct{}{}
+	}
+
+	labels := []string{}
+	for label := range set {
+		if len(label) > 0 {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+// markdown renders the report for the step summary.
+func (u *labelUsage) markdown(days int) string {
+	summary := &strings.Builder{}
+	summary.WriteString("### Label usage\n\n")
+	if len(u.Unused) == 0 && len(u.Deleted) == 0 && len(u.Miscolored) == 0 {
+		summary.WriteString("All configured labels are in use.\n")
+		return summary.String()
+	}
+	if len(u.Unused) > 0 {
+		fmt.Fprintf(summary, "Watched labels on no open PR or issue, nor on one closed in the last %d days:\n\n", days)
+		for _, label := range u.Unused {
+			fmt.Fprintf(summary, "- `%s`\n", label)
+		}
+		summary.WriteString("\n")
+	}
+	if len(u.Deleted) > 0 {
+		summary.WriteString("Configured labels deleted from the repository:\n\n")
+		for _, label := range u.Deleted {
+			fmt.Fprintf(summary, "- `%s`\n", label)
+		}
+		summary.WriteString("\n")
+	}
+	if len(u.Miscolored) > 0 {
+		summary.WriteString("Configured labels off their color convention:\n\n")
+		for _, l := range u.Miscolored {
+			fmt.Fprintf(summary, "- `%s` is `#%s`, expected a hue around %.0f°\n", l.Label, l.Color, l.Hue)
+		}
+	}
+	return summary.String()
+}
@@ -0,0 +1,99 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+
+	"github.com/maxsxu/action-labeler/pkg/scm"
+)
+
+// Build information, injected at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+//
+// commit and buildDate fall back to the VCS information stamped by go build.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo describes the running build, e.g. "v1.2.0 (commit 1a2b3c4, built 2026-01-01T00:00:00Z)".
+func buildInfo() string {
+	v, c, d := version, commit, buildDate
+	if len(v) == 0 {
+		v = "dev"
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && len(c) == 0:
+				c = s.Value
+			case s.Key == "vcs.time" && len(d) == 0:
+				d = s.Value
+			}
+		}
+	}
+	if len(c) > 7 {
+		c = c[:7]
+	}
+
+	details := ""
+	if len(c) > 0 {
+		details = "commit " + c
+	}
+	if len(d) > 0 {
+		if len(details) > 0 {
+			details += ", "
+		}
+		details += "built " + d
+	}
+	if len(details) == 0 {
+		return v
+	}
+	return fmt.Sprintf("%s (%s)", v, details)
+}
+
+// versionMarker is the hidden marker recording the build that posted a comment.
+func versionMarker() string {
+	// json.Marshal escapes ">", so the version can't end the HTML comment
+	data, _ := json.Marshal(buildInfo())
+	return fmt.Sprintf("%sversion=%s -->", markerPrefix, data)
+}
+
+// versionMarkerRegexp matches the version marker appended to a comment, along with the line break before it.
+var versionMarkerRegexp = regexp.MustCompile(`\n?` + regexp.QuoteMeta(markerPrefix+"version=") + `"(?:[^"\\]|\\.)*" -->`)
+
+// versionProvider appends the version marker to the comments posted through a Provider, so that
+// operators can tell which deployment produced a comment.
+type versionProvider struct {
+	scm.Provider
+}
+
+func (p *versionProvider) Comment(ctx context.Context, number int, body string) error {
+	return p.Provider.Comment(ctx, number, body+"\n"+versionMarker())
+}
+
+func (p *versionProvider) EditComment(ctx context.Context, number int, id int64, body string) error {
+	return p.Provider.EditComment(ctx, number, id, body+"\n"+versionMarker())
+}
@@ -0,0 +1,44 @@
+// Licensed to the Apache Software Foundation (ASF) under one
+// or more contributor license agreements.  See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership.  The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/sethvargo/go-githubactions"
+
+	"github.com/maxsxu/action-labeler/pkg/logger"
+)
+
+// warn records a non-fatal problem of the run, like a label that failed to be added or a comment that failed
+// to be posted, so that the remaining operations proceed and the problems are reported together at the end.
+func (a *Action) warn(operation string, err error) {
+	logger.Errorf("%v: %v\n", operation, err)
+	a.warnings = append(a.warnings, fmt.Errorf("%s: %w", operation, err))
+}
+
+// reportWarnings emits a warning annotation for each non-fatal problem recorded by warn.
+func (a *Action) reportWarnings() {
+	if len(a.warnings) == 0 {
+		return
+	}
+	logger.Infof("@Report %d non-fatal problems\n", len(a.warnings))
+	for _, w := range a.warnings {
+		githubactions.Warningf("PR #%d: %v", a.config.GetNumber(), w)
+	}
+}